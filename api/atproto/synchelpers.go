@@ -0,0 +1,53 @@
+package atproto
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+)
+
+// EventTimeError is returned by [SyncSubscribeRepos_Commit.Lag] when the event's "time" field is missing or malformed
+type EventTimeError struct {
+	// the raw "time" field; empty if missing
+	Time string
+	// parse error, or nil if the field was missing
+	Err error
+}
+
+func (e *EventTimeError) Error() string {
+	if e.Err == nil {
+		return "event has no time"
+	}
+	return fmt.Sprintf("invalid event time %q: %s", e.Time, e.Err)
+}
+
+func (e *EventTimeError) Unwrap() error {
+	return e.Err
+}
+
+// Lag returns how long before now the commit event was emitted, according to its "time" field. The result is negative if the event time is in the future (eg, due to clock skew). Returns an [*EventTimeError] if the time is missing or malformed.
+func (c *SyncSubscribeRepos_Commit) Lag(now time.Time) (time.Duration, error) {
+	if c.Time == "" {
+		return 0, &EventTimeError{}
+	}
+	t, err := syntax.ParseDatetimeTime(c.Time)
+	if err != nil {
+		return 0, &EventTimeError{Time: c.Time, Err: err}
+	}
+	return now.Sub(t), nil
+}
+
+// IsTooBig reports whether the commit's (deprecated) "tooBig" flag is set, meaning that blocks were omitted from the event. The event's blocks and ops can't be relied on; consumers should fetch the full repo via sync (eg, com.atproto.sync.getRepo) instead.
+func (c *SyncSubscribeRepos_Commit) IsTooBig() bool {
+	return c != nil && c.TooBig
+}
+
+// ParsePath splits the op's repo path ("collection/rkey") in to collection NSID and record key, validating the syntax of both parts. On error, both strings are empty.
+func (op *SyncSubscribeRepos_RepoOp) ParsePath() (collection, rkey string, err error) {
+	nsid, rk, err := syntax.ParseRepoPath(op.Path)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid repo op path %q: %w", op.Path, err)
+	}
+	return nsid.String(), rk.String(), nil
+}
@@ -0,0 +1,103 @@
+package atproto
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommitLag(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	// past
+	evt := SyncSubscribeRepos_Commit{Time: "2024-01-02T03:03:05.000Z"}
+	lag, err := evt.Lag(now)
+	assert.NoError(err)
+	assert.Equal(time.Minute, lag)
+
+	// non-UTC offset
+	evt.Time = "2024-01-02T04:04:04.500+01:00"
+	lag, err = evt.Lag(now)
+	assert.NoError(err)
+	assert.Equal(500*time.Millisecond, lag)
+
+	// future
+	evt.Time = "2024-01-02T03:04:15.000Z"
+	lag, err = evt.Lag(now)
+	assert.NoError(err)
+	assert.Equal(-10*time.Second, lag)
+
+	var timeErr *EventTimeError
+
+	// missing
+	evt.Time = ""
+	_, err = evt.Lag(now)
+	if assert.ErrorAs(err, &timeErr) {
+		assert.Equal("", timeErr.Time)
+		assert.Nil(timeErr.Err)
+	}
+
+	// malformed
+	for _, ts := range []string{"yesterday", "2024-01-02", "2024-01-02 03:04:05Z", "1704164645"} {
+		evt.Time = ts
+		_, err = evt.Lag(now)
+		if assert.ErrorAs(err, &timeErr, ts) {
+			assert.Equal(ts, timeErr.Time)
+			assert.NotNil(errors.Unwrap(err))
+		}
+	}
+}
+
+func TestRepoOpParsePath(t *testing.T) {
+	assert := assert.New(t)
+
+	valid := []struct {
+		path       string
+		collection string
+		rkey       string
+	}{
+		{"app.bsky.feed.post/3jzfcijpj2z2a", "app.bsky.feed.post", "3jzfcijpj2z2a"},
+		{"app.bsky.actor.profile/self", "app.bsky.actor.profile", "self"},
+		{"com.example.record/a:b-c_d~e.f", "com.example.record", "a:b-c_d~e.f"},
+	}
+	for _, tc := range valid {
+		op := SyncSubscribeRepos_RepoOp{Action: "create", Path: tc.path}
+		collection, rkey, err := op.ParsePath()
+		assert.NoError(err, tc.path)
+		assert.Equal(tc.collection, collection)
+		assert.Equal(tc.rkey, rkey)
+	}
+
+	invalid := []string{
+		"",
+		"app.bsky.feed.post",
+		"app.bsky.feed.post3jzfcijpj2z2a",
+		"app.bsky.feed.post/",
+		"/3jzfcijpj2z2a",
+		"app.bsky.feed.post/3jzfcijpj2z2a/extra",
+		"not-an-nsid/3jzfcijpj2z2a",
+		"app.bsky.feed.post/..",
+		"app.bsky.feed.post/bad key",
+	}
+	for _, path := range invalid {
+		op := SyncSubscribeRepos_RepoOp{Action: "delete", Path: path}
+		collection, rkey, err := op.ParsePath()
+		assert.Error(err, path)
+		assert.Empty(collection)
+		assert.Empty(rkey)
+	}
+}
+
+func TestCommitIsTooBig(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.False((&SyncSubscribeRepos_Commit{}).IsTooBig())
+	assert.True((&SyncSubscribeRepos_Commit{TooBig: true}).IsTooBig())
+
+	var nilCommit *SyncSubscribeRepos_Commit
+	assert.False(nilCommit.IsTooBig())
+}
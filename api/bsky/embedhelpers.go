@@ -0,0 +1,59 @@
+package bsky
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	comatproto "github.com/bluesky-social/indigo/api/atproto"
+)
+
+// AspectRatioFromBytes reads the dimensions of an image (PNG, JPEG, or GIF) from its header, for setting the aspect ratio of image and video embeds. The image is not fully decoded.
+func AspectRatioFromBytes(img []byte) (*EmbedDefs_AspectRatio, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(img))
+	if err != nil {
+		return nil, fmt.Errorf("reading image dimensions: %w", err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, fmt.Errorf("invalid %s image dimensions: %dx%d", format, cfg.Width, cfg.Height)
+	}
+	return &EmbedDefs_AspectRatio{
+		Width:  int64(cfg.Width),
+		Height: int64(cfg.Height),
+	}, nil
+}
+
+// Images returns the images media of the embed, if that is the media type. Safe to call on a nil embed.
+func (e *EmbedRecordWithMedia) Images() (*EmbedImages, bool) {
+	if e == nil || e.Media == nil || e.Media.EmbedImages == nil {
+		return nil, false
+	}
+	return e.Media.EmbedImages, true
+}
+
+// Video returns the video media of the embed, if that is the media type. Safe to call on a nil embed.
+func (e *EmbedRecordWithMedia) Video() (*EmbedVideo, bool) {
+	if e == nil || e.Media == nil || e.Media.EmbedVideo == nil {
+		return nil, false
+	}
+	return e.Media.EmbedVideo, true
+}
+
+// External returns the external link card media of the embed, if that is the media type. Safe to call on a nil embed.
+func (e *EmbedRecordWithMedia) External() (*EmbedExternal, bool) {
+	if e == nil || e.Media == nil || e.Media.EmbedExternal == nil {
+		return nil, false
+	}
+	return e.Media.EmbedExternal, true
+}
+
+// RecordRef returns the strong reference to the embedded (quoted) record, or nil if missing. Safe to call on a nil embed.
+func (e *EmbedRecordWithMedia) RecordRef() *comatproto.RepoStrongRef {
+	if e == nil || e.Record == nil {
+		return nil
+	}
+	return e.Record.Record
+}
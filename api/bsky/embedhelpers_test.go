@@ -0,0 +1,113 @@
+package bsky
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	comatproto "github.com/bluesky-social/indigo/api/atproto"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmbedRecordWithMediaAccessors(t *testing.T) {
+	assert := assert.New(t)
+
+	ref := &comatproto.RepoStrongRef{
+		Uri: "at://did:plc:abc123/app.bsky.feed.post/3jzfcijpj2z2a",
+		Cid: "bafyreidfayvfuwqa7qlnopdjiqrxzs6blmoeu4rujcjtnci5beludirz2a",
+	}
+
+	images := &EmbedRecordWithMedia{
+		Media:  &EmbedRecordWithMedia_Media{EmbedImages: &EmbedImages{Images: []*EmbedImages_Image{{Alt: "a cat"}}}},
+		Record: &EmbedRecord{Record: ref},
+	}
+	img, ok := images.Images()
+	assert.True(ok)
+	assert.Equal("a cat", img.Images[0].Alt)
+	_, ok = images.External()
+	assert.False(ok)
+	_, ok = images.Video()
+	assert.False(ok)
+	assert.Equal(ref, images.RecordRef())
+
+	external := &EmbedRecordWithMedia{
+		Media:  &EmbedRecordWithMedia_Media{EmbedExternal: &EmbedExternal{External: &EmbedExternal_External{Uri: "https://example.com"}}},
+		Record: &EmbedRecord{Record: ref},
+	}
+	ext, ok := external.External()
+	assert.True(ok)
+	assert.Equal("https://example.com", ext.External.Uri)
+	_, ok = external.Images()
+	assert.False(ok)
+
+	video := &EmbedRecordWithMedia{
+		Media: &EmbedRecordWithMedia_Media{EmbedVideo: &EmbedVideo{Alt: strPtr("clip")}},
+	}
+	vid, ok := video.Video()
+	assert.True(ok)
+	assert.Equal("clip", *vid.Alt)
+	_, ok = video.Images()
+	assert.False(ok)
+	assert.Nil(video.RecordRef())
+
+	// decoded from JSON, the union is populated by $type
+	var decoded EmbedRecordWithMedia
+	assert.NoError(json.Unmarshal([]byte(`{
+		"$type": "app.bsky.embed.recordWithMedia",
+		"media": {"$type": "app.bsky.embed.external", "external": {"uri": "https://example.com", "title": "", "description": ""}},
+		"record": {"$type": "app.bsky.embed.record", "record": {"uri": "at://did:plc:abc123/app.bsky.feed.post/3jzfcijpj2z2a", "cid": "bafyreidfayvfuwqa7qlnopdjiqrxzs6blmoeu4rujcjtnci5beludirz2a"}}
+	}`), &decoded))
+	_, ok = decoded.External()
+	assert.True(ok)
+	assert.Equal(ref.Uri, decoded.RecordRef().Uri)
+
+	// nil and empty embeds
+	var empty *EmbedRecordWithMedia
+	_, ok = empty.Images()
+	assert.False(ok)
+	_, ok = empty.External()
+	assert.False(ok)
+	assert.Nil(empty.RecordRef())
+	_, ok = (&EmbedRecordWithMedia{}).Images()
+	assert.False(ok)
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestAspectRatioFromBytes(t *testing.T) {
+	assert := assert.New(t)
+
+	img := image.NewRGBA(image.Rect(0, 0, 64, 48))
+
+	var pngBuf bytes.Buffer
+	if err := png.Encode(&pngBuf, img); err != nil {
+		t.Fatal(err)
+	}
+	ar, err := AspectRatioFromBytes(pngBuf.Bytes())
+	assert.NoError(err)
+	assert.Equal(&EmbedDefs_AspectRatio{Width: 64, Height: 48}, ar)
+
+	var jpegBuf bytes.Buffer
+	if err := jpeg.Encode(&jpegBuf, image.NewGray(image.Rect(0, 0, 30, 100)), nil); err != nil {
+		t.Fatal(err)
+	}
+	ar, err = AspectRatioFromBytes(jpegBuf.Bytes())
+	assert.NoError(err)
+	assert.Equal(&EmbedDefs_AspectRatio{Width: 30, Height: 100}, ar)
+
+	// only the header is needed
+	ar, err = AspectRatioFromBytes(pngBuf.Bytes()[:33])
+	assert.NoError(err)
+	assert.Equal(int64(64), ar.Width)
+
+	_, err = AspectRatioFromBytes([]byte("not an image"))
+	assert.Error(err)
+	_, err = AspectRatioFromBytes(nil)
+	assert.Error(err)
+}
@@ -0,0 +1,39 @@
+package bsky
+
+// ShiftFacets returns facets with byte ranges adjusted for an edit to the text they annotate. A positive delta is an insertion of that many bytes at byte offset editStart; a negative delta is a deletion of -delta bytes starting at editStart.
+//
+// Facets entirely after the edit are shifted, and those entirely before it are unchanged. Text inserted strictly inside a facet extends it. Facets which overlap deleted text are dropped, as are facets which were already invalid (missing or empty byte range). The input facets are not modified; features are shared with the returned facets.
+func ShiftFacets(facets []*RichtextFacet, editStart, delta int) []*RichtextFacet {
+	start := int64(editStart)
+	d := int64(delta)
+	out := make([]*RichtextFacet, 0, len(facets))
+	for _, f := range facets {
+		if f == nil || f.Index == nil || f.Index.ByteStart < 0 || f.Index.ByteEnd <= f.Index.ByteStart {
+			continue
+		}
+		s, e := f.Index.ByteStart, f.Index.ByteEnd
+		switch {
+		case d >= 0 && start <= s:
+			// insertion before the facet
+			s, e = s+d, e+d
+		case d >= 0 && start < e:
+			// insertion inside the facet
+			e += d
+		case d >= 0:
+			// insertion after the facet
+		case start-d <= s:
+			// deletion before the facet
+			s, e = s+d, e+d
+		case start >= e:
+			// deletion after the facet
+		default:
+			// deletion overlapping the facet
+			continue
+		}
+		out = append(out, &RichtextFacet{
+			Features: f.Features,
+			Index:    &RichtextFacet_ByteSlice{ByteStart: s, ByteEnd: e},
+		})
+	}
+	return out
+}
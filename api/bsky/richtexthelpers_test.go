@@ -0,0 +1,58 @@
+package bsky
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShiftFacets(t *testing.T) {
+	assert := assert.New(t)
+
+	tag := &RichtextFacet_Features_Elem{RichtextFacet_Tag: &RichtextFacet_Tag{Tag: "art"}}
+	facet := func(start, end int64) *RichtextFacet {
+		return &RichtextFacet{
+			Features: []*RichtextFacet_Features_Elem{tag},
+			Index:    &RichtextFacet_ByteSlice{ByteStart: start, ByteEnd: end},
+		}
+	}
+	ranges := func(facets []*RichtextFacet) [][2]int64 {
+		out := make([][2]int64, len(facets))
+		for i, f := range facets {
+			assert.Equal([]*RichtextFacet_Features_Elem{tag}, f.Features)
+			out[i] = [2]int64{f.Index.ByteStart, f.Index.ByteEnd}
+		}
+		return out
+	}
+
+	// "hello #art and #more"
+	facets := []*RichtextFacet{facet(6, 10), facet(15, 20)}
+
+	// insertion before both facets
+	assert.Equal([][2]int64{{9, 13}, {18, 23}}, ranges(ShiftFacets(facets, 0, 3)))
+	// insertion exactly at a facet start pushes it along
+	assert.Equal([][2]int64{{8, 12}, {17, 22}}, ranges(ShiftFacets(facets, 6, 2)))
+	// insertion inside the first facet extends it
+	assert.Equal([][2]int64{{6, 12}, {17, 22}}, ranges(ShiftFacets(facets, 8, 2)))
+	// insertion at a facet end, between the facets
+	assert.Equal([][2]int64{{6, 10}, {17, 22}}, ranges(ShiftFacets(facets, 10, 2)))
+	// insertion after both facets
+	assert.Equal([][2]int64{{6, 10}, {15, 20}}, ranges(ShiftFacets(facets, 20, 5)))
+
+	// deletion before both facets
+	assert.Equal([][2]int64{{4, 8}, {13, 18}}, ranges(ShiftFacets(facets, 0, -2)))
+	// deletion between the facets, adjacent to both
+	assert.Equal([][2]int64{{6, 10}, {10, 15}}, ranges(ShiftFacets(facets, 10, -5)))
+	// deletion overlapping the first facet drops it
+	assert.Equal([][2]int64{{12, 17}}, ranges(ShiftFacets(facets, 4, -3)))
+	// deletion inside the second facet drops it
+	assert.Equal([][2]int64{{6, 10}}, ranges(ShiftFacets(facets, 16, -1)))
+
+	// the input is not modified
+	assert.Equal([][2]int64{{6, 10}, {15, 20}}, ranges(facets))
+
+	// invalid facets are dropped
+	invalid := []*RichtextFacet{nil, {Features: []*RichtextFacet_Features_Elem{tag}}, facet(5, 5), facet(-1, 3), facet(1, 2)}
+	assert.Equal([][2]int64{{1, 2}}, ranges(ShiftFacets(invalid, 10, 1)))
+	assert.Empty(ShiftFacets(nil, 0, 1))
+}
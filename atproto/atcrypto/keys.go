@@ -1,6 +1,7 @@
 package atcrypto
 
 import (
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"strings"
@@ -71,6 +72,24 @@ type PublicKey interface {
 
 var ErrInvalidSignature = errors.New("cryptographic signature invalid")
 
+// Returned when parsing a multibase-encoded key with a multicodec prefix which is not one of the supported atproto key types.
+type UnsupportedKeyTypeError struct {
+	// Multicodec code decoded from the varint prefix. Zero if the prefix could not be decoded.
+	Codec uint64
+}
+
+func (e *UnsupportedKeyTypeError) Error() string {
+	return fmt.Sprintf("unsupported atproto key type (unknown multicodec prefix 0x%x)", e.Codec)
+}
+
+func unsupportedKeyType(data []byte) error {
+	code, n := binary.Uvarint(data)
+	if n <= 0 {
+		code = 0
+	}
+	return &UnsupportedKeyTypeError{Codec: code}
+}
+
 /*
 // quick code to verify varint byte conversion (https://play.golang.com/):
 import  (
@@ -103,11 +122,13 @@ func ParsePrivateMultibase(encoded string) (PrivateKeyExportable, error) {
 		// multicodec secp256k1-priv, code 0x1301, varint-encoded bytes: [0x81, 0x26]
 		return ParsePrivateBytesK256(data[2:])
 	} else {
-		return nil, fmt.Errorf("unsupported atproto key type (unknown multicodec prefix)")
+		return nil, unsupportedKeyType(data)
 	}
 }
 
 // Loads a public key from multibase string encoding, with multicodec indicating the key type.
+//
+// Supports the P-256 and K-256 (secp256k1) curves. Returns an [UnsupportedKeyTypeError] for any other multicodec.
 func ParsePublicMultibase(encoded string) (PublicKey, error) {
 	if len(encoded) < 2 || encoded[0] != 'z' {
 		return nil, fmt.Errorf("crypto: not a multibase base58btc string")
@@ -126,7 +147,7 @@ func ParsePublicMultibase(encoded string) (PublicKey, error) {
 		// multicodec secp256k1-pub, code 0xE7, varint bytes: [0xE7, 0x01]
 		return ParsePublicBytesK256(data[2:])
 	} else {
-		return nil, fmt.Errorf("unsupported atproto key type (unknown multicodec prefix)")
+		return nil, unsupportedKeyType(data)
 	}
 }
 
@@ -4,6 +4,7 @@ import (
 	"crypto/rand"
 	"testing"
 
+	"github.com/mr-tron/base58"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -174,3 +175,42 @@ func TestParsePrivateMultibase(t *testing.T) {
 	assert.True(ok)
 	assert.Equal(privK256MB, privK256FromMB.Multibase())
 }
+
+func TestParsePublicMultibase(t *testing.T) {
+	assert := assert.New(t)
+
+	// public keys from the W3C did:key fixtures
+	pubP256MB := "zDnaeTiq1PdzvZXUaMdezchcMJQpBdH2VN4pgrrEhMCCbmwSb"
+	pubK256MB := "zQ3shokFTS3brHcDQrn82RUDfCZESWL1ZdCEJwekUDPQiYBme"
+
+	pubP256, err := ParsePublicMultibase(pubP256MB)
+	assert.NoError(err)
+	_, ok := pubP256.(*PublicKeyP256)
+	assert.True(ok)
+	assert.Equal(pubP256MB, pubP256.Multibase())
+	assert.Equal("did:key:"+pubP256MB, pubP256.DIDKey())
+
+	pubK256, err := ParsePublicMultibase(pubK256MB)
+	assert.NoError(err)
+	_, ok = pubK256.(*PublicKeyK256)
+	assert.True(ok)
+	assert.Equal(pubK256MB, pubK256.Multibase())
+	assert.Equal("did:key:"+pubK256MB, pubK256.DIDKey())
+
+	// ed25519-pub multicodec (0xED), which atproto does not support
+	ed25519MB := "z" + base58.Encode(append([]byte{0xED, 0x01}, make([]byte, 32)...))
+	_, err = ParsePublicMultibase(ed25519MB)
+	var keyTypeErr *UnsupportedKeyTypeError
+	assert.ErrorAs(err, &keyTypeErr)
+	assert.Equal(uint64(0xED), keyTypeErr.Codec)
+
+	// private key multibase is not a public key
+	_, err = ParsePublicMultibase("z42tvqQS5sVhaV1jLZ5P6ZKEPEbSpYavNVmT88YDYV3MEZ8D")
+	assert.ErrorAs(err, &keyTypeErr)
+	assert.Equal(uint64(0x1306), keyTypeErr.Codec)
+
+	for _, bad := range []string{"", "z", "zzz", "uAAAA", "z1"} {
+		_, err = ParsePublicMultibase(bad)
+		assert.Error(err, bad)
+	}
+}
@@ -7,11 +7,16 @@ import (
 	"io"
 )
 
-// Helper type for extracting record $type from CBOR
+// Helper type for extracting record $type from JSON or CBOR, for any record type, including unknown Lexicons. All other fields are ignored when decoding.
 type GenericRecord struct {
 	Type string `json:"$type" cborgen:"$type"`
 }
 
+// LexiconType returns the record's $type, and whether it was present (non-empty)
+func (r GenericRecord) LexiconType() (string, bool) {
+	return r.Type, r.Type != ""
+}
+
 // Parses the top-level $type field from generic atproto JSON data
 func ExtractTypeJSON(b []byte) (string, error) {
 	var gr GenericRecord
@@ -1,6 +1,8 @@
 package atdata
 
 import (
+	"bytes"
+	"encoding/json"
 	"io"
 	"os"
 	"testing"
@@ -39,3 +41,61 @@ func TestExtract(t *testing.T) {
 	assert.NoError(err)
 	assert.Equal("app.bsky.feed.post", tp)
 }
+
+func TestGenericRecordUnknownType(t *testing.T) {
+	assert := assert.New(t)
+
+	jsonBytes := []byte(`{
+		"$type": "com.example.unknown.record",
+		"text": "hello",
+		"nested": {
+			"$type": "com.example.unknown.nested",
+			"list": [1, "two", {"three": [3]}],
+			"link": {"$link": "bafyreidfayvfuwqa7qlnopdjiqrxzs6blmoeu4rujcjtnci5beludirz2a"}
+		},
+		"flag": true
+	}`)
+
+	var gr GenericRecord
+	assert.NoError(json.Unmarshal(jsonBytes, &gr))
+	typ, ok := gr.LexiconType()
+	assert.True(ok)
+	assert.Equal("com.example.unknown.record", typ)
+
+	// CBOR round-trip through the generic data model, with nested unknown fields skipped
+	obj, err := UnmarshalJSON(jsonBytes)
+	assert.NoError(err)
+	cborBytes, err := MarshalCBOR(obj)
+	assert.NoError(err)
+	gr = GenericRecord{}
+	assert.NoError(gr.UnmarshalCBOR(bytes.NewReader(cborBytes)))
+	typ, ok = gr.LexiconType()
+	assert.True(ok)
+	assert.Equal("com.example.unknown.record", typ)
+
+	// missing $type
+	obj, err = UnmarshalJSON([]byte(`{"a": {"b": [1, 2]}}`))
+	assert.NoError(err)
+	cborBytes, err = MarshalCBOR(obj)
+	assert.NoError(err)
+	gr = GenericRecord{}
+	assert.NoError(gr.UnmarshalCBOR(bytes.NewReader(cborBytes)))
+	_, ok = gr.LexiconType()
+	assert.False(ok)
+
+	// unexpected shapes are errors, not panics
+	for _, bad := range []string{`{"$type": 5}`, `{"$type": {"a": 1}}`, `[1, 2]`, `"record"`, `{`} {
+		_, err := ExtractTypeJSON([]byte(bad))
+		assert.Error(err, bad)
+	}
+	badCBOR := [][]byte{
+		{},
+		{0x82, 0x01, 0x02}, // array
+		{0xa1, 0x65, '$', 't', 'y', 'p', 'e', 0x05}, // $type is an integer
+		{0xa1, 0x61, 'a'}, // truncated map
+	}
+	for _, bad := range badCBOR {
+		gr = GenericRecord{}
+		assert.Error(gr.UnmarshalCBOR(bytes.NewReader(bad)), "%x", bad)
+	}
+}
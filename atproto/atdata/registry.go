@@ -0,0 +1,41 @@
+package atdata
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+
+	cbg "github.com/whyrusleeping/cbor-gen"
+)
+
+var (
+	registryLk sync.RWMutex
+	registry   = make(map[string]func() cbg.CBORUnmarshaler)
+)
+
+// Register a factory for a strongly-typed record struct, to be used by [Decode] for records of the given NSID. The factory must return a new (pointer) value on each call. Registering the same NSID again replaces the earlier factory.
+func Register(nsid string, factory func() cbg.CBORUnmarshaler) {
+	registryLk.Lock()
+	defer registryLk.Unlock()
+	registry[nsid] = factory
+}
+
+// Decode parses a CBOR record of the given NSID. If a type has been registered for the NSID (see [Register]), returns a new value of that type. Otherwise falls back to generic data (map[string]any), as returned by [UnmarshalCBOR].
+func Decode(nsid string, b []byte) (any, error) {
+	if len(b) > MAX_CBOR_RECORD_SIZE {
+		return nil, fmt.Errorf("exceeded max CBOR record size: %d", len(b))
+	}
+
+	registryLk.RLock()
+	factory, ok := registry[nsid]
+	registryLk.RUnlock()
+	if !ok {
+		return UnmarshalCBOR(b)
+	}
+
+	v := factory()
+	if err := v.UnmarshalCBOR(bytes.NewReader(b)); err != nil {
+		return nil, fmt.Errorf("decoding %s record: %w", nsid, err)
+	}
+	return v, nil
+}
@@ -0,0 +1,74 @@
+package atdata
+
+import (
+	"bytes"
+	"maps"
+	"testing"
+
+	"github.com/bluesky-social/indigo/api/bsky"
+
+	"github.com/stretchr/testify/assert"
+	cbg "github.com/whyrusleeping/cbor-gen"
+)
+
+// snapshots the global registry, restoring it when the test finishes
+func saveRegistry(t *testing.T) {
+	registryLk.Lock()
+	saved := maps.Clone(registry)
+	registryLk.Unlock()
+	t.Cleanup(func() {
+		registryLk.Lock()
+		registry = saved
+		registryLk.Unlock()
+	})
+}
+
+func TestRegistryDecode(t *testing.T) {
+	assert := assert.New(t)
+	saveRegistry(t)
+
+	post := bsky.FeedPost{
+		LexiconTypeID: "app.bsky.feed.post",
+		Text:          "hello world",
+		CreatedAt:     "2024-01-02T03:04:05.006Z",
+	}
+	buf := new(bytes.Buffer)
+	if err := post.MarshalCBOR(buf); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+
+	// unknown type falls back to generic data
+	out, err := Decode("app.bsky.feed.post", b)
+	assert.NoError(err)
+	generic, ok := out.(map[string]any)
+	if assert.True(ok) {
+		assert.Equal("hello world", generic["text"])
+	}
+
+	Register("app.bsky.feed.post", func() cbg.CBORUnmarshaler { return new(bsky.FeedPost) })
+
+	out, err = Decode("app.bsky.feed.post", b)
+	assert.NoError(err)
+	typed, ok := out.(*bsky.FeedPost)
+	if assert.True(ok) {
+		assert.Equal(post.Text, typed.Text)
+		assert.Equal(post.CreatedAt, typed.CreatedAt)
+	}
+
+	// each decode gets a fresh value
+	again, err := Decode("app.bsky.feed.post", b)
+	assert.NoError(err)
+	assert.NotSame(typed, again)
+
+	// other NSIDs still decode generically
+	out, err = Decode("com.example.record", b)
+	assert.NoError(err)
+	assert.IsType(map[string]any{}, out)
+
+	// malformed input
+	_, err = Decode("app.bsky.feed.post", []byte{0xa1})
+	assert.Error(err)
+	_, err = Decode("com.example.record", []byte{0xa1})
+	assert.Error(err)
+}
@@ -0,0 +1,167 @@
+package identity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+)
+
+// Returned by CircuitBreakerDirectory when the breaker is open and lookups are being short-circuited
+var ErrCircuitOpen = errors.New("identity resolution circuit breaker open")
+
+// State of a CircuitBreakerDirectory
+type BreakerState int
+
+const (
+	// Lookups pass through to the inner directory
+	BreakerClosed BreakerState = iota
+	// Lookups fail immediately with ErrCircuitOpen, until the cooldown has passed
+	BreakerOpen
+	// The cooldown has passed, and a single probe lookup is allowed through; others fail immediately
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("BreakerState(%d)", int(s))
+	}
+}
+
+// CircuitBreakerDirectory is an implementation of identity.Directory which stops sending lookups to an inner directory while it is failing (eg, when the PLC directory is down), so that callers get a fast error instead of stalling on retries and timeouts.
+//
+// After MaxFailures consecutive failed lookups the breaker opens, and lookups fail with ErrCircuitOpen for the Cooldown period. After that, a single probe lookup is let through: if it succeeds the breaker closes, otherwise it re-opens for another cooldown.
+//
+// Only resolution failures count against the breaker. Definitive answers from a working service (like ErrDIDNotFound or ErrHandleNotFound), and lookups abandoned because the caller's context was done, do not. Purges always pass through.
+type CircuitBreakerDirectory struct {
+	Inner       Directory
+	MaxFailures int
+	Cooldown    time.Duration
+
+	lk       sync.Mutex
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+var _ Directory = (*CircuitBreakerDirectory)(nil)
+
+func NewCircuitBreakerDirectory(inner Directory, maxFailures int, cooldown time.Duration) *CircuitBreakerDirectory {
+	return &CircuitBreakerDirectory{
+		Inner:       inner,
+		MaxFailures: maxFailures,
+		Cooldown:    cooldown,
+	}
+}
+
+// State returns the current state of the breaker, eg for reporting as a metric.
+func (d *CircuitBreakerDirectory) State() BreakerState {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+	return d.state(time.Now())
+}
+
+func (d *CircuitBreakerDirectory) state(now time.Time) BreakerState {
+	if d.failures < max(d.MaxFailures, 1) {
+		return BreakerClosed
+	}
+	if now.Sub(d.openedAt) < d.Cooldown {
+		return BreakerOpen
+	}
+	return BreakerHalfOpen
+}
+
+// checks whether a lookup may proceed. If the returned bool is true, the lookup is a half-open probe.
+func (d *CircuitBreakerDirectory) allow() (bool, error) {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+
+	switch d.state(time.Now()) {
+	case BreakerClosed:
+		return false, nil
+	case BreakerHalfOpen:
+		if !d.probing {
+			d.probing = true
+			return true, nil
+		}
+	}
+	return false, ErrCircuitOpen
+}
+
+// records the outcome of a lookup which was allowed through
+func (d *CircuitBreakerDirectory) record(ctx context.Context, probe bool, err error) {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+
+	if probe {
+		d.probing = false
+	}
+	switch {
+	case !isResolutionFailure(err):
+		d.failures = 0
+	case ctx.Err() != nil:
+		// the caller gave up; says nothing about the inner directory
+	default:
+		d.failures++
+		if d.failures >= max(d.MaxFailures, 1) {
+			d.openedAt = time.Now()
+		}
+	}
+}
+
+// reports whether err indicates that resolution failed, as opposed to completing with a (possibly negative) answer
+func isResolutionFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	for _, definitive := range []error{ErrDIDNotFound, ErrHandleNotFound, ErrHandleMismatch, ErrHandleNotDeclared, ErrHandleReservedTLD, ErrInvalidHandle, ErrKeyNotDeclared} {
+		if errors.Is(err, definitive) {
+			return false
+		}
+	}
+	return true
+}
+
+func (d *CircuitBreakerDirectory) LookupHandle(ctx context.Context, h syntax.Handle) (*Identity, error) {
+	probe, err := d.allow()
+	if err != nil {
+		return nil, err
+	}
+	ident, err := d.Inner.LookupHandle(ctx, h)
+	d.record(ctx, probe, err)
+	return ident, err
+}
+
+func (d *CircuitBreakerDirectory) LookupDID(ctx context.Context, did syntax.DID) (*Identity, error) {
+	probe, err := d.allow()
+	if err != nil {
+		return nil, err
+	}
+	ident, err := d.Inner.LookupDID(ctx, did)
+	d.record(ctx, probe, err)
+	return ident, err
+}
+
+func (d *CircuitBreakerDirectory) Lookup(ctx context.Context, a syntax.AtIdentifier) (*Identity, error) {
+	probe, err := d.allow()
+	if err != nil {
+		return nil, err
+	}
+	ident, err := d.Inner.Lookup(ctx, a)
+	d.record(ctx, probe, err)
+	return ident, err
+}
+
+func (d *CircuitBreakerDirectory) Purge(ctx context.Context, a syntax.AtIdentifier) error {
+	return d.Inner.Purge(ctx, a)
+}
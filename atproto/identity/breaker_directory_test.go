@@ -0,0 +1,113 @@
+package identity
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// wraps a directory, failing all lookups while down is set, and counting lookups which reach it
+type flakyDirectory struct {
+	MockDirectory
+
+	lk    sync.Mutex
+	down  bool
+	calls int
+}
+
+func (d *flakyDirectory) setDown(down bool) {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+	d.down = down
+}
+
+func (d *flakyDirectory) LookupDID(ctx context.Context, did syntax.DID) (*Identity, error) {
+	d.lk.Lock()
+	d.calls++
+	down := d.down
+	d.lk.Unlock()
+	if down {
+		return nil, ErrDIDResolutionFailed
+	}
+	return d.MockDirectory.LookupDID(ctx, did)
+}
+
+func TestCircuitBreakerDirectory(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	ident := Identity{
+		DID:    syntax.DID("did:plc:abc111"),
+		Handle: syntax.Handle("handle.example.com"),
+	}
+	inner := &flakyDirectory{MockDirectory: NewMockDirectory()}
+	inner.Insert(ident)
+
+	d := NewCircuitBreakerDirectory(inner, 3, 100*time.Millisecond)
+	assert.Equal(BreakerClosed, d.State())
+
+	_, err := d.LookupDID(ctx, ident.DID)
+	assert.NoError(err)
+
+	// not-found is a definitive answer, and doesn't count as a failure
+	for i := 0; i < 5; i++ {
+		_, err = d.LookupDID(ctx, syntax.DID("did:plc:missing"))
+		assert.ErrorIs(err, ErrDIDNotFound)
+	}
+	assert.Equal(BreakerClosed, d.State())
+
+	// drive the breaker open
+	inner.setDown(true)
+	for i := 0; i < 3; i++ {
+		_, err = d.LookupDID(ctx, ident.DID)
+		assert.ErrorIs(err, ErrDIDResolutionFailed)
+	}
+	assert.Equal(BreakerOpen, d.State())
+	assert.Equal("open", d.State().String())
+
+	// fast-fail without reaching the inner directory
+	calls := inner.calls
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		_, err = d.LookupDID(ctx, ident.DID)
+		assert.ErrorIs(err, ErrCircuitOpen)
+	}
+	assert.Equal(calls, inner.calls)
+	assert.Less(time.Since(start), 50*time.Millisecond)
+
+	// after the cooldown, a failed probe re-opens the breaker
+	time.Sleep(120 * time.Millisecond)
+	assert.Equal(BreakerHalfOpen, d.State())
+	_, err = d.LookupDID(ctx, ident.DID)
+	assert.ErrorIs(err, ErrDIDResolutionFailed)
+	assert.Equal(calls+1, inner.calls)
+	assert.Equal(BreakerOpen, d.State())
+	_, err = d.LookupDID(ctx, ident.DID)
+	assert.ErrorIs(err, ErrCircuitOpen)
+
+	// recovery: a successful probe closes the breaker
+	inner.setDown(false)
+	time.Sleep(120 * time.Millisecond)
+	out, err := d.LookupDID(ctx, ident.DID)
+	assert.NoError(err)
+	assert.Equal(ident.DID, out.DID)
+	assert.Equal(BreakerClosed, d.State())
+
+	// a single failure after recovery doesn't re-open
+	inner.setDown(true)
+	_, err = d.LookupDID(ctx, ident.DID)
+	assert.ErrorIs(err, ErrDIDResolutionFailed)
+	assert.Equal(BreakerClosed, d.State())
+
+	// purges always pass through
+	for i := 0; i < 3; i++ {
+		d.LookupDID(ctx, ident.DID)
+	}
+	assert.Equal(BreakerOpen, d.State())
+	assert.NoError(d.Purge(ctx, ident.DID.AtIdentifier()))
+}
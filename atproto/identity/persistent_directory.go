@@ -0,0 +1,221 @@
+package identity
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+)
+
+// CacheStore is a persistent key/value store, used by PersistentCacheDirectory to keep resolved identities across process restarts. Implementations must be safe for concurrent use.
+type CacheStore interface {
+	// Get returns the stored value for key. The bool is false if there is no such key.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, val []byte) error
+	// Delete removes key from the store. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// PersistentCacheDirectory is an implementation of identity.Directory which caches successful lookups from an inner directory in a CacheStore (eg, on local disk), so that a restarted service doesn't need to re-resolve every identity it sees.
+//
+// Entries older than TTL are ignored, and refreshed from the inner directory. Failed lookups are not cached. Errors reading from or writing to the store are logged, and lookups fall through to the inner directory.
+//
+// This is intended to sit behind an in-memory CacheDirectory, which handles hot identities and request coalescing.
+type PersistentCacheDirectory struct {
+	Inner Directory
+	Store CacheStore
+	TTL   time.Duration
+}
+
+var _ Directory = (*PersistentCacheDirectory)(nil)
+
+type storedIdentity struct {
+	Updated  time.Time `json:"updated"`
+	Identity Identity  `json:"identity"`
+}
+
+type storedHandle struct {
+	Updated time.Time  `json:"updated"`
+	DID     syntax.DID `json:"did"`
+}
+
+func NewPersistentCacheDirectory(inner Directory, store CacheStore, ttl time.Duration) PersistentCacheDirectory {
+	return PersistentCacheDirectory{
+		Inner: inner,
+		Store: store,
+		TTL:   ttl,
+	}
+}
+
+func didStoreKey(did syntax.DID) string {
+	return "did/" + did.String()
+}
+
+func handleStoreKey(h syntax.Handle) string {
+	return "handle/" + h.String()
+}
+
+// reads and decodes a store entry. Returns false for missing, undecodable, or expired entries.
+func (d *PersistentCacheDirectory) load(ctx context.Context, key string, val any, updated *time.Time) bool {
+	b, ok, err := d.Store.Get(ctx, key)
+	if err != nil {
+		slog.Warn("identity cache store read failed", "key", key, "err", err)
+		return false
+	}
+	if !ok {
+		return false
+	}
+	if err := json.Unmarshal(b, val); err != nil {
+		slog.Warn("identity cache store entry invalid", "key", key, "err", err)
+		return false
+	}
+	if d.TTL > 0 && time.Since(*updated) > d.TTL {
+		return false
+	}
+	return true
+}
+
+func (d *PersistentCacheDirectory) save(ctx context.Context, key string, val any) {
+	b, err := json.Marshal(val)
+	if err != nil {
+		slog.Error("identity cache store encoding failed", "key", key, "err", err)
+		return
+	}
+	if err := d.Store.Set(ctx, key, b); err != nil {
+		slog.Warn("identity cache store write failed", "key", key, "err", err)
+	}
+}
+
+func (d *PersistentCacheDirectory) saveIdentity(ctx context.Context, ident *Identity) {
+	now := time.Now()
+	d.save(ctx, didStoreKey(ident.DID), storedIdentity{Updated: now, Identity: *ident})
+	if !ident.Handle.IsInvalidHandle() {
+		d.save(ctx, handleStoreKey(ident.Handle.Normalize()), storedHandle{Updated: now, DID: ident.DID})
+	}
+}
+
+func (d *PersistentCacheDirectory) LookupDID(ctx context.Context, did syntax.DID) (*Identity, error) {
+	var entry storedIdentity
+	if d.load(ctx, didStoreKey(did), &entry, &entry.Updated) && entry.Identity.DID == did {
+		return &entry.Identity, nil
+	}
+
+	ident, err := d.Inner.LookupDID(ctx, did)
+	if err != nil {
+		return nil, err
+	}
+	d.saveIdentity(ctx, ident)
+	return ident, nil
+}
+
+func (d *PersistentCacheDirectory) LookupHandle(ctx context.Context, h syntax.Handle) (*Identity, error) {
+	h = h.Normalize()
+	var entry storedHandle
+	if d.load(ctx, handleStoreKey(h), &entry, &entry.Updated) {
+		ident, err := d.LookupDID(ctx, entry.DID)
+		// the handle must still be verified for the identity; otherwise resolve it again
+		if err == nil && ident.Handle.Normalize() == h {
+			return ident, nil
+		}
+	}
+
+	ident, err := d.Inner.LookupHandle(ctx, h)
+	if err != nil {
+		return nil, err
+	}
+	d.saveIdentity(ctx, ident)
+	return ident, nil
+}
+
+func (d *PersistentCacheDirectory) Lookup(ctx context.Context, a syntax.AtIdentifier) (*Identity, error) {
+	handle, err := a.AsHandle()
+	if nil == err { // if not an error, is a handle
+		return d.LookupHandle(ctx, handle)
+	}
+	did, err := a.AsDID()
+	if nil == err { // if not an error, is a DID
+		return d.LookupDID(ctx, did)
+	}
+	return nil, fmt.Errorf("at-identifier neither a Handle nor a DID")
+}
+
+// Removes the identifier from the store, and purges the inner directory as well.
+func (d *PersistentCacheDirectory) Purge(ctx context.Context, a syntax.AtIdentifier) error {
+	var key string
+	if handle, err := a.AsHandle(); nil == err {
+		key = handleStoreKey(handle.Normalize())
+	} else if did, err := a.AsDID(); nil == err {
+		key = didStoreKey(did)
+	} else {
+		return fmt.Errorf("at-identifier neither a Handle nor a DID")
+	}
+	if err := d.Store.Delete(ctx, key); err != nil {
+		return fmt.Errorf("purging identity cache store: %w", err)
+	}
+	return d.Inner.Purge(ctx, a)
+}
+
+// FileCacheStore is a CacheStore which keeps each entry as a file in a local directory.
+type FileCacheStore struct {
+	Dir string
+}
+
+var _ CacheStore = (*FileCacheStore)(nil)
+
+// Creates the directory if it does not already exist.
+func NewFileCacheStore(dir string) (*FileCacheStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating identity cache directory: %w", err)
+	}
+	return &FileCacheStore{Dir: dir}, nil
+}
+
+// keys contain characters (like '/' and ':') which aren't safe in file names, so files are named by key hash
+func (s *FileCacheStore) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.Dir, hex.EncodeToString(sum[:]))
+}
+
+func (s *FileCacheStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	b, err := os.ReadFile(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return b, true, nil
+}
+
+// Writes are atomic: the value is written to a temporary file, which is then renamed in to place.
+func (s *FileCacheStore) Set(ctx context.Context, key string, val []byte) error {
+	f, err := os.CreateTemp(s.Dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(val); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), s.path(key))
+}
+
+func (s *FileCacheStore) Delete(ctx context.Context, key string) error {
+	err := os.Remove(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
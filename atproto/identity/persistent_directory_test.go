@@ -0,0 +1,171 @@
+package identity
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// in-memory CacheStore which counts operations
+type fakeCacheStore struct {
+	lk      sync.Mutex
+	vals    map[string][]byte
+	gets    int
+	sets    int
+	deletes int
+}
+
+func (s *fakeCacheStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+	s.gets++
+	b, ok := s.vals[key]
+	return b, ok, nil
+}
+
+func (s *fakeCacheStore) Set(ctx context.Context, key string, val []byte) error {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+	s.sets++
+	s.vals[key] = val
+	return nil
+}
+
+func (s *fakeCacheStore) Delete(ctx context.Context, key string) error {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+	s.deletes++
+	delete(s.vals, key)
+	return nil
+}
+
+// wraps a directory, counting lookups which reach it
+type countingDirectory struct {
+	MockDirectory
+	calls int
+}
+
+func (d *countingDirectory) LookupHandle(ctx context.Context, h syntax.Handle) (*Identity, error) {
+	d.calls++
+	return d.MockDirectory.LookupHandle(ctx, h)
+}
+
+func (d *countingDirectory) LookupDID(ctx context.Context, did syntax.DID) (*Identity, error) {
+	d.calls++
+	return d.MockDirectory.LookupDID(ctx, did)
+}
+
+func TestPersistentCacheDirectory(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	ident := Identity{
+		DID:    syntax.DID("did:plc:abc111"),
+		Handle: syntax.Handle("handle.example.com"),
+		Services: map[string]ServiceEndpoint{
+			"atproto_pds": {Type: "AtprotoPersonalDataServer", URL: "https://pds.example.com"},
+		},
+	}
+	inner := &countingDirectory{MockDirectory: NewMockDirectory()}
+	inner.Insert(ident)
+	store := &fakeCacheStore{vals: make(map[string][]byte)}
+	d := NewPersistentCacheDirectory(inner, store, time.Hour)
+
+	// first lookup resolves and writes both the identity and handle entries
+	out, err := d.LookupHandle(ctx, syntax.Handle("Handle.Example.com"))
+	assert.NoError(err)
+	assert.Equal(ident, *out)
+	assert.Equal(1, inner.calls)
+	assert.Equal(2, store.sets)
+	assert.Contains(store.vals, "did/did:plc:abc111")
+	assert.Contains(store.vals, "handle/handle.example.com")
+
+	// later lookups are served from the store
+	out, err = d.LookupDID(ctx, ident.DID)
+	assert.NoError(err)
+	assert.Equal(ident, *out)
+	out, err = d.Lookup(ctx, ident.Handle.AtIdentifier())
+	assert.NoError(err)
+	assert.Equal(ident, *out)
+	assert.Equal(1, inner.calls)
+	assert.Equal(2, store.sets)
+
+	// as if restarted: a fresh directory over the same store
+	d2 := NewPersistentCacheDirectory(inner, store, time.Hour)
+	_, err = d2.LookupDID(ctx, ident.DID)
+	assert.NoError(err)
+	assert.Equal(1, inner.calls)
+
+	// expired entries are re-resolved and re-written
+	old, err := json.Marshal(storedIdentity{Updated: time.Now().Add(-2 * time.Hour), Identity: ident})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.vals["did/did:plc:abc111"] = old
+	_, err = d.LookupDID(ctx, ident.DID)
+	assert.NoError(err)
+	assert.Equal(2, inner.calls)
+	assert.Equal(4, store.sets)
+	_, err = d.LookupDID(ctx, ident.DID)
+	assert.NoError(err)
+	assert.Equal(2, inner.calls)
+
+	// failures are not cached
+	_, err = d.LookupDID(ctx, syntax.DID("did:plc:missing"))
+	assert.ErrorIs(err, ErrDIDNotFound)
+	_, err = d.LookupDID(ctx, syntax.DID("did:plc:missing"))
+	assert.ErrorIs(err, ErrDIDNotFound)
+	assert.Equal(4, inner.calls)
+	assert.Equal(4, store.sets)
+
+	// purge deletes from the store
+	assert.NoError(d.Purge(ctx, ident.DID.AtIdentifier()))
+	assert.Equal(1, store.deletes)
+	assert.NotContains(store.vals, "did/did:plc:abc111")
+	_, err = d.LookupDID(ctx, ident.DID)
+	assert.NoError(err)
+	assert.Equal(5, inner.calls)
+}
+
+func TestFileCacheStore(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	s, err := NewFileCacheStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, ok, err := s.Get(ctx, "did/did:plc:abc111")
+	assert.NoError(err)
+	assert.False(ok)
+
+	assert.NoError(s.Set(ctx, "did/did:plc:abc111", []byte("one")))
+	assert.NoError(s.Set(ctx, "did/did:plc:abc111", []byte("two")))
+	b, ok, err := s.Get(ctx, "did/did:plc:abc111")
+	assert.NoError(err)
+	assert.True(ok)
+	assert.Equal([]byte("two"), b)
+
+	// reopening the same directory sees the same entries
+	s2, err := NewFileCacheStore(s.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, ok, err = s2.Get(ctx, "did/did:plc:abc111")
+	assert.NoError(err)
+	assert.True(ok)
+	assert.Equal([]byte("two"), b)
+
+	assert.NoError(s.Delete(ctx, "did/did:plc:abc111"))
+	assert.NoError(s.Delete(ctx, "did/did:plc:abc111"))
+	_, ok, err = s2.Get(ctx, "did/did:plc:abc111")
+	assert.NoError(err)
+	assert.False(ok)
+}
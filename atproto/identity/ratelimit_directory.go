@@ -0,0 +1,53 @@
+package identity
+
+import (
+	"context"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+
+	"golang.org/x/time/rate"
+)
+
+// RateLimitDirectory is an implementation of identity.Directory which throttles all lookups to an inner directory through a single shared token-bucket limiter. When the bucket is empty, lookups block until a token is available, or the context is done.
+//
+// To only limit actual network resolution, wrap the uncached directory and put a CacheDirectory in front of this one, so that cache hits do not consume tokens. Compare with BaseDirectory.PLCLimiter, which only limits requests to the PLC directory.
+type RateLimitDirectory struct {
+	Inner   Directory
+	Limiter *rate.Limiter
+}
+
+var _ Directory = (*RateLimitDirectory)(nil)
+
+// Creates a directory allowing an average of perSecond lookups, with bursts of up to burst lookups.
+func NewRateLimitDirectory(inner Directory, perSecond float64, burst int) RateLimitDirectory {
+	return RateLimitDirectory{
+		Inner:   inner,
+		Limiter: rate.NewLimiter(rate.Limit(perSecond), burst),
+	}
+}
+
+func (d *RateLimitDirectory) LookupHandle(ctx context.Context, h syntax.Handle) (*Identity, error) {
+	if err := d.Limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	return d.Inner.LookupHandle(ctx, h)
+}
+
+func (d *RateLimitDirectory) LookupDID(ctx context.Context, did syntax.DID) (*Identity, error) {
+	if err := d.Limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	return d.Inner.LookupDID(ctx, did)
+}
+
+func (d *RateLimitDirectory) Lookup(ctx context.Context, a syntax.AtIdentifier) (*Identity, error) {
+	if err := d.Limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	return d.Inner.Lookup(ctx, a)
+}
+
+// Purges are passed through to the inner directory without consuming a token.
+func (d *RateLimitDirectory) Purge(ctx context.Context, a syntax.AtIdentifier) error {
+	return d.Inner.Purge(ctx, a)
+}
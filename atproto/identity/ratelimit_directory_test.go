@@ -0,0 +1,57 @@
+package identity
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimitDirectory(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	inner := NewMockDirectory()
+	ident := Identity{
+		DID:    syntax.DID("did:plc:abc111"),
+		Handle: syntax.Handle("handle.example.com"),
+	}
+	inner.Insert(ident)
+
+	// 20 per second, no burst: lookups after the first are spaced 50ms apart
+	d := NewRateLimitDirectory(&inner, 20, 1)
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		out, err := d.LookupDID(ctx, ident.DID)
+		assert.NoError(err)
+		assert.Equal(ident.DID, out.DID)
+	}
+	elapsed := time.Since(start)
+	assert.GreaterOrEqual(elapsed, 180*time.Millisecond)
+	assert.Less(elapsed, 2*time.Second)
+
+	// all lookup methods share the bucket
+	start = time.Now()
+	_, err := d.LookupHandle(ctx, ident.Handle)
+	assert.NoError(err)
+	_, err = d.Lookup(ctx, ident.DID.AtIdentifier())
+	assert.NoError(err)
+	assert.GreaterOrEqual(time.Since(start), 80*time.Millisecond)
+
+	// purges don't wait
+	slow := NewRateLimitDirectory(&inner, 0.01, 1)
+	_, err = slow.LookupDID(ctx, ident.DID)
+	assert.NoError(err)
+	assert.NoError(slow.Purge(ctx, ident.DID.AtIdentifier()))
+
+	// an empty bucket respects the context deadline
+	tctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	_, err = slow.LookupDID(tctx, ident.DID)
+	assert.Error(err)
+	assert.Less(time.Since(start), time.Second)
+}
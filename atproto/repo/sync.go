@@ -3,6 +3,7 @@ package repo
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 
@@ -13,6 +14,9 @@ import (
 	"github.com/ipfs/go-cid"
 )
 
+// Returned by VerifyCommitMessage for commit events with the "tooBig" flag set. Blocks were omitted from the event, so the commit can't be verified (or its records read) from the event alone; fetch the repo via sync instead.
+var ErrCommitTooBig = errors.New("commit event too big: blocks omitted")
+
 // temporary/experimental method to parse and verify a firehose commit message.
 //
 // TODO: move to a separate 'sync' package? break up in to smaller components?
@@ -33,8 +37,8 @@ func VerifyCommitMessage(ctx context.Context, msg *comatproto.SyncSubscribeRepos
 		return nil, err
 	}
 
-	if msg.TooBig {
-		logger.Warn("event with tooBig flag set")
+	if msg.IsTooBig() {
+		return nil, ErrCommitTooBig
 	}
 	if msg.Rebase {
 		logger.Warn("event with rebase flag set")
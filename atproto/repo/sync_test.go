@@ -29,6 +29,28 @@ func TestFirehoseMergePartialNodes(t *testing.T) {
 	//testCommitFile(t, "testdata/firehose_commit_4621332152.json")
 }
 
+func TestVerifyCommitMessageTooBig(t *testing.T) {
+	ctx := context.Background()
+
+	body, err := os.ReadFile("testdata/firehose_commit_4623075231.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msg comatproto.SyncSubscribeRepos_Commit
+	if err := json.Unmarshal(body, &msg); err != nil {
+		t.Fatal(err)
+	}
+
+	// even with the blocks present, a tooBig commit isn't verified
+	msg.TooBig = true
+	_, err = VerifyCommitMessage(ctx, &msg)
+	assert.ErrorIs(t, err, ErrCommitTooBig)
+
+	msg.TooBig = false
+	_, err = VerifyCommitMessage(ctx, &msg)
+	assert.NoError(t, err)
+}
+
 func testCommitFile(t *testing.T, p string) {
 	assert := assert.New(t)
 	ctx := context.Background()
@@ -44,6 +44,49 @@ func ParseATURI(raw string) (ATURI, error) {
 	return ATURI(raw), nil
 }
 
+// Assembles an AT URI from a DID authority, collection, and record key, validating each part. The result is normalized.
+//
+// The collection and record key may be empty, to refer to an entire repository or collection. If you need a handle in the authority position, use [MakeATURIWithHandle].
+func MakeATURI(authority, collection, rkey string) (ATURI, error) {
+	did, err := ParseDID(authority)
+	if err != nil {
+		return "", fmt.Errorf("AT-URI authority not a DID: %w", err)
+	}
+	return makeATURI(did.AtIdentifier(), collection, rkey)
+}
+
+// Variant of [MakeATURI] which takes a handle authority, instead of a DID.
+func MakeATURIWithHandle(authority, collection, rkey string) (ATURI, error) {
+	handle, err := ParseHandle(authority)
+	if err != nil {
+		return "", fmt.Errorf("AT-URI authority not a handle: %w", err)
+	}
+	return makeATURI(handle.AtIdentifier(), collection, rkey)
+}
+
+func makeATURI(auth AtIdentifier, collection, rkey string) (ATURI, error) {
+	out := "at://" + auth.Normalize().String()
+	if collection == "" {
+		if rkey != "" {
+			return "", errors.New("AT-URI record key requires a collection")
+		}
+		return ATURI(out), nil
+	}
+	nsid, err := ParseNSID(collection)
+	if err != nil {
+		return "", fmt.Errorf("AT-URI collection not an NSID: %w", err)
+	}
+	out += "/" + nsid.Normalize().String()
+	if rkey != "" {
+		rk, err := ParseRecordKey(rkey)
+		if err != nil {
+			return "", fmt.Errorf("AT-URI record key not valid: %w", err)
+		}
+		out += "/" + rk.String()
+	}
+	return ParseATURI(out)
+}
+
 // Every valid ATURI has a valid AtIdentifier in the authority position.
 //
 // If this ATURI is malformed, returns empty
@@ -120,3 +120,45 @@ func TestATURINoPanic(t *testing.T) {
 		_ = bad.Path()
 	}
 }
+
+func TestMakeATURI(t *testing.T) {
+	assert := assert.New(t)
+
+	uri, err := MakeATURI("did:plc:abc123", "app.bsky.feed.post", "3jzfcijpj2z2a")
+	assert.NoError(err)
+	assert.Equal("at://did:plc:abc123/app.bsky.feed.post/3jzfcijpj2z2a", uri.String())
+	assert.Equal("did:plc:abc123", uri.Authority().String())
+	assert.Equal("app.bsky.feed.post", uri.Collection().String())
+	assert.Equal("3jzfcijpj2z2a", uri.RecordKey().String())
+
+	uri, err = MakeATURI("did:plc:abc123", "app.bsky.feed.post", "")
+	assert.NoError(err)
+	assert.Equal("at://did:plc:abc123/app.bsky.feed.post", uri.String())
+
+	uri, err = MakeATURI("did:plc:abc123", "", "")
+	assert.NoError(err)
+	assert.Equal("at://did:plc:abc123", uri.String())
+
+	// normalizes handle case and NSID authority case
+	uri, err = MakeATURIWithHandle("Alice.Example.COM", "COM.Example.record", "self")
+	assert.NoError(err)
+	assert.Equal("at://alice.example.com/com.example.record/self", uri.String())
+
+	invalid := [][]string{
+		{"", "app.bsky.feed.post", "abc"},
+		{"alice.example.com", "app.bsky.feed.post", "abc"},
+		{"did:plc:abc123", "not-an-nsid", "abc"},
+		{"did:plc:abc123", "app.bsky.feed.post", "bad/rkey"},
+		{"did:plc:abc123", "app.bsky.feed.post", ".."},
+		{"did:plc:abc123", "", "abc"},
+	}
+	for _, parts := range invalid {
+		_, err := MakeATURI(parts[0], parts[1], parts[2])
+		assert.Error(err, parts)
+	}
+
+	_, err = MakeATURIWithHandle("did:plc:abc123", "app.bsky.feed.post", "abc")
+	assert.Error(err)
+	_, err = MakeATURIWithHandle("-bad-.example.com", "app.bsky.feed.post", "abc")
+	assert.Error(err)
+}
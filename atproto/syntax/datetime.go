@@ -106,6 +106,18 @@ func (d Datetime) Time() time.Time {
 	return ret
 }
 
+// Returns the same instant with a UTC ("Z") timezone, for consistent storage and comparison of datetimes with differing offsets. Sub-second precision is preserved (without trailing zeros). Values already in "Z" form are returned unchanged, as are (invalid) values which fail to parse.
+func (d Datetime) UTC() Datetime {
+	if strings.HasSuffix(string(d), "Z") {
+		return d
+	}
+	t, err := time.Parse(time.RFC3339Nano, d.String())
+	if err != nil {
+		return d
+	}
+	return Datetime(t.UTC().Format(time.RFC3339Nano))
+}
+
 // Creates a new valid Datetime string matching the current time, in preferred syntax.
 func DatetimeNow() Datetime {
 	t := time.Now().UTC()
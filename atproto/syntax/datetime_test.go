@@ -113,3 +113,32 @@ func TestDatetimeNow(t *testing.T) {
 	_, err := ParseDatetimeTime(dt.String())
 	assert.NoError(err)
 }
+
+func TestDatetimeUTC(t *testing.T) {
+	assert := assert.New(t)
+
+	fixtures := map[string]string{
+		"1985-04-12T23:20:50.123+00:00":    "1985-04-12T23:20:50.123Z",
+		"1985-04-12T23:20:50.123-07:00":    "1985-04-13T06:20:50.123Z",
+		"1985-04-13T01:20:50+02:00":        "1985-04-12T23:20:50Z",
+		"1985-04-12T23:20:50.123456+05:30": "1985-04-12T17:50:50.123456Z",
+		"1985-04-12T23:20:50.100-01:00":    "1985-04-13T00:20:50.1Z",
+	}
+	for raw, expected := range fixtures {
+		dt, err := ParseDatetime(raw)
+		assert.NoError(err)
+		utc := dt.UTC()
+		assert.Equal(expected, utc.String())
+		assert.True(dt.Time().Equal(utc.Time()))
+		_, err = ParseDatetime(utc.String())
+		assert.NoError(err)
+		assert.Equal(utc, utc.UTC())
+	}
+
+	// already-UTC values are unchanged
+	for _, raw := range []string{"1985-04-12T23:20:50.123Z", "1985-04-12T23:20:50.000Z", "1985-04-12T23:20:50Z"} {
+		dt, err := ParseDatetime(raw)
+		assert.NoError(err)
+		assert.Equal(dt, dt.UTC())
+	}
+}
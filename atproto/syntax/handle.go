@@ -34,6 +34,54 @@ func ParseHandle(raw string) (Handle, error) {
 	return Handle(raw), nil
 }
 
+// Validate checks the handle syntax, like [ParseHandle], but with a specific error describing what is wrong (eg, an empty label, or a label which is too long). Useful for giving feedback on user input before attempting resolution.
+//
+// This only checks syntax: use [Handle.AllowedTLD] to reject handles with reserved TLDs.
+func (h Handle) Validate() error {
+	raw := string(h)
+	if raw == "" {
+		return errors.New("expected handle, got empty string")
+	}
+	if len(raw) > 253 {
+		return fmt.Errorf("handle is too long (%d chars, 253 max)", len(raw))
+	}
+	if strings.HasPrefix(raw, ".") {
+		return errors.New("handle can not start with a dot")
+	}
+	if strings.HasSuffix(raw, ".") {
+		return errors.New("handle can not end with a dot")
+	}
+	labels := strings.Split(raw, ".")
+	if len(labels) < 2 {
+		return errors.New("handle must have at least two labels (eg, 'example.com')")
+	}
+	for _, label := range labels {
+		if label == "" {
+			return errors.New("handle can not contain empty labels (consecutive dots)")
+		}
+		if len(label) > 63 {
+			return fmt.Errorf("handle label is too long (%d chars, 63 max): %s", len(label), label)
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+				return fmt.Errorf("handle label contains disallowed character %q: %s", c, label)
+			}
+		}
+		if label[0] == '-' || label[len(label)-1] == '-' {
+			return fmt.Errorf("handle label can not start or end with a hyphen: %s", label)
+		}
+	}
+	tld := labels[len(labels)-1]
+	if !(tld[0] >= 'a' && tld[0] <= 'z' || tld[0] >= 'A' && tld[0] <= 'Z') {
+		return fmt.Errorf("handle top-level domain must start with a letter: %s", tld)
+	}
+	// fall back to the canonical check, so Validate never accepts a handle ParseHandle rejects
+	if !handleRegex.MatchString(raw) {
+		return fmt.Errorf("handle syntax didn't validate via regex: %s", raw)
+	}
+	return nil
+}
+
 // Some top-level domains (TLDs) are disallowed for registration across the atproto ecosystem. The *syntax* is valid, but these should never be considered acceptable handles for account registration or linking.
 //
 // The reserved '.test' TLD is allowed, for testing and development. It is expected that '.test' domain resolution will fail in a real-world network.
@@ -62,6 +110,7 @@ func (h Handle) IsInvalidHandle() bool {
 	return h.Normalize() == HandleInvalid
 }
 
+// Normalize returns the handle in canonical (lower-case) form. Handles are case-insensitive.
 func (h Handle) Normalize() Handle {
 	return Handle(strings.ToLower(string(h)))
 }
@@ -5,6 +5,7 @@ import (
 	"encoding"
 	"fmt"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -78,3 +79,62 @@ func TestHandleInterfaces(t *testing.T) {
 	var _ encoding.TextMarshaler = h
 	var _ encoding.TextUnmarshaler = &h
 }
+
+func TestHandleValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	valid := []string{
+		"john.test",
+		"JoHn.TeST",
+		"a.co",
+		"xn--ls8h.test",
+		"sub-domain.example.com",
+		"1.2.3.4.example",
+		strings.Repeat("a", 63) + ".com",
+	}
+	for _, raw := range valid {
+		assert.NoError(Handle(raw).Validate(), raw)
+	}
+
+	invalid := []struct {
+		raw string
+		msg string
+	}{
+		{"", "empty string"},
+		{strings.Repeat("a.", 126) + "com", "too long"},
+		{".john.test", "start with a dot"},
+		{"john.test.", "end with a dot"},
+		{"john", "at least two labels"},
+		{"john..test", "empty labels"},
+		{strings.Repeat("a", 64) + ".com", "label is too long"},
+		{"jo_hn.test", "disallowed character"},
+		{"jöhn.test", "disallowed character"},
+		{"john .test", "disallowed character"},
+		{"-john.test", "hyphen"},
+		{"john-.test", "hyphen"},
+		{"john.-test", "hyphen"},
+		{"john.123", "must start with a letter"},
+	}
+	for _, tc := range invalid {
+		assert.ErrorContains(Handle(tc.raw).Validate(), tc.msg, tc.raw)
+	}
+
+	// agrees with ParseHandle on the interop fixtures
+	for _, path := range []string{"testdata/handle_syntax_valid.txt", "testdata/handle_syntax_invalid.txt"} {
+		file, err := os.Open(path)
+		assert.NoError(err)
+		defer file.Close()
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if len(line) == 0 || line[0] == '#' {
+				continue
+			}
+			_, parseErr := ParseHandle(line)
+			assert.Equal(parseErr == nil, Handle(line).Validate() == nil, line)
+		}
+		assert.NoError(scanner.Err())
+	}
+
+	assert.Equal(Handle("john.test"), Handle("JoHn.TeST").Normalize())
+}
@@ -138,6 +138,9 @@ func ClockFromTID(t TID) TIDClock {
 	}
 }
 
+// Returns a new TID, which is guaranteed to be greater than any TID previously returned by this clock.
+//
+// If called more than once within the same microsecond (or if the system clock moves backwards), the timestamp portion is advanced as a logical counter, so the TID may run slightly ahead of wall-clock time under heavy load.
 func (c *TIDClock) Next() TID {
 	now := time.Now().UTC().UnixMicro()
 	c.mtx.Lock()
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"sync"
 	"testing"
 	"time"
 
@@ -129,3 +130,41 @@ func TestTIDClock(t *testing.T) {
 		last = next
 	}
 }
+
+func TestTIDClockConcurrent(t *testing.T) {
+	assert := assert.New(t)
+
+	clk := NewTIDClock(7)
+	workers := 8
+	perWorker := 2000
+
+	results := make([][]TID, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			out := make([]TID, perWorker)
+			for i := range out {
+				out[i] = clk.Next()
+			}
+			results[w] = out
+		}(w)
+	}
+	wg.Wait()
+
+	seen := make(map[TID]bool, workers*perWorker)
+	for _, out := range results {
+		for i, tid := range out {
+			assert.False(seen[tid], "duplicate TID: %s", tid)
+			seen[tid] = true
+			assert.Equal(uint(7), tid.ClockID())
+			if i > 0 {
+				// strictly increasing, both as integer and in string sort order
+				assert.Greater(tid.Integer(), out[i-1].Integer())
+				assert.Greater(tid.String(), out[i-1].String())
+			}
+		}
+	}
+	assert.Equal(workers*perWorker, len(seen))
+}
@@ -3,6 +3,7 @@ package helpers
 import (
 	"fmt"
 
+	comatproto "github.com/bluesky-social/indigo/api/atproto"
 	appbsky "github.com/bluesky-social/indigo/api/bsky"
 	"github.com/bluesky-social/indigo/atproto/syntax"
 	"github.com/bluesky-social/indigo/automod"
@@ -209,6 +210,14 @@ func ParentOrRootIsFollower(c *automod.RecordContext, post *appbsky.FeedPost) bo
 	return false
 }
 
+// PostReplyRefs returns the thread root and immediate parent of a reply post. ok is false (and both refs nil) for top-level posts, and for malformed replies missing either ref.
+func PostReplyRefs(post *appbsky.FeedPost) (root, parent *comatproto.RepoStrongRef, ok bool) {
+	if post == nil || post.Reply == nil || post.Reply.Root == nil || post.Reply.Parent == nil {
+		return nil, nil, false
+	}
+	return post.Reply.Root, post.Reply.Parent, true
+}
+
 func PostParentOrRootIsDid(post *appbsky.FeedPost, did string) bool {
 	if post.Reply == nil {
 		return false
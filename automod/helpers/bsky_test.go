@@ -139,3 +139,46 @@ func TestPostMentionsDid(t *testing.T) {
 	assert.True(PostMentionsAnyDid(post, didList1))
 	assert.False(PostMentionsAnyDid(post, didList2))
 }
+
+func TestPostReplyRefs(t *testing.T) {
+	assert := assert.New(t)
+
+	// top-level post
+	root, parent, ok := PostReplyRefs(&appbsky.FeedPost{Text: "hello"})
+	assert.False(ok)
+	assert.Nil(root)
+	assert.Nil(parent)
+
+	_, _, ok = PostReplyRefs(nil)
+	assert.False(ok)
+
+	// reply
+	rootRef := &comatproto.RepoStrongRef{
+		Uri: "at://did:plc:abc123/app.bsky.feed.post/rkey123",
+		Cid: "bafyreie5737gdxlw5i64vzichcalba3z2v5n6icifvx5xytvske7mr3hpm",
+	}
+	parentRef := &comatproto.RepoStrongRef{
+		Uri: "at://did:plc:321abc/app.bsky.feed.post/rkey456",
+		Cid: "bafyreihnowezerzbuebxucsbktbjt4qdsnr4vhalmbejvhcl5vs6dojryq",
+	}
+	reply := &appbsky.FeedPost{
+		Text: "a reply",
+		Reply: &appbsky.FeedPost_ReplyRef{
+			Root:   rootRef,
+			Parent: parentRef,
+		},
+	}
+	root, parent, ok = PostReplyRefs(reply)
+	assert.True(ok)
+	assert.Equal(rootRef, root)
+	assert.Equal(parentRef, parent)
+
+	// malformed reply, missing the parent ref
+	root, parent, ok = PostReplyRefs(&appbsky.FeedPost{
+		Text:  "broken reply",
+		Reply: &appbsky.FeedPost_ReplyRef{Root: rootRef},
+	})
+	assert.False(ok)
+	assert.Nil(root)
+	assert.Nil(parent)
+}
@@ -86,6 +86,22 @@ func run(args []string) error {
 			Value:   "palomar_profile",
 			Sources: cli.EnvVars("ES_PROFILE_INDEX"),
 		},
+		&cli.StringFlag{
+			Name:    "profile-sort-field",
+			Usage:   "numeric field to rank profile search results by (eg, 'pagerank'); empty for relevance only",
+			Sources: cli.EnvVars("PALOMAR_PROFILE_SORT_FIELD"),
+		},
+		&cli.DurationFlag{
+			Name:    "query-cache-ttl",
+			Usage:   "if set, cache identical search query responses for this long",
+			Sources: cli.EnvVars("PALOMAR_QUERY_CACHE_TTL"),
+		},
+		&cli.IntFlag{
+			Name:    "query-cache-size",
+			Usage:   "maximum number of cached search query responses",
+			Value:   1000,
+			Sources: cli.EnvVars("PALOMAR_QUERY_CACHE_SIZE"),
+		},
 		&cli.StringFlag{
 			Name:    "atp-relay-host",
 			Usage:   "hostname and port of Relay to subscribe to",
@@ -262,9 +278,12 @@ var runCmd = &cli.Command{
 		dir := identity.NewCacheDirectory(&base, 1_500_000, time.Hour*24, time.Minute*2, time.Minute*5)
 
 		apiConfig := search.ServerConfig{
-			Logger:       logger,
-			ProfileIndex: cmd.String("es-profile-index"),
-			PostIndex:    cmd.String("es-post-index"),
+			Logger:           logger,
+			ProfileIndex:     cmd.String("es-profile-index"),
+			PostIndex:        cmd.String("es-post-index"),
+			ProfileSortField: cmd.String("profile-sort-field"),
+			QueryCacheTTL:    cmd.Duration("query-cache-ttl"),
+			QueryCacheSize:   cmd.Int("query-cache-size"),
 		}
 
 		srv, err := search.NewServer(escli, &dir, apiConfig)
@@ -400,7 +419,7 @@ var searchPostCmd = &cli.Command{
 		res, err := search.DoSearchPosts(
 			context.Background(),
 			identity.DefaultDirectory(), // TODO: parse PLC arg
-			escli,
+			search.NewClientSearcher(escli),
 			cmd.String("es-post-index"),
 			&search.PostSearchParams{
 				Query:  strings.Join(cmd.Args().Slice(), " "),
@@ -432,7 +451,7 @@ var searchProfileCmd = &cli.Command{
 		if cmd.Bool("typeahead") {
 			res, err := search.DoSearchProfilesTypeahead(
 				context.Background(),
-				escli,
+				search.NewClientSearcher(escli),
 				cmd.String("es-profile-index"),
 				&search.ActorSearchParams{
 					Query: strings.Join(cmd.Args().Slice(), " "),
@@ -447,7 +466,7 @@ var searchProfileCmd = &cli.Command{
 			res, err := search.DoSearchProfiles(
 				context.Background(),
 				identity.DefaultDirectory(), // TODO: parse PLC arg
-				escli,
+				search.NewClientSearcher(escli),
 				cmd.String("es-profile-index"),
 				&search.ActorSearchParams{
 					Query:  strings.Join(cmd.Args().Slice(), " "),
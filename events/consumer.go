@@ -10,6 +10,7 @@ import (
 
 	"github.com/RussellLuo/slidingwindow"
 	comatproto "github.com/bluesky-social/indigo/api/atproto"
+	"github.com/bluesky-social/indigo/atproto/syntax"
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/gorilla/websocket"
@@ -24,28 +25,57 @@ type RepoStreamCallbacks struct {
 	LabelLabels  func(evt *comatproto.LabelSubscribeLabels_Labels) error
 	LabelInfo    func(evt *comatproto.LabelSubscribeLabels_Info) error
 	Error        func(evt *ErrorFrame) error
+
+	// Optional instrumentation hooks, called for every event passed to EventHandler. If nil, no metrics are recorded.
+	Metrics Metrics
 }
 
 func (rsc *RepoStreamCallbacks) EventHandler(ctx context.Context, xev *XRPCStreamEvent) error {
+	m := rsc.Metrics
+	if m == nil {
+		_, err := rsc.dispatch(xev)
+		return err
+	}
+	if ts, ok := xev.eventTime(); ok {
+		// strict parsing, same as SyncSubscribeRepos_Commit.Lag
+		if t, err := syntax.ParseDatetimeTime(ts); err == nil {
+			m.ObserveLag(time.Since(t).Seconds())
+		}
+	}
+
+	handled, err := rsc.dispatch(xev)
+	switch {
+	case err != nil:
+		m.IncEvent(xev.MsgType(), OutcomeError)
+	case !handled:
+		m.IncEvent(xev.MsgType(), OutcomeIgnored)
+	default:
+		m.IncEvent(xev.MsgType(), OutcomeOK)
+	}
+	return err
+}
+
+// calls the matching callback, if any, returning whether one was called
+func (rsc *RepoStreamCallbacks) dispatch(xev *XRPCStreamEvent) (bool, error) {
 	switch {
 	case xev.RepoCommit != nil && rsc.RepoCommit != nil:
-		return rsc.RepoCommit(xev.RepoCommit)
+		return true, rsc.RepoCommit(xev.RepoCommit)
 	case xev.RepoSync != nil && rsc.RepoSync != nil:
-		return rsc.RepoSync(xev.RepoSync)
+		return true, rsc.RepoSync(xev.RepoSync)
 	case xev.RepoInfo != nil && rsc.RepoInfo != nil:
-		return rsc.RepoInfo(xev.RepoInfo)
+		return true, rsc.RepoInfo(xev.RepoInfo)
 	case xev.RepoIdentity != nil && rsc.RepoIdentity != nil:
-		return rsc.RepoIdentity(xev.RepoIdentity)
+		return true, rsc.RepoIdentity(xev.RepoIdentity)
 	case xev.RepoAccount != nil && rsc.RepoAccount != nil:
-		return rsc.RepoAccount(xev.RepoAccount)
+		return true, rsc.RepoAccount(xev.RepoAccount)
 	case xev.LabelLabels != nil && rsc.LabelLabels != nil:
-		return rsc.LabelLabels(xev.LabelLabels)
+		return true, rsc.LabelLabels(xev.LabelLabels)
 	case xev.LabelInfo != nil && rsc.LabelInfo != nil:
-		return rsc.LabelInfo(xev.LabelInfo)
+		return true, rsc.LabelInfo(xev.LabelInfo)
 	case xev.Error != nil && rsc.Error != nil:
-		return rsc.Error(xev.Error)
+		return true, rsc.Error(xev.Error)
 	default:
-		return nil
+		return false, nil
 	}
 }
 
@@ -195,6 +225,22 @@ func HandleRepoStream(ctx context.Context, con *websocket.Conn, sched Scheduler,
 
 		eventsFromStreamCounter.WithLabelValues(remoteAddr).Inc()
 
+		// unknown ops are left to the hard error below
+		if err := header.Validate(); err != nil {
+			switch {
+			case header.Op == EvtKindMessage && header.MsgType != "":
+				// consumers are expected to ignore message types they don't know about
+				log.Debug("skipping event with unknown message type", "t", header.MsgType)
+				continue
+			case header.Op == EvtKindMessage:
+				log.Warn("skipping event with invalid header", "err", err)
+				continue
+			case header.Op == EvtKindErrorFrame:
+				// still deliver the error: the server is about to close the stream, and the caller needs to know why
+				log.Warn("error frame with invalid header", "err", err)
+			}
+		}
+
 		switch header.Op {
 		case EvtKindMessage:
 			switch header.MsgType {
@@ -0,0 +1,119 @@
+package events
+
+import (
+	"bytes"
+	"fmt"
+
+	comatproto "github.com/bluesky-social/indigo/api/atproto"
+)
+
+// Event is a decoded event stream message. It is implemented by each of the concrete event types in this package (CommitEvent, SyncEvent, IdentityEvent, etc), so callers can use a type switch on the result of [DecodeEvent].
+type Event interface {
+	// MsgType returns the header message type for this event (eg, "#commit"), or empty string for error frames
+	MsgType() string
+}
+
+type CommitEvent struct {
+	*comatproto.SyncSubscribeRepos_Commit
+}
+
+type SyncEvent struct {
+	*comatproto.SyncSubscribeRepos_Sync
+}
+
+type IdentityEvent struct {
+	*comatproto.SyncSubscribeRepos_Identity
+}
+
+type AccountEvent struct {
+	*comatproto.SyncSubscribeRepos_Account
+}
+
+type InfoEvent struct {
+	*comatproto.SyncSubscribeRepos_Info
+}
+
+type LabelsEvent struct {
+	*comatproto.LabelSubscribeLabels_Labels
+}
+
+type ErrorEvent struct {
+	*ErrorFrame
+}
+
+func (CommitEvent) MsgType() string   { return "#commit" }
+func (SyncEvent) MsgType() string     { return "#sync" }
+func (IdentityEvent) MsgType() string { return "#identity" }
+func (AccountEvent) MsgType() string  { return "#account" }
+func (InfoEvent) MsgType() string     { return "#info" }
+func (LabelsEvent) MsgType() string   { return "#labels" }
+func (ErrorEvent) MsgType() string    { return "" }
+
+// DecodeEvent decodes the payload (the CBOR object following the header) of an event stream frame, returning a typed Event based on the header.
+//
+// The header is validated first; see [EventHeader.Validate].
+func DecodeEvent(header *EventHeader, payload []byte) (Event, error) {
+	if err := header.Validate(); err != nil {
+		return nil, err
+	}
+	r := bytes.NewReader(payload)
+
+	if header.Op == EvtKindErrorFrame {
+		var evt ErrorFrame
+		if err := evt.UnmarshalCBOR(r); err != nil {
+			return nil, fmt.Errorf("reading error frame: %w", err)
+		}
+		return ErrorEvent{&evt}, nil
+	}
+
+	switch header.MsgType {
+	case "#commit":
+		var evt comatproto.SyncSubscribeRepos_Commit
+		if err := evt.UnmarshalCBOR(r); err != nil {
+			return nil, fmt.Errorf("reading repoCommit event: %w", err)
+		}
+		return CommitEvent{&evt}, nil
+	case "#sync":
+		var evt comatproto.SyncSubscribeRepos_Sync
+		if err := evt.UnmarshalCBOR(r); err != nil {
+			return nil, fmt.Errorf("reading repoSync event: %w", err)
+		}
+		return SyncEvent{&evt}, nil
+	case "#identity":
+		var evt comatproto.SyncSubscribeRepos_Identity
+		if err := evt.UnmarshalCBOR(r); err != nil {
+			return nil, fmt.Errorf("reading repoIdentity event: %w", err)
+		}
+		return IdentityEvent{&evt}, nil
+	case "#account":
+		var evt comatproto.SyncSubscribeRepos_Account
+		if err := evt.UnmarshalCBOR(r); err != nil {
+			return nil, fmt.Errorf("reading repoAccount event: %w", err)
+		}
+		return AccountEvent{&evt}, nil
+	case "#info":
+		// TODO: this might also be a LabelInfo (as opposed to RepoInfo)
+		var evt comatproto.SyncSubscribeRepos_Info
+		if err := evt.UnmarshalCBOR(r); err != nil {
+			return nil, fmt.Errorf("reading repoInfo event: %w", err)
+		}
+		return InfoEvent{&evt}, nil
+	case "#labels":
+		var evt comatproto.LabelSubscribeLabels_Labels
+		if err := evt.UnmarshalCBOR(r); err != nil {
+			return nil, fmt.Errorf("reading Labels event: %w", err)
+		}
+		return LabelsEvent{&evt}, nil
+	}
+	return nil, &InvalidHeaderError{Op: header.Op, MsgType: header.MsgType, Reason: "unknown message type"}
+}
+
+// DecodeFrame decodes a complete event stream frame (header followed by payload), as would be received in a single WebSocket message.
+func DecodeFrame(frame []byte) (Event, error) {
+	r := bytes.NewReader(frame)
+	var header EventHeader
+	if err := header.UnmarshalCBOR(r); err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	return DecodeEvent(&header, frame[len(frame)-r.Len():])
+}
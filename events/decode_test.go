@@ -0,0 +1,99 @@
+package events
+
+import (
+	"bytes"
+	"testing"
+
+	comatproto "github.com/bluesky-social/indigo/api/atproto"
+	lexutil "github.com/bluesky-social/indigo/lex/util"
+
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multihash"
+	"github.com/stretchr/testify/assert"
+)
+
+func serializeTestEvent(t *testing.T, evt *XRPCStreamEvent) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := evt.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func testCID(t *testing.T, data string) cid.Cid {
+	t.Helper()
+	c, err := cid.NewPrefixV1(cid.DagCBOR, multihash.SHA2_256).Sum([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestDecodeEvent(t *testing.T) {
+	assert := assert.New(t)
+
+	handle := "alice.example.com"
+	msg := "cursor too old"
+	fixtures := []*XRPCStreamEvent{
+		{RepoCommit: &comatproto.SyncSubscribeRepos_Commit{
+			Repo:   "did:plc:abc123",
+			Rev:    "3jzfcijpj2z2a",
+			Seq:    101,
+			Time:   "2024-01-02T03:04:05.006Z",
+			Commit: lexutil.LexLink(testCID(t, "commit")),
+			Blocks: lexutil.LexBytes{},
+			Ops:    []*comatproto.SyncSubscribeRepos_RepoOp{},
+			Blobs:  []lexutil.LexLink{},
+		}},
+		{RepoSync: &comatproto.SyncSubscribeRepos_Sync{Did: "did:plc:abc123", Rev: "3jzfcijpj2z2a", Seq: 102, Blocks: []byte{}}},
+		{RepoIdentity: &comatproto.SyncSubscribeRepos_Identity{Did: "did:plc:abc123", Handle: &handle, Seq: 103}},
+		{RepoAccount: &comatproto.SyncSubscribeRepos_Account{Did: "did:plc:abc123", Active: true, Seq: 104}},
+		{RepoInfo: &comatproto.SyncSubscribeRepos_Info{Name: "OutdatedCursor", Message: &msg}},
+		{Error: &ErrorFrame{Error: "FutureCursor", Message: "cursor in the future"}},
+	}
+
+	for _, fix := range fixtures {
+		evt, err := DecodeFrame(serializeTestEvent(t, fix))
+		if !assert.NoError(err) {
+			continue
+		}
+		switch v := evt.(type) {
+		case CommitEvent:
+			assert.Equal("#commit", v.MsgType())
+			assert.Equal(int64(101), v.Seq)
+			assert.Equal("did:plc:abc123", v.Repo)
+		case SyncEvent:
+			assert.Equal(int64(102), v.Seq)
+		case IdentityEvent:
+			assert.Equal(int64(103), v.Seq)
+			assert.Equal(handle, *v.Handle)
+		case AccountEvent:
+			assert.Equal(int64(104), v.Seq)
+			assert.True(v.Active)
+		case InfoEvent:
+			assert.Equal("OutdatedCursor", v.Name)
+		case ErrorEvent:
+			assert.Equal("", v.MsgType())
+			assert.Equal("FutureCursor", v.Error)
+		default:
+			t.Fatalf("unexpected event type: %T", evt)
+		}
+	}
+
+	// labels frames aren't handled by XRPCStreamEvent.Serialize, so encode the payload directly
+	var buf bytes.Buffer
+	labels := comatproto.LabelSubscribeLabels_Labels{Seq: 105, Labels: []*comatproto.LabelDefs_Label{}}
+	assert.NoError(labels.MarshalCBOR(&buf))
+	evt, err := DecodeEvent(&EventHeader{Op: EvtKindMessage, MsgType: "#labels"}, buf.Bytes())
+	assert.NoError(err)
+	le, ok := evt.(LabelsEvent)
+	assert.True(ok)
+	assert.Equal(int64(105), le.Seq)
+
+	// invalid header or payload
+	_, err = DecodeEvent(&EventHeader{Op: EvtKindMessage, MsgType: "#unknown"}, buf.Bytes())
+	assert.Error(err)
+	_, err = DecodeEvent(&EventHeader{Op: EvtKindMessage, MsgType: "#commit"}, []byte{0x01})
+	assert.Error(err)
+}
@@ -161,6 +161,47 @@ type EventHeader struct {
 	MsgType string `cborgen:"t,omitempty"`
 }
 
+// Message types (header `t` values) which may appear on repo or label event streams
+var KnownMessageTypes = map[string]bool{
+	"#commit":   true,
+	"#sync":     true,
+	"#identity": true,
+	"#account":  true,
+	"#info":     true,
+	"#labels":   true,
+}
+
+// Error returned by [EventHeader.Validate] for malformed or unsupported frame headers
+type InvalidHeaderError struct {
+	Op      int64
+	MsgType string
+	Reason  string
+}
+
+func (e *InvalidHeaderError) Error() string {
+	return fmt.Sprintf("invalid event header (op=%d t=%q): %s", e.Op, e.MsgType, e.Reason)
+}
+
+// Validate checks that the header has a known op, and that the message type is consistent with the op: message frames must have a known type, and error frames must not have a type.
+func (h *EventHeader) Validate() error {
+	switch h.Op {
+	case EvtKindMessage:
+		if h.MsgType == "" {
+			return &InvalidHeaderError{Op: h.Op, MsgType: h.MsgType, Reason: "message frame missing type"}
+		}
+		if !KnownMessageTypes[h.MsgType] {
+			return &InvalidHeaderError{Op: h.Op, MsgType: h.MsgType, Reason: "unknown message type"}
+		}
+	case EvtKindErrorFrame:
+		if h.MsgType != "" {
+			return &InvalidHeaderError{Op: h.Op, MsgType: h.MsgType, Reason: "error frame should not have a type"}
+		}
+	default:
+		return &InvalidHeaderError{Op: h.Op, MsgType: h.MsgType, Reason: "unknown op"}
+	}
+	return nil
+}
+
 var (
 	// AccountStatusActive is not in the spec but used internally
 	// the alternative would be an additional SQL column for "active" or status="" to imply active
@@ -483,6 +524,48 @@ func (evt *XRPCStreamEvent) GetSequence() (int64, bool) {
 	}
 }
 
+// MsgType returns the stream message type of the event (eg, "#commit"), "#error" for error frames, or an empty string if no event is set.
+func (evt *XRPCStreamEvent) MsgType() string {
+	switch {
+	case evt == nil:
+		return ""
+	case evt.RepoCommit != nil:
+		return "#commit"
+	case evt.RepoSync != nil:
+		return "#sync"
+	case evt.RepoIdentity != nil:
+		return "#identity"
+	case evt.RepoAccount != nil:
+		return "#account"
+	case evt.RepoInfo != nil, evt.LabelInfo != nil:
+		return "#info"
+	case evt.LabelLabels != nil:
+		return "#labels"
+	case evt.Error != nil:
+		return "#error"
+	default:
+		return ""
+	}
+}
+
+// returns the "time" field of the event, for types which have one
+func (evt *XRPCStreamEvent) eventTime() (string, bool) {
+	switch {
+	case evt == nil:
+		return "", false
+	case evt.RepoCommit != nil:
+		return evt.RepoCommit.Time, true
+	case evt.RepoSync != nil:
+		return evt.RepoSync.Time, true
+	case evt.RepoIdentity != nil:
+		return evt.RepoIdentity.Time, true
+	case evt.RepoAccount != nil:
+		return evt.RepoAccount.Time, true
+	default:
+		return "", false
+	}
+}
+
 func (em *EventManager) rmSubscriber(sub *Subscriber) {
 	em.subsLk.Lock()
 	defer em.subsLk.Unlock()
@@ -0,0 +1,43 @@
+package events
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventHeaderValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	valid := []EventHeader{
+		{Op: EvtKindMessage, MsgType: "#commit"},
+		{Op: EvtKindMessage, MsgType: "#sync"},
+		{Op: EvtKindMessage, MsgType: "#identity"},
+		{Op: EvtKindMessage, MsgType: "#account"},
+		{Op: EvtKindMessage, MsgType: "#info"},
+		{Op: EvtKindMessage, MsgType: "#labels"},
+		{Op: EvtKindErrorFrame},
+	}
+	for _, h := range valid {
+		assert.NoError(h.Validate(), h)
+	}
+
+	invalid := []EventHeader{
+		{Op: EvtKindMessage},
+		{Op: EvtKindMessage, MsgType: "#handle"},
+		{Op: EvtKindMessage, MsgType: "commit"},
+		{Op: EvtKindErrorFrame, MsgType: "#commit"},
+		{Op: 0, MsgType: "#commit"},
+		{Op: 2},
+		{Op: -2, MsgType: "#commit"},
+	}
+	for _, h := range invalid {
+		err := h.Validate()
+		assert.Error(err, h)
+		var hdrErr *InvalidHeaderError
+		assert.True(errors.As(err, &hdrErr))
+		assert.Equal(h.Op, hdrErr.Op)
+		assert.Equal(h.MsgType, hdrErr.MsgType)
+	}
+}
@@ -0,0 +1,93 @@
+package events
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// MaxFrameLogFrameSize is the largest frame [FrameLogReader] will read. It is well above the size of any legitimate firehose frame, and guards against allocating huge buffers when reading a corrupt log.
+const MaxFrameLogFrameSize = 16 << 20
+
+// FrameLogWriter appends raw event stream frames to an underlying writer, for debugging and later replay with [FrameLogReader].
+//
+// Each frame is written as a 4-byte big-endian length prefix followed by the frame bytes (the CBOR header and payload, exactly as received in a WebSocket message). It is safe for concurrent use.
+type FrameLogWriter struct {
+	lk sync.Mutex
+	w  io.Writer
+}
+
+func NewFrameLogWriter(w io.Writer) *FrameLogWriter {
+	return &FrameLogWriter{w: w}
+}
+
+// WriteFrame appends a single raw frame to the log.
+func (fw *FrameLogWriter) WriteFrame(frame []byte) error {
+	if len(frame) > MaxFrameLogFrameSize {
+		return fmt.Errorf("frame too large for log: %d bytes", len(frame))
+	}
+	var prefix [4]byte
+	binary.BigEndian.PutUint32(prefix[:], uint32(len(frame)))
+
+	fw.lk.Lock()
+	defer fw.lk.Unlock()
+
+	if _, err := fw.w.Write(prefix[:]); err != nil {
+		return fmt.Errorf("writing frame length: %w", err)
+	}
+	if _, err := fw.w.Write(frame); err != nil {
+		return fmt.Errorf("writing frame: %w", err)
+	}
+	return nil
+}
+
+// WriteEvent serializes evt as a frame and appends it to the log.
+func (fw *FrameLogWriter) WriteEvent(evt *XRPCStreamEvent) error {
+	var buf bytes.Buffer
+	if err := evt.Serialize(&buf); err != nil {
+		return fmt.Errorf("serializing event: %w", err)
+	}
+	return fw.WriteFrame(buf.Bytes())
+}
+
+// FrameLogReader reads frames written by [FrameLogWriter], in the order they were written.
+type FrameLogReader struct {
+	r *bufio.Reader
+}
+
+func NewFrameLogReader(r io.Reader) *FrameLogReader {
+	return &FrameLogReader{r: bufio.NewReader(r)}
+}
+
+// ReadFrame returns the next raw frame from the log. It returns [io.EOF] at the end of the log, and [io.ErrUnexpectedEOF] if the log ends partway through a frame (eg, if the writer was interrupted).
+func (fr *FrameLogReader) ReadFrame() ([]byte, error) {
+	var prefix [4]byte
+	if _, err := io.ReadFull(fr.r, prefix[:]); err != nil {
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(prefix[:])
+	if size > MaxFrameLogFrameSize {
+		return nil, fmt.Errorf("frame length %d exceeds maximum", size)
+	}
+	frame := make([]byte, size)
+	if _, err := io.ReadFull(fr.r, frame); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return frame, nil
+}
+
+// Next reads the next frame from the log and decodes it with [DecodeFrame]. It returns [io.EOF] at the end of the log.
+func (fr *FrameLogReader) Next() (Event, error) {
+	frame, err := fr.ReadFrame()
+	if err != nil {
+		return nil, err
+	}
+	return DecodeFrame(frame)
+}
@@ -0,0 +1,78 @@
+package events
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	comatproto "github.com/bluesky-social/indigo/api/atproto"
+	lexutil "github.com/bluesky-social/indigo/lex/util"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFrameLogRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+
+	handle := "alice.example.com"
+	fixtures := []*XRPCStreamEvent{
+		{RepoCommit: &comatproto.SyncSubscribeRepos_Commit{
+			Repo:   "did:plc:abc123",
+			Rev:    "3jzfcijpj2z2a",
+			Seq:    1,
+			Time:   "2024-01-02T03:04:05.006Z",
+			Commit: lexutil.LexLink(testCID(t, "commit")),
+			Blocks: lexutil.LexBytes{},
+			Ops:    []*comatproto.SyncSubscribeRepos_RepoOp{},
+			Blobs:  []lexutil.LexLink{},
+		}},
+		{RepoIdentity: &comatproto.SyncSubscribeRepos_Identity{Did: "did:plc:abc123", Handle: &handle, Seq: 2}},
+		{RepoAccount: &comatproto.SyncSubscribeRepos_Account{Did: "did:plc:abc123", Active: true, Seq: 3}},
+		{RepoSync: &comatproto.SyncSubscribeRepos_Sync{Did: "did:plc:abc123", Rev: "3jzfcijpj2z2b", Seq: 4, Blocks: []byte{}}},
+		{RepoInfo: &comatproto.SyncSubscribeRepos_Info{Name: "OutdatedCursor"}},
+		{Error: &ErrorFrame{Error: "FutureCursor"}},
+	}
+
+	var buf bytes.Buffer
+	fw := NewFrameLogWriter(&buf)
+	for _, fix := range fixtures {
+		assert.NoError(fw.WriteEvent(fix))
+	}
+
+	fr := NewFrameLogReader(bytes.NewReader(buf.Bytes()))
+	var types []string
+	var seqs []int64
+	for {
+		evt, err := fr.Next()
+		if err == io.EOF {
+			break
+		}
+		if !assert.NoError(err) {
+			break
+		}
+		types = append(types, evt.MsgType())
+		switch v := evt.(type) {
+		case CommitEvent:
+			seqs = append(seqs, v.Seq)
+		case IdentityEvent:
+			seqs = append(seqs, v.Seq)
+			assert.Equal(handle, *v.Handle)
+		case AccountEvent:
+			seqs = append(seqs, v.Seq)
+		case SyncEvent:
+			seqs = append(seqs, v.Seq)
+		}
+	}
+	assert.Equal([]string{"#commit", "#identity", "#account", "#sync", "#info", ""}, types)
+	assert.Equal([]int64{1, 2, 3, 4}, seqs)
+
+	// a log truncated partway through a frame
+	fr = NewFrameLogReader(bytes.NewReader(buf.Bytes()[:buf.Len()-2]))
+	var err error
+	for range fixtures {
+		if _, err = fr.ReadFrame(); err != nil {
+			break
+		}
+	}
+	assert.ErrorIs(err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,58 @@
+package events
+
+import (
+	"context"
+	"fmt"
+
+	comatproto "github.com/bluesky-social/indigo/api/atproto"
+	"github.com/bluesky-social/indigo/atproto/identity"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+)
+
+// ApplyIdentityEvent updates an identity directory cache in response to a firehose `#identity` event, so that subsequent lookups return fresh data.
+//
+// Any cached entries for the DID, the previously cached handle, and the newly announced handle are purged, then the DID is re-resolved to warm the cache. `evt` may be either a `*comatproto.SyncSubscribeRepos_Identity` or an `*XRPCStreamEvent` wrapping one; other event types are ignored.
+func ApplyIdentityEvent(ctx context.Context, dir identity.Directory, evt any) error {
+	var ie *comatproto.SyncSubscribeRepos_Identity
+	switch v := evt.(type) {
+	case *comatproto.SyncSubscribeRepos_Identity:
+		ie = v
+	case *XRPCStreamEvent:
+		ie = v.RepoIdentity
+	}
+	if ie == nil {
+		return nil
+	}
+
+	did, err := syntax.ParseDID(ie.Did)
+	if err != nil {
+		return fmt.Errorf("invalid DID in identity event: %w", err)
+	}
+
+	// purge the old handle, if we had one cached
+	prev, err := dir.LookupDID(ctx, did)
+	if err == nil && !prev.Handle.IsInvalidHandle() {
+		if err := dir.Purge(ctx, prev.Handle.AtIdentifier()); err != nil {
+			return err
+		}
+	}
+
+	if ie.Handle != nil {
+		handle, err := syntax.ParseHandle(*ie.Handle)
+		if err == nil && !handle.IsInvalidHandle() {
+			if err := dir.Purge(ctx, handle.AtIdentifier()); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := dir.Purge(ctx, did.AtIdentifier()); err != nil {
+		return err
+	}
+
+	_, err = dir.LookupDID(ctx, did)
+	if err != nil {
+		return fmt.Errorf("refreshing identity: %w", err)
+	}
+	return nil
+}
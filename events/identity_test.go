@@ -0,0 +1,69 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	comatproto "github.com/bluesky-social/indigo/api/atproto"
+	"github.com/bluesky-social/indigo/atproto/identity"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyIdentityEvent(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	did := syntax.DID("did:plc:abc111")
+	inner := identity.NewMockDirectory()
+	inner.Insert(identity.Identity{
+		DID:         did,
+		Handle:      syntax.Handle("old.example.com"),
+		AlsoKnownAs: []string{"at://old.example.com"},
+	})
+	dir := identity.NewCacheDirectory(&inner, 100, time.Hour, time.Hour, time.Hour)
+
+	ident, err := dir.LookupDID(ctx, did)
+	assert.NoError(err)
+	assert.Equal("old.example.com", ident.Handle.String())
+
+	// handle changes upstream; cache is still stale
+	delete(inner.Handles, syntax.Handle("old.example.com"))
+	inner.Insert(identity.Identity{
+		DID:         did,
+		Handle:      syntax.Handle("new.example.com"),
+		AlsoKnownAs: []string{"at://new.example.com"},
+	})
+	ident, err = dir.LookupDID(ctx, did)
+	assert.NoError(err)
+	assert.Equal("old.example.com", ident.Handle.String())
+
+	newHandle := "new.example.com"
+	evt := &comatproto.SyncSubscribeRepos_Identity{
+		Did:    did.String(),
+		Handle: &newHandle,
+		Seq:    123,
+	}
+	assert.NoError(ApplyIdentityEvent(ctx, &dir, evt))
+
+	ident, err = dir.LookupDID(ctx, did)
+	assert.NoError(err)
+	assert.Equal("new.example.com", ident.Handle.String())
+
+	ident, err = dir.LookupHandle(ctx, syntax.Handle("new.example.com"))
+	if assert.NoError(err) {
+		assert.Equal(did, ident.DID)
+	}
+
+	// wrapped stream event, without a handle
+	assert.NoError(ApplyIdentityEvent(ctx, &dir, &XRPCStreamEvent{
+		RepoIdentity: &comatproto.SyncSubscribeRepos_Identity{Did: did.String(), Seq: 124},
+	}))
+
+	// other events are ignored
+	assert.NoError(ApplyIdentityEvent(ctx, &dir, &comatproto.SyncSubscribeRepos_Info{Name: "OutdatedCursor"}))
+
+	assert.Error(ApplyIdentityEvent(ctx, &dir, &comatproto.SyncSubscribeRepos_Identity{Did: "not-a-did"}))
+}
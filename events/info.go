@@ -0,0 +1,77 @@
+package events
+
+import (
+	"log/slog"
+
+	comatproto "github.com/bluesky-social/indigo/api/atproto"
+)
+
+// Known values for the "name" field of #info frames
+const (
+	// The requested cursor was older than the server's retained window, and the stream started from the oldest available event instead
+	InfoOutdatedCursor = "OutdatedCursor"
+)
+
+// InfoAction is what a consumer should do in response to an #info frame
+type InfoAction int
+
+const (
+	// Unrecognized info frame; log and continue
+	InfoActionNone InfoAction = iota
+	// Events were missed, so the consumer should backfill or resynchronize any affected state
+	InfoActionResync
+)
+
+func (a InfoAction) String() string {
+	switch a {
+	case InfoActionNone:
+		return "none"
+	case InfoActionResync:
+		return "resync"
+	default:
+		return "unknown"
+	}
+}
+
+// NewInfoFrame constructs an #info message body. An empty message is omitted from the frame.
+func NewInfoFrame(name, message string) *comatproto.SyncSubscribeRepos_Info {
+	info := &comatproto.SyncSubscribeRepos_Info{Name: name}
+	if message != "" {
+		info.Message = &message
+	}
+	return info
+}
+
+// ClassifyInfo maps an #info frame to the action a consumer should take
+func ClassifyInfo(info *comatproto.SyncSubscribeRepos_Info) InfoAction {
+	switch info.Name {
+	case InfoOutdatedCursor:
+		return InfoActionResync
+	default:
+		return InfoActionNone
+	}
+}
+
+// InfoHandler returns a callback for [RepoStreamCallbacks.RepoInfo] which logs each info frame and calls onResync (if not nil) for frames indicating that events were missed.
+func InfoHandler(logger *slog.Logger, onResync func(info *comatproto.SyncSubscribeRepos_Info) error) func(info *comatproto.SyncSubscribeRepos_Info) error {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return func(info *comatproto.SyncSubscribeRepos_Info) error {
+		action := ClassifyInfo(info)
+		var msg string
+		if info.Message != nil {
+			msg = *info.Message
+		}
+		switch action {
+		case InfoActionResync:
+			logger.Warn("info event indicates missed events", "name", info.Name, "message", msg, "action", action)
+			if onResync != nil {
+				return onResync(info)
+			}
+		default:
+			logger.Info("info event", "name", info.Name, "message", msg, "action", action)
+		}
+		return nil
+	}
+}
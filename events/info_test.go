@@ -0,0 +1,66 @@
+package events
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	comatproto "github.com/bluesky-social/indigo/api/atproto"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewInfoFrame(t *testing.T) {
+	assert := assert.New(t)
+
+	info := NewInfoFrame(InfoOutdatedCursor, "cursor too old")
+	assert.Equal("OutdatedCursor", info.Name)
+	assert.Equal("cursor too old", *info.Message)
+
+	info = NewInfoFrame(InfoOutdatedCursor, "")
+	assert.Nil(info.Message)
+
+	// round-trips through a stream frame
+	frame := serializeTestEvent(t, &XRPCStreamEvent{RepoInfo: NewInfoFrame(InfoOutdatedCursor, "cursor too old")})
+	evt, err := DecodeFrame(frame)
+	assert.NoError(err)
+	decoded, ok := evt.(InfoEvent)
+	assert.True(ok)
+	assert.Equal(InfoOutdatedCursor, decoded.Name)
+	assert.Equal("cursor too old", *decoded.Message)
+}
+
+func TestClassifyInfo(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(InfoActionResync, ClassifyInfo(NewInfoFrame(InfoOutdatedCursor, "")))
+	assert.Equal(InfoActionNone, ClassifyInfo(NewInfoFrame("SomethingNew", "")))
+	assert.Equal(InfoActionNone, ClassifyInfo(NewInfoFrame("", "")))
+	assert.Equal("resync", InfoActionResync.String())
+	assert.Equal("none", InfoActionNone.String())
+}
+
+func TestInfoHandler(t *testing.T) {
+	assert := assert.New(t)
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+
+	var resynced []string
+	handler := InfoHandler(logger, func(info *comatproto.SyncSubscribeRepos_Info) error {
+		resynced = append(resynced, info.Name)
+		return nil
+	})
+	rsc := RepoStreamCallbacks{RepoInfo: handler}
+
+	assert.NoError(rsc.EventHandler(t.Context(), &XRPCStreamEvent{RepoInfo: NewInfoFrame("SomethingNew", "hello")}))
+	assert.Empty(resynced)
+	assert.Contains(buf.String(), "name=SomethingNew")
+
+	assert.NoError(rsc.EventHandler(t.Context(), &XRPCStreamEvent{RepoInfo: NewInfoFrame(InfoOutdatedCursor, "")}))
+	assert.Equal([]string{InfoOutdatedCursor}, resynced)
+	assert.Contains(buf.String(), "action=resync")
+
+	// a nil resync callback is allowed
+	assert.NoError(InfoHandler(logger, nil)(NewInfoFrame(InfoOutdatedCursor, "")))
+}
@@ -0,0 +1,78 @@
+package events
+
+import (
+	"fmt"
+
+	comatproto "github.com/bluesky-social/indigo/api/atproto"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+)
+
+// LifecycleCallbacks are invoked by [HandleAccountLifecycle] for account lifecycle changes. Any callback may be nil, in which case the corresponding events are ignored.
+//
+// The legacy `#migrate` and `#tombstone` firehose messages have been removed from the protocol: account migration is now signaled by an `#identity` event (the DID document changed), and account deletion by an `#account` event with status "deleted".
+type LifecycleCallbacks struct {
+	// Account was deleted (formerly `#tombstone`). Consumers should clear all local state for the DID.
+	Tombstone func(did syntax.DID, evt *comatproto.SyncSubscribeRepos_Account) error
+	// Account is no longer active for a reason other than deletion, such as "deactivated" or "takendown". Status may be empty if the host did not give a reason.
+	Deactivated func(did syntax.DID, status string, evt *comatproto.SyncSubscribeRepos_Account) error
+	// Account is active (again)
+	Activated func(did syntax.DID, evt *comatproto.SyncSubscribeRepos_Account) error
+	// Account identity changed, including migration to a new PDS (formerly `#migrate`). Consumers should refresh any cached identity data.
+	Identity func(did syntax.DID, evt *comatproto.SyncSubscribeRepos_Identity) error
+}
+
+// HandleAccountLifecycle routes `#account` and `#identity` events to the matching typed callback in cb.
+//
+// `evt` may be a bare `*comatproto.SyncSubscribeRepos_Account` or `*comatproto.SyncSubscribeRepos_Identity`, an [AccountEvent] or [IdentityEvent] from [DecodeEvent], or an `*XRPCStreamEvent` wrapping one; other event types are ignored.
+func HandleAccountLifecycle(evt any, cb LifecycleCallbacks) error {
+	var acct *comatproto.SyncSubscribeRepos_Account
+	var ident *comatproto.SyncSubscribeRepos_Identity
+	switch v := evt.(type) {
+	case *comatproto.SyncSubscribeRepos_Account:
+		acct = v
+	case *comatproto.SyncSubscribeRepos_Identity:
+		ident = v
+	case AccountEvent:
+		acct = v.SyncSubscribeRepos_Account
+	case IdentityEvent:
+		ident = v.SyncSubscribeRepos_Identity
+	case *XRPCStreamEvent:
+		acct = v.RepoAccount
+		ident = v.RepoIdentity
+	}
+
+	switch {
+	case acct != nil:
+		did, err := syntax.ParseDID(acct.Did)
+		if err != nil {
+			return fmt.Errorf("invalid DID in account event: %w", err)
+		}
+		var status string
+		if acct.Status != nil {
+			status = *acct.Status
+		}
+		switch {
+		case acct.Active:
+			if cb.Activated != nil {
+				return cb.Activated(did, acct)
+			}
+		case status == "deleted":
+			if cb.Tombstone != nil {
+				return cb.Tombstone(did, acct)
+			}
+		default:
+			if cb.Deactivated != nil {
+				return cb.Deactivated(did, status, acct)
+			}
+		}
+	case ident != nil:
+		did, err := syntax.ParseDID(ident.Did)
+		if err != nil {
+			return fmt.Errorf("invalid DID in identity event: %w", err)
+		}
+		if cb.Identity != nil {
+			return cb.Identity(did, ident)
+		}
+	}
+	return nil
+}
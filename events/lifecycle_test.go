@@ -0,0 +1,65 @@
+package events
+
+import (
+	"testing"
+
+	comatproto "github.com/bluesky-social/indigo/api/atproto"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleAccountLifecycle(t *testing.T) {
+	assert := assert.New(t)
+
+	var fired []string
+	cb := LifecycleCallbacks{
+		Tombstone: func(did syntax.DID, evt *comatproto.SyncSubscribeRepos_Account) error {
+			fired = append(fired, "tombstone:"+did.String())
+			return nil
+		},
+		Deactivated: func(did syntax.DID, status string, evt *comatproto.SyncSubscribeRepos_Account) error {
+			fired = append(fired, "deactivated:"+status)
+			return nil
+		},
+		Activated: func(did syntax.DID, evt *comatproto.SyncSubscribeRepos_Account) error {
+			fired = append(fired, "activated:"+did.String())
+			return nil
+		},
+		Identity: func(did syntax.DID, evt *comatproto.SyncSubscribeRepos_Identity) error {
+			fired = append(fired, "identity:"+did.String())
+			return nil
+		},
+	}
+
+	did := "did:plc:abc123"
+	status := func(s string) *string { return &s }
+
+	testCases := []struct {
+		evt      any
+		expected string
+	}{
+		{&comatproto.SyncSubscribeRepos_Account{Did: did, Active: false, Status: status("deleted")}, "tombstone:" + did},
+		{&comatproto.SyncSubscribeRepos_Account{Did: did, Active: false, Status: status("takendown")}, "deactivated:takendown"},
+		{&comatproto.SyncSubscribeRepos_Account{Did: did, Active: false}, "deactivated:"},
+		{&comatproto.SyncSubscribeRepos_Account{Did: did, Active: true}, "activated:" + did},
+		{&comatproto.SyncSubscribeRepos_Identity{Did: did}, "identity:" + did},
+		{&XRPCStreamEvent{RepoAccount: &comatproto.SyncSubscribeRepos_Account{Did: did, Status: status("deleted")}}, "tombstone:" + did},
+		{&XRPCStreamEvent{RepoIdentity: &comatproto.SyncSubscribeRepos_Identity{Did: did}}, "identity:" + did},
+		{AccountEvent{&comatproto.SyncSubscribeRepos_Account{Did: did, Status: status("deactivated")}}, "deactivated:deactivated"},
+		{IdentityEvent{&comatproto.SyncSubscribeRepos_Identity{Did: did}}, "identity:" + did},
+	}
+	for _, tc := range testCases {
+		fired = nil
+		assert.NoError(HandleAccountLifecycle(tc.evt, cb))
+		assert.Equal([]string{tc.expected}, fired)
+	}
+
+	// other event types, and events with no matching callback, are ignored
+	fired = nil
+	assert.NoError(HandleAccountLifecycle(&XRPCStreamEvent{RepoInfo: NewInfoFrame(InfoOutdatedCursor, "")}, cb))
+	assert.NoError(HandleAccountLifecycle(&comatproto.SyncSubscribeRepos_Identity{Did: did}, LifecycleCallbacks{}))
+	assert.Empty(fired)
+
+	assert.Error(HandleAccountLifecycle(&comatproto.SyncSubscribeRepos_Account{Did: "not-a-did"}, cb))
+}
@@ -24,3 +24,46 @@ var eventsBroadcast = promauto.NewCounterVec(prometheus.CounterOpts{
 	Name: "indigo_events_broadcast_total",
 	Help: "Total number of events broadcast to subscribers",
 }, []string{"pool"})
+
+var eventsDispatched = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "indigo_events_dispatched_total",
+	Help: "Total number of stream events dispatched to handler callbacks, by message type and outcome",
+}, []string{"msg_type", "outcome"})
+
+var eventDispatchLag = promauto.NewHistogram(prometheus.HistogramOpts{
+	Name:    "indigo_events_dispatch_lag_seconds",
+	Help:    "Delay between an event's timestamp and its dispatch to handler callbacks",
+	Buckets: prometheus.ExponentialBuckets(0.01, 4, 10),
+})
+
+// Outcomes reported to [Metrics.IncEvent]
+const (
+	OutcomeOK      = "ok"
+	OutcomeError   = "error"
+	OutcomeIgnored = "ignored" // no callback registered for the message type
+)
+
+// Metrics receives instrumentation hooks from event dispatch (see [RepoStreamCallbacks]). Implementations must be safe for concurrent use, as schedulers may dispatch from multiple goroutines.
+type Metrics interface {
+	// Called once per dispatched event, with the message type (eg, "#commit", or "#error" for error frames) and one of the Outcome constants
+	IncEvent(msgType, outcome string)
+	// Called with the delay between the event's "time" field and dispatch, for event types which have that field
+	ObserveLag(seconds float64)
+}
+
+// NoopMetrics discards all metrics, the same as leaving [RepoStreamCallbacks.Metrics] unset.
+type NoopMetrics struct{}
+
+func (NoopMetrics) IncEvent(msgType, outcome string) {}
+func (NoopMetrics) ObserveLag(seconds float64)       {}
+
+// PrometheusMetrics records event metrics to the default Prometheus registry.
+type PrometheusMetrics struct{}
+
+func (PrometheusMetrics) IncEvent(msgType, outcome string) {
+	eventsDispatched.WithLabelValues(msgType, outcome).Inc()
+}
+
+func (PrometheusMetrics) ObserveLag(seconds float64) {
+	eventDispatchLag.Observe(seconds)
+}
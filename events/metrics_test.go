@@ -0,0 +1,93 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	comatproto "github.com/bluesky-social/indigo/api/atproto"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingMetrics struct {
+	lk     sync.Mutex
+	events map[string]string // msgType -> last outcome
+	lags   []float64
+}
+
+func (rm *recordingMetrics) IncEvent(msgType, outcome string) {
+	rm.lk.Lock()
+	defer rm.lk.Unlock()
+	rm.events[msgType] = outcome
+}
+
+func (rm *recordingMetrics) ObserveLag(seconds float64) {
+	rm.lk.Lock()
+	defer rm.lk.Unlock()
+	rm.lags = append(rm.lags, seconds)
+}
+
+func TestCallbackMetrics(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	m := &recordingMetrics{events: map[string]string{}}
+	rsc := &RepoStreamCallbacks{
+		RepoCommit:   func(evt *comatproto.SyncSubscribeRepos_Commit) error { return nil },
+		RepoSync:     func(evt *comatproto.SyncSubscribeRepos_Sync) error { return nil },
+		RepoIdentity: func(evt *comatproto.SyncSubscribeRepos_Identity) error { return nil },
+		RepoAccount:  func(evt *comatproto.SyncSubscribeRepos_Account) error { return errors.New("failed") },
+		RepoInfo:     func(evt *comatproto.SyncSubscribeRepos_Info) error { return nil },
+		LabelLabels:  func(evt *comatproto.LabelSubscribeLabels_Labels) error { return nil },
+		Error:        func(evt *ErrorFrame) error { return nil },
+		Metrics:      m,
+	}
+
+	then := syntax.DatetimeNow().String()
+	time.Sleep(5 * time.Millisecond)
+	evts := []*XRPCStreamEvent{
+		{RepoCommit: &comatproto.SyncSubscribeRepos_Commit{Seq: 1, Time: then}},
+		{RepoSync: &comatproto.SyncSubscribeRepos_Sync{Seq: 2, Time: then}},
+		{RepoIdentity: &comatproto.SyncSubscribeRepos_Identity{Seq: 3, Time: then}},
+		{RepoAccount: &comatproto.SyncSubscribeRepos_Account{Seq: 4, Time: then}},
+		{RepoInfo: &comatproto.SyncSubscribeRepos_Info{Name: "OutdatedCursor"}},
+		{LabelLabels: &comatproto.LabelSubscribeLabels_Labels{Seq: 5}},
+		{LabelInfo: &comatproto.LabelSubscribeLabels_Info{Name: "OutdatedCursor"}},
+		{Error: &ErrorFrame{Error: "FutureCursor"}},
+	}
+	for _, evt := range evts {
+		rsc.EventHandler(ctx, evt)
+	}
+
+	assert.Equal(map[string]string{
+		"#commit":   OutcomeOK,
+		"#sync":     OutcomeOK,
+		"#identity": OutcomeOK,
+		"#account":  OutcomeError,
+		// RepoInfo was handled, then LabelInfo was not
+		"#info":   OutcomeIgnored,
+		"#labels": OutcomeOK,
+		"#error":  OutcomeOK,
+	}, m.events)
+
+	// only the four types with a "time" field report lag
+	assert.Len(m.lags, 4)
+	for _, l := range m.lags {
+		assert.Greater(l, 0.0)
+	}
+
+	// malformed times are skipped, as by SyncSubscribeRepos_Commit.Lag
+	bad := &XRPCStreamEvent{RepoCommit: &comatproto.SyncSubscribeRepos_Commit{Seq: 6, Time: "2024-01-02 03:04:05"}}
+	_, err := bad.RepoCommit.Lag(time.Now())
+	assert.Error(err)
+	assert.NoError(rsc.EventHandler(ctx, bad))
+	assert.Len(m.lags, 4)
+
+	// no metrics configured is fine
+	rsc.Metrics = nil
+	assert.NoError(rsc.EventHandler(ctx, evts[0]))
+}
@@ -0,0 +1,77 @@
+package events
+
+import (
+	"sync"
+)
+
+// ReplayBuffer is a fixed-size, in-memory ring buffer of recent sequenced events.
+//
+// It is intended for consumers which want to re-process the last few events (eg, after a handler crash) without going back to the upstream service. Events without a sequence number (eg, info or error frames) are not retained.
+type ReplayBuffer struct {
+	lk sync.Mutex
+
+	events []*XRPCStreamEvent
+	// index of the next slot to write to
+	next int
+	// number of valid events in the buffer
+	count int
+
+	// highest sequence number which has been evicted from the buffer, or -1
+	evictedSeq int64
+}
+
+// NewReplayBuffer creates a buffer retaining up to capacity events.
+func NewReplayBuffer(capacity int) *ReplayBuffer {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &ReplayBuffer{
+		events:     make([]*XRPCStreamEvent, capacity),
+		evictedSeq: -1,
+	}
+}
+
+// Add appends an event to the buffer, evicting the oldest event if the buffer is full.
+func (rb *ReplayBuffer) Add(evt *XRPCStreamEvent) {
+	if _, ok := evt.GetSequence(); !ok {
+		return
+	}
+
+	rb.lk.Lock()
+	defer rb.lk.Unlock()
+
+	if rb.count == len(rb.events) {
+		if old := rb.events[rb.next].Sequence(); old > rb.evictedSeq {
+			rb.evictedSeq = old
+		}
+	} else {
+		rb.count++
+	}
+	rb.events[rb.next] = evt
+	rb.next = (rb.next + 1) % len(rb.events)
+}
+
+// Replay returns all retained events with sequence number greater than or equal to fromSeq, oldest first.
+//
+// The boolean return is false if events at or after fromSeq have already been evicted from the buffer, in which case the returned events are incomplete and the caller needs to fall back to some other source (like a full backfill).
+func (rb *ReplayBuffer) Replay(fromSeq int64) ([]*XRPCStreamEvent, bool) {
+	rb.lk.Lock()
+	defer rb.lk.Unlock()
+
+	var out []*XRPCStreamEvent
+	start := (rb.next - rb.count + len(rb.events)) % len(rb.events)
+	for i := 0; i < rb.count; i++ {
+		evt := rb.events[(start+i)%len(rb.events)]
+		if evt.Sequence() >= fromSeq {
+			out = append(out, evt)
+		}
+	}
+	return out, fromSeq > rb.evictedSeq
+}
+
+// Len returns the number of events currently retained.
+func (rb *ReplayBuffer) Len() int {
+	rb.lk.Lock()
+	defer rb.lk.Unlock()
+	return rb.count
+}
@@ -0,0 +1,72 @@
+package events
+
+import (
+	"testing"
+
+	comatproto "github.com/bluesky-social/indigo/api/atproto"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testCommitEvent(seq int64) *XRPCStreamEvent {
+	return &XRPCStreamEvent{
+		RepoCommit: &comatproto.SyncSubscribeRepos_Commit{
+			Repo: "did:plc:abc123",
+			Seq:  seq,
+		},
+	}
+}
+
+func seqs(evts []*XRPCStreamEvent) []int64 {
+	out := make([]int64, len(evts))
+	for i, e := range evts {
+		out[i] = e.Sequence()
+	}
+	return out
+}
+
+func TestReplayBuffer(t *testing.T) {
+	assert := assert.New(t)
+
+	rb := NewReplayBuffer(5)
+	out, ok := rb.Replay(0)
+	assert.True(ok)
+	assert.Empty(out)
+
+	// partially fill
+	for i := int64(1); i <= 3; i++ {
+		rb.Add(testCommitEvent(i))
+	}
+	// info frames have no sequence and are skipped
+	rb.Add(&XRPCStreamEvent{RepoInfo: &comatproto.SyncSubscribeRepos_Info{Name: "OutdatedCursor"}})
+	assert.Equal(3, rb.Len())
+
+	out, ok = rb.Replay(2)
+	assert.True(ok)
+	assert.Equal([]int64{2, 3}, seqs(out))
+
+	// fill and wrap around
+	for i := int64(4); i <= 8; i++ {
+		rb.Add(testCommitEvent(i))
+	}
+	assert.Equal(5, rb.Len())
+
+	out, ok = rb.Replay(0)
+	assert.False(ok)
+	assert.Equal([]int64{4, 5, 6, 7, 8}, seqs(out))
+
+	// still in buffer
+	out, ok = rb.Replay(6)
+	assert.True(ok)
+	assert.Equal([]int64{6, 7, 8}, seqs(out))
+
+	// evicted
+	out, ok = rb.Replay(3)
+	assert.False(ok)
+	assert.Equal([]int64{4, 5, 6, 7, 8}, seqs(out))
+
+	// beyond the end
+	out, ok = rb.Replay(9)
+	assert.True(ok)
+	assert.Empty(out)
+}
@@ -0,0 +1,135 @@
+package sharded
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"sync"
+
+	"github.com/bluesky-social/indigo/events"
+	"github.com/bluesky-social/indigo/events/schedulers"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Scheduler is a parallel scheduler which hashes the repo DID of each event to pick a worker.
+//
+// Because all events for a given DID always land on the same worker queue, they are processed strictly in the order they were added. Events for different DIDs are processed concurrently across workers.
+type Scheduler struct {
+	numWorkers int
+	queueSize  int
+
+	do func(context.Context, *events.XRPCStreamEvent) error
+
+	queues []chan *events.XRPCStreamEvent
+	wg     sync.WaitGroup
+
+	lk       sync.RWMutex
+	shutdown bool
+
+	ident string
+
+	// metrics
+	itemsAdded     prometheus.Counter
+	itemsProcessed prometheus.Counter
+	itemsActive    prometheus.Counter
+	workersActive  prometheus.Gauge
+
+	log *slog.Logger
+}
+
+// NewScheduler creates a scheduler with numWorkers workers, each with a buffered queue of queueSize events.
+func NewScheduler(numWorkers, queueSize int, ident string, do func(context.Context, *events.XRPCStreamEvent) error) *Scheduler {
+	if numWorkers < 1 {
+		numWorkers = 1
+	}
+	if queueSize < 0 {
+		queueSize = 0
+	}
+
+	p := &Scheduler{
+		numWorkers: numWorkers,
+		queueSize:  queueSize,
+
+		do: do,
+
+		queues: make([]chan *events.XRPCStreamEvent, numWorkers),
+
+		ident: ident,
+
+		itemsAdded:     schedulers.WorkItemsAdded.WithLabelValues(ident, "sharded"),
+		itemsProcessed: schedulers.WorkItemsProcessed.WithLabelValues(ident, "sharded"),
+		itemsActive:    schedulers.WorkItemsActive.WithLabelValues(ident, "sharded"),
+		workersActive:  schedulers.WorkersActive.WithLabelValues(ident, "sharded"),
+
+		log: slog.Default().With("system", "sharded-scheduler"),
+	}
+
+	for i := 0; i < numWorkers; i++ {
+		p.queues[i] = make(chan *events.XRPCStreamEvent, queueSize)
+		p.wg.Add(1)
+		go p.worker(p.queues[i])
+	}
+
+	p.workersActive.Set(float64(numWorkers))
+
+	return p
+}
+
+// Shutdown stops accepting new work, and blocks until all already-queued events have been processed.
+func (p *Scheduler) Shutdown() {
+	p.log.Info("shutting down sharded scheduler", "ident", p.ident)
+
+	p.lk.Lock()
+	if p.shutdown {
+		p.lk.Unlock()
+		return
+	}
+	p.shutdown = true
+	for _, q := range p.queues {
+		close(q)
+	}
+	p.lk.Unlock()
+
+	p.wg.Wait()
+	p.workersActive.Set(0)
+
+	p.log.Info("sharded scheduler shutdown complete")
+}
+
+// ErrShutdown is returned when work is added after the scheduler has been shut down.
+var ErrShutdown = fmt.Errorf("scheduler is shut down")
+
+func (p *Scheduler) AddWork(ctx context.Context, repo string, val *events.XRPCStreamEvent) error {
+	p.lk.RLock()
+	defer p.lk.RUnlock()
+	if p.shutdown {
+		return ErrShutdown
+	}
+
+	p.itemsAdded.Inc()
+	select {
+	case p.queues[p.shardForRepo(repo)] <- val:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *Scheduler) shardForRepo(repo string) int {
+	h := fnv.New32a()
+	h.Write([]byte(repo))
+	return int(h.Sum32() % uint32(p.numWorkers))
+}
+
+func (p *Scheduler) worker(queue chan *events.XRPCStreamEvent) {
+	defer p.wg.Done()
+	for evt := range queue {
+		p.itemsActive.Inc()
+		if err := p.do(context.TODO(), evt); err != nil {
+			p.log.Error("event handler failed", "err", err)
+		}
+		p.itemsProcessed.Inc()
+	}
+}
@@ -0,0 +1,77 @@
+package sharded
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	comatproto "github.com/bluesky-social/indigo/api/atproto"
+	"github.com/bluesky-social/indigo/events"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func commitEvent(did string, seq int64) *events.XRPCStreamEvent {
+	return &events.XRPCStreamEvent{
+		RepoCommit: &comatproto.SyncSubscribeRepos_Commit{
+			Repo: did,
+			Seq:  seq,
+		},
+	}
+}
+
+func TestShardedOrdering(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	var lk sync.Mutex
+	seen := make(map[string][]int64)
+	otherDone := make(chan struct{})
+
+	didA := "did:plc:aaaa"
+	var didB string
+
+	sched := NewScheduler(4, 100, "test", func(ctx context.Context, evt *events.XRPCStreamEvent) error {
+		did := evt.RepoCommit.Repo
+		// the first event for A waits until B has been processed, which can only happen if the two DIDs run concurrently
+		if did == didA && evt.RepoCommit.Seq == 0 {
+			select {
+			case <-otherDone:
+			case <-time.After(5 * time.Second):
+				return fmt.Errorf("timed out waiting for other DID")
+			}
+		}
+		lk.Lock()
+		seen[did] = append(seen[did], evt.RepoCommit.Seq)
+		lk.Unlock()
+		if did == didB {
+			close(otherDone)
+		}
+		return nil
+	})
+
+	// find a DID which hashes to a different worker
+	for i := 0; ; i++ {
+		didB = fmt.Sprintf("did:plc:bbbb%d", i)
+		if sched.shardForRepo(didB) != sched.shardForRepo(didA) {
+			break
+		}
+	}
+
+	for i := int64(0); i < 50; i++ {
+		assert.NoError(sched.AddWork(ctx, didA, commitEvent(didA, i)))
+	}
+	assert.NoError(sched.AddWork(ctx, didB, commitEvent(didB, 0)))
+
+	sched.Shutdown()
+
+	assert.Equal(1, len(seen[didB]))
+	assert.Equal(50, len(seen[didA]))
+	for i, seq := range seen[didA] {
+		assert.Equal(int64(i), seq)
+	}
+
+	assert.ErrorIs(sched.AddWork(ctx, didA, commitEvent(didA, 51)), ErrShutdown)
+}
@@ -0,0 +1,239 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// CursorStore persists the last-seen firehose sequence number, so that a subscription can resume where it left off after reconnecting or restarting.
+type CursorStore interface {
+	// Returns the stored cursor, or zero if none has been stored yet
+	GetCursor(ctx context.Context) (int64, error)
+	SetCursor(ctx context.Context, seq int64) error
+}
+
+// MemCursorStore is a [CursorStore] which only keeps the cursor in memory. It resumes across reconnects, but not across process restarts.
+type MemCursorStore struct {
+	lk  sync.Mutex
+	seq int64
+}
+
+func (m *MemCursorStore) GetCursor(ctx context.Context) (int64, error) {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	return m.seq, nil
+}
+
+func (m *MemCursorStore) SetCursor(ctx context.Context, seq int64) error {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	m.seq = seq
+	return nil
+}
+
+type SubscribeOptions struct {
+	// Where to persist the last-seen sequence number. Defaults to a [MemCursorStore].
+	Cursors CursorStore
+	// How often the cursor is written to Cursors while events are flowing. It is always written when a connection ends. Defaults to one second.
+	CursorFlushInterval time.Duration
+	// Opens a connection to the given URL. Defaults to a [websocket.Dialer] with a 10 second handshake timeout.
+	Dial func(ctx context.Context, u string) (*websocket.Conn, error)
+	// Delay before the first reconnection attempt. Doubles after each consecutive failure, up to MaxBackoff. Default 1 second.
+	MinBackoff time.Duration
+	// Default 30 seconds
+	MaxBackoff time.Duration
+	// Maximum number of consecutive connection attempts which receive no events, before giving up. Zero means retry forever.
+	MaxAttempts int
+	Logger      *slog.Logger
+}
+
+// Returned by [Subscribe] when the upstream host rejected the stored cursor as being in the future. This requires operator intervention (eg, resetting the cursor), so is not retried.
+var ErrFutureCursor = errors.New("host rejected future cursor")
+
+// wraps errors which should not be retried by Subscribe
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Subscribe consumes a repo event stream (eg, "wss://bsky.network/xrpc/com.atproto.sync.subscribeRepos"), passing events to sched, and reconnecting with exponential backoff when the connection drops.
+//
+// The sequence number of each event handed to the scheduler is recorded in the configured [CursorStore], and each (re)connection resumes from the stored cursor. Note that this is the last event seen, not necessarily the last event fully processed by the scheduler.
+//
+// Subscribe runs until the context is cancelled, or a non-recoverable error occurs: the host rejecting the cursor ([ErrFutureCursor]), a client error (4xx) response to the connection request, a failure reading or writing the cursor, or too many consecutive failed attempts (see [SubscribeOptions.MaxAttempts]). The scheduler is shut down before returning.
+func Subscribe(ctx context.Context, streamURL string, sched Scheduler, opts *SubscribeOptions) error {
+	if opts == nil {
+		opts = &SubscribeOptions{}
+	}
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.Default().With("system", "events")
+	}
+	cursors := opts.Cursors
+	if cursors == nil {
+		cursors = &MemCursorStore{}
+	}
+	dial := opts.Dial
+	if dial == nil {
+		d := websocket.Dialer{HandshakeTimeout: 10 * time.Second}
+		dial = func(ctx context.Context, u string) (*websocket.Conn, error) {
+			con, res, err := d.DialContext(ctx, u, http.Header{})
+			if err != nil && res != nil && res.StatusCode >= 400 && res.StatusCode < 500 && res.StatusCode != http.StatusTooManyRequests {
+				return nil, &permanentError{fmt.Errorf("subscription rejected (status %d): %w", res.StatusCode, err)}
+			}
+			return con, err
+		}
+	}
+	minBackoff := opts.MinBackoff
+	if minBackoff <= 0 {
+		minBackoff = time.Second
+	}
+	maxBackoff := opts.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = 30 * time.Second
+	}
+	flushInterval := opts.CursorFlushInterval
+	if flushInterval <= 0 {
+		flushInterval = time.Second
+	}
+
+	base, err := url.Parse(streamURL)
+	if err != nil {
+		return fmt.Errorf("invalid stream URL: %w", err)
+	}
+
+	cs := &cursorScheduler{
+		inner:         sched,
+		cursors:       cursors,
+		flushInterval: flushInterval,
+	}
+	defer sched.Shutdown()
+
+	failures := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		cursor, err := cursors.GetCursor(ctx)
+		if err != nil {
+			return fmt.Errorf("reading cursor: %w", err)
+		}
+		u := *base
+		if cursor > 0 {
+			q := u.Query()
+			q.Set("cursor", strconv.FormatInt(cursor, 10))
+			u.RawQuery = q.Encode()
+		}
+		cs.reset(cursor)
+
+		con, err := dial(ctx, u.String())
+		if err == nil {
+			logger.Info("connected to event stream", "url", u.String(), "cursor", cursor)
+			err = HandleRepoStream(ctx, con, cs, logger)
+			con.Close()
+			if ferr := cs.flush(ctx); ferr != nil {
+				return fmt.Errorf("writing cursor: %w", ferr)
+			}
+		}
+
+		var perm *permanentError
+		switch {
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case errors.As(err, &perm):
+			return perm.err
+		}
+
+		if cs.progressed() {
+			failures = 0
+		} else {
+			failures++
+		}
+		if opts.MaxAttempts > 0 && failures >= opts.MaxAttempts {
+			return fmt.Errorf("giving up after %d consecutive failed attempts: %w", failures, err)
+		}
+
+		backoff := maxBackoff
+		if failures < 16 {
+			backoff = min(minBackoff<<max(failures-1, 0), maxBackoff)
+		}
+		logger.Warn("event stream disconnected, reconnecting", "err", err, "failures", failures, "backoff", backoff)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// cursorScheduler passes events through to an inner scheduler, tracking the latest sequence number and periodically writing it to a CursorStore. It outlives individual connections, so Shutdown is a no-op.
+type cursorScheduler struct {
+	inner         Scheduler
+	cursors       CursorStore
+	flushInterval time.Duration
+
+	lk        sync.Mutex
+	startSeq  int64
+	lastSeq   int64
+	lastFlush time.Time
+}
+
+func (cs *cursorScheduler) reset(cursor int64) {
+	cs.lk.Lock()
+	defer cs.lk.Unlock()
+	cs.startSeq = cursor
+	cs.lastSeq = cursor
+}
+
+// whether any sequenced events were seen since the last reset
+func (cs *cursorScheduler) progressed() bool {
+	cs.lk.Lock()
+	defer cs.lk.Unlock()
+	return cs.lastSeq != cs.startSeq
+}
+
+func (cs *cursorScheduler) flush(ctx context.Context) error {
+	cs.lk.Lock()
+	defer cs.lk.Unlock()
+	cs.lastFlush = time.Now()
+	return cs.cursors.SetCursor(ctx, cs.lastSeq)
+}
+
+func (cs *cursorScheduler) AddWork(ctx context.Context, repo string, val *XRPCStreamEvent) error {
+	if val.Error != nil && val.Error.Error == "FutureCursor" {
+		return &permanentError{fmt.Errorf("%w: %s", ErrFutureCursor, val.Error.Message)}
+	}
+
+	if err := cs.inner.AddWork(ctx, repo, val); err != nil {
+		return err
+	}
+
+	seq, ok := val.GetSequence()
+	if !ok {
+		return nil
+	}
+	cs.lk.Lock()
+	cs.lastSeq = seq
+	due := time.Since(cs.lastFlush) >= cs.flushInterval
+	cs.lk.Unlock()
+	if due {
+		if err := cs.flush(ctx); err != nil {
+			return &permanentError{fmt.Errorf("writing cursor: %w", err)}
+		}
+	}
+	return nil
+}
+
+func (cs *cursorScheduler) Shutdown() {}
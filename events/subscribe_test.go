@@ -0,0 +1,185 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	comatproto "github.com/bluesky-social/indigo/api/atproto"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+// scheduler which records all events, in order
+type collectScheduler struct {
+	lk     sync.Mutex
+	seqs   []int64
+	errors []string
+}
+
+func (cs *collectScheduler) AddWork(ctx context.Context, repo string, val *XRPCStreamEvent) error {
+	cs.lk.Lock()
+	defer cs.lk.Unlock()
+	if seq, ok := val.GetSequence(); ok {
+		cs.seqs = append(cs.seqs, seq)
+	}
+	if val.Error != nil {
+		cs.errors = append(cs.errors, val.Error.Error)
+	}
+	return nil
+}
+
+func (cs *collectScheduler) Shutdown() {}
+
+// fake event stream server: each connection is served the next batch of events, then closed
+func testStreamServer(t *testing.T, batches [][]*XRPCStreamEvent) (*httptest.Server, *[]string) {
+	var lk sync.Mutex
+	var cursors []string
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lk.Lock()
+		n := len(cursors)
+		cursors = append(cursors, r.URL.Query().Get("cursor"))
+		lk.Unlock()
+		if n >= len(batches) {
+			http.Error(w, "no more batches", http.StatusServiceUnavailable)
+			return
+		}
+
+		con, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer con.Close()
+		for _, evt := range batches[n] {
+			wc, err := con.NextWriter(websocket.BinaryMessage)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if err := evt.Serialize(wc); err != nil {
+				t.Error(err)
+				return
+			}
+			wc.Close()
+		}
+		con.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &cursors
+}
+
+var quietLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func testIdentityEvent(seq int64) *XRPCStreamEvent {
+	return &XRPCStreamEvent{RepoIdentity: &comatproto.SyncSubscribeRepos_Identity{Did: "did:plc:abc123", Seq: seq, Time: "2024-01-02T03:04:05.006Z"}}
+}
+
+func TestSubscribeResumesFromCursor(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	srv, cursors := testStreamServer(t, [][]*XRPCStreamEvent{
+		{testIdentityEvent(11), testIdentityEvent(12), testIdentityEvent(13)},
+		{testIdentityEvent(14), testIdentityEvent(15)},
+		{{Error: &ErrorFrame{Error: "FutureCursor", Message: "cursor in the future"}}},
+	})
+	streamURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/xrpc/com.atproto.sync.subscribeRepos"
+
+	store := &MemCursorStore{}
+	assert.NoError(store.SetCursor(ctx, 10))
+	sched := &collectScheduler{}
+
+	err := Subscribe(ctx, streamURL, sched, &SubscribeOptions{
+		Cursors:    store,
+		Logger:     quietLogger,
+		MinBackoff: time.Millisecond,
+		MaxBackoff: 10 * time.Millisecond,
+	})
+	assert.True(errors.Is(err, ErrFutureCursor), err)
+
+	assert.Equal([]int64{11, 12, 13, 14, 15}, sched.seqs)
+	assert.Equal([]string{"10", "13", "15"}, *cursors)
+	seq, err := store.GetCursor(ctx)
+	assert.NoError(err)
+	assert.Equal(int64(15), seq)
+}
+
+func TestSubscribeGivesUp(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	// server which accepts a single connection with no events, then is unavailable
+	srv, cursors := testStreamServer(t, [][]*XRPCStreamEvent{{}})
+	streamURL := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	err := Subscribe(ctx, streamURL, &collectScheduler{}, &SubscribeOptions{
+		Logger:      quietLogger,
+		MinBackoff:  time.Millisecond,
+		MaxBackoff:  time.Millisecond,
+		MaxAttempts: 3,
+	})
+	assert.ErrorContains(err, "giving up after 3")
+	assert.Equal([]string{"", "", ""}, *cursors)
+
+	// cancellation stops retrying
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	err = Subscribe(ctx, streamURL, &collectScheduler{}, &SubscribeOptions{
+		Logger:     quietLogger,
+		MinBackoff: time.Millisecond,
+		MaxBackoff: time.Millisecond,
+	})
+	assert.ErrorIs(err, context.DeadlineExceeded)
+}
+
+func TestHandleRepoStreamErrorFrameWithType(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	// error frame with a stray message type, which should still be delivered
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		con, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer con.Close()
+		wc, err := con.NextWriter(websocket.BinaryMessage)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		header := EventHeader{Op: EvtKindErrorFrame, MsgType: "#error"}
+		if err := header.MarshalCBOR(wc); err != nil {
+			t.Error(err)
+			return
+		}
+		errframe := ErrorFrame{Error: "FutureCursor", Message: "cursor in the future"}
+		if err := errframe.MarshalCBOR(wc); err != nil {
+			t.Error(err)
+			return
+		}
+		wc.Close()
+		con.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+	}))
+	defer srv.Close()
+
+	con, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sched := &collectScheduler{}
+	HandleRepoStream(ctx, con, sched, quietLogger)
+	assert.Equal([]string{"FutureCursor"}, sched.errors)
+}
@@ -4,9 +4,11 @@ import (
 	"bytes"
 	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"io"
 
 	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multihash"
 	cbg "github.com/whyrusleeping/cbor-gen"
 	xerrors "golang.org/x/xerrors"
 )
@@ -144,6 +146,39 @@ type LexBlob struct {
 	Size     int64
 }
 
+// Returned when a blob reference is not a CIDv1 using the "raw" multicodec and sha2-256 hash
+type InvalidBlobRefError struct {
+	Ref    cid.Cid
+	Reason string
+}
+
+func (e *InvalidBlobRefError) Error() string {
+	return fmt.Sprintf("invalid blob ref %s: %s", e.Ref, e.Reason)
+}
+
+// Checks that a CID is a valid atproto blob reference: CIDv1, "raw" multicodec, and sha2-256 hash. Returns an [InvalidBlobRefError] if not.
+func ValidateBlobRef(c cid.Cid) error {
+	if !c.Defined() {
+		return &InvalidBlobRefError{Ref: c, Reason: "undefined CID"}
+	}
+	pfx := c.Prefix()
+	if pfx.Version != 1 {
+		return &InvalidBlobRefError{Ref: c, Reason: fmt.Sprintf("expected CIDv1, got v%d", pfx.Version)}
+	}
+	if pfx.Codec != cid.Raw {
+		return &InvalidBlobRefError{Ref: c, Reason: fmt.Sprintf("expected raw codec, got 0x%x", pfx.Codec)}
+	}
+	if pfx.MhType != multihash.SHA2_256 {
+		return &InvalidBlobRefError{Ref: c, Reason: fmt.Sprintf("expected sha2-256 hash, got 0x%x", pfx.MhType)}
+	}
+	return nil
+}
+
+// Checks that the blob ref is well-formed. See [ValidateBlobRef].
+func (b *LexBlob) Validate() error {
+	return ValidateBlobRef(cid.Cid(b.Ref))
+}
+
 type LegacyBlob struct {
 	Cid      string `json:"cid" cborgen:"cid"`
 	MimeType string `json:"mimeType" cborgen:"mimeType"`
@@ -156,6 +191,20 @@ type BlobSchema struct {
 	Size          int64   `json:"size" cborgen:"size"`
 }
 
+// Checks that the legacy blob's string CID parses and is well-formed. See [ValidateBlobRef].
+func (lb *LegacyBlob) Validate() error {
+	c, err := cid.Decode(lb.Cid)
+	if err != nil {
+		return &InvalidBlobRefError{Reason: fmt.Sprintf("parsing CID: %v", err)}
+	}
+	return ValidateBlobRef(c)
+}
+
+// Checks that the blob ref is well-formed. See [ValidateBlobRef].
+func (bs *BlobSchema) Validate() error {
+	return ValidateBlobRef(cid.Cid(bs.Ref))
+}
+
 func (b LexBlob) MarshalJSON() ([]byte, error) {
 	if b.Size < 0 {
 		lb := LegacyBlob{
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multihash"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -78,3 +79,34 @@ func TestBlobParse(t *testing.T) {
 	assert.NoError(json.Unmarshal(goJsonBytesLegacy, &goJsonAllLegacy))
 	assert.Equal(jsonAllLegacy, goJsonAllLegacy)
 }
+
+func TestValidateBlobRef(t *testing.T) {
+	assert := assert.New(t)
+
+	rawCid, err := cid.NewPrefixV1(cid.Raw, multihash.SHA2_256).Sum([]byte("blob data"))
+	assert.NoError(err)
+	cborCid, err := cid.NewPrefixV1(cid.DagCBOR, multihash.SHA2_256).Sum([]byte("blob data"))
+	assert.NoError(err)
+	v0Cid, err := cid.NewPrefixV0(multihash.SHA2_256).Sum([]byte("blob data"))
+	assert.NoError(err)
+	blake3Cid, err := cid.NewPrefixV1(cid.Raw, multihash.BLAKE3).Sum([]byte("blob data"))
+	assert.NoError(err)
+
+	assert.NoError(ValidateBlobRef(rawCid))
+	assert.NoError((&LexBlob{Ref: LexLink(rawCid), MimeType: "image/png", Size: 9}).Validate())
+	assert.NoError((&BlobSchema{Ref: LexLink(rawCid), MimeType: "image/png", Size: 9}).Validate())
+	assert.NoError((&LegacyBlob{Cid: rawCid.String(), MimeType: "image/png"}).Validate())
+
+	var refErr *InvalidBlobRefError
+	for _, bad := range []cid.Cid{cborCid, v0Cid, blake3Cid, cid.Undef} {
+		err := ValidateBlobRef(bad)
+		assert.ErrorAs(err, &refErr, bad.String())
+		assert.ErrorAs((&LexBlob{Ref: LexLink(bad)}).Validate(), &refErr)
+	}
+	assert.ErrorContains(ValidateBlobRef(cborCid), "raw codec")
+	assert.ErrorContains(ValidateBlobRef(v0Cid), "CIDv1")
+	assert.ErrorContains(ValidateBlobRef(blake3Cid), "sha2-256")
+
+	assert.ErrorAs((&LegacyBlob{Cid: cborCid.String()}).Validate(), &refErr)
+	assert.ErrorAs((&LegacyBlob{Cid: "not-a-cid"}).Validate(), &refErr)
+}
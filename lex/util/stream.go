@@ -0,0 +1,38 @@
+package util
+
+import (
+	"fmt"
+	"io"
+
+	cbornode "github.com/ipfs/go-ipld-cbor"
+	cbg "github.com/whyrusleeping/cbor-gen"
+)
+
+// DecodeCBORStream reads a single top-level CBOR array from r, decoding its elements one at a time and passing each to cb in order. Only one element is held in memory at a time, so this can be used for bulk processing of arrays too large to buffer.
+//
+// Elements are decoded generically (maps as map[string]any, links as cid.Cid, etc). If cb returns an error, decoding stops and that error is returned.
+func DecodeCBORStream(r io.Reader, cb func(item any) error) error {
+	cr := cbg.NewCborReader(r)
+	maj, extra, err := cr.ReadHeader()
+	if err != nil {
+		return fmt.Errorf("reading array header: %w", err)
+	}
+	if maj != cbg.MajArray {
+		return fmt.Errorf("expected CBOR array, got major type %d", maj)
+	}
+
+	for i := uint64(0); i < extra; i++ {
+		var raw cbg.Deferred
+		if err := raw.UnmarshalCBOR(cr); err != nil {
+			return fmt.Errorf("reading array element %d: %w", i, err)
+		}
+		var item any
+		if err := cbornode.DecodeInto(raw.Raw, &item); err != nil {
+			return fmt.Errorf("decoding array element %d: %w", i, err)
+		}
+		if err := cb(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package util
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	cbornode "github.com/ipfs/go-ipld-cbor"
+)
+
+func TestDecodeCBORStream(t *testing.T) {
+	const count = 20000
+
+	items := make([]any, count)
+	for i := range items {
+		items[i] = map[string]any{
+			"$type": "app.bsky.feed.post",
+			"text":  "hello",
+			"seq":   i,
+		}
+	}
+	b, err := cbornode.DumpObject(items)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	next := 0
+	err = DecodeCBORStream(bytes.NewReader(b), func(item any) error {
+		obj, ok := item.(map[string]any)
+		if !ok {
+			t.Fatalf("item %d: expected map, got %T", next, item)
+		}
+		seq, ok := obj["seq"].(int)
+		if !ok {
+			t.Fatalf("item %d: unexpected seq type %T", next, obj["seq"])
+		}
+		if seq != next {
+			t.Fatalf("item out of order: expected %d, got %d", next, seq)
+		}
+		next++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next != count {
+		t.Fatalf("expected %d items, got %d", count, next)
+	}
+
+	// callback errors stop decoding
+	stop := errors.New("stop")
+	seen := 0
+	err = DecodeCBORStream(bytes.NewReader(b), func(item any) error {
+		seen++
+		if seen == 3 {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) || seen != 3 {
+		t.Fatalf("expected to stop after 3 items with callback error, got %d: %v", seen, err)
+	}
+
+	// truncated input
+	if err := DecodeCBORStream(bytes.NewReader(b[:len(b)-5]), func(any) error { return nil }); err == nil {
+		t.Fatal("expected error for truncated array")
+	}
+
+	// not an array
+	obj, err := cbornode.DumpObject(map[string]any{"a": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := DecodeCBORStream(bytes.NewReader(obj), func(any) error { return nil }); err == nil {
+		t.Fatal("expected error for non-array input")
+	}
+}
@@ -0,0 +1,131 @@
+package util
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Returned by ValidateUTF8 when a string contains invalid UTF-8
+type InvalidUTF8Error struct {
+	// Location of the string within the object, like "embed.images[1].alt". Map keys are in brackets; a string which is itself a map key has "(key)" appended.
+	Path string
+	// Byte offset of the first invalid sequence within the string
+	Offset int
+}
+
+func (e *InvalidUTF8Error) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("invalid UTF-8 at byte %d", e.Offset)
+	}
+	return fmt.Sprintf("invalid UTF-8 in %s at byte %d", e.Path, e.Offset)
+}
+
+// ValidateUTF8 checks that every string in obj (struct fields, map keys and values, and slice elements, recursively) is valid UTF-8, returning an [InvalidUTF8Error] for the first which is not.
+//
+// CBOR text strings must be valid UTF-8, but the generated marshal code writes Go strings as-is, and some decoders reject the result. Call this before marshaling records built from untrusted input. Struct fields are identified by JSON name; unexported fields, and byte slices, are not checked.
+func ValidateUTF8(obj any) error {
+	return validateUTF8Value(reflect.ValueOf(obj), "", map[utf8Visit]bool{})
+}
+
+// identifies a pointer, map, or slice already checked, so that cyclic values terminate. The type and length are included because different values can share an address (eg, a struct and its first field).
+type utf8Visit struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+func validateUTF8String(s, path string) error {
+	if utf8.ValidString(s) {
+		return nil
+	}
+	offset := 0
+	for offset < len(s) {
+		r, size := utf8.DecodeRuneInString(s[offset:])
+		if r == utf8.RuneError && size <= 1 {
+			break
+		}
+		offset += size
+	}
+	return &InvalidUTF8Error{Path: path, Offset: offset}
+}
+
+func validateUTF8Value(v reflect.Value, path string, seen map[utf8Visit]bool) error {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+		visit := utf8Visit{ptr: v.Pointer(), typ: v.Type()}
+		if v.Kind() == reflect.Slice {
+			visit.len = v.Len()
+		}
+		if seen[visit] {
+			return nil
+		}
+		seen[visit] = true
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return validateUTF8String(v.String(), path)
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return validateUTF8Value(v.Elem(), path, seen)
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name := f.Name
+			if tag, _, _ := strings.Cut(f.Tag.Get("json"), ","); tag != "" && tag != "-" {
+				name = tag
+			}
+			if f.Anonymous {
+				name = ""
+			}
+			if err := validateUTF8Value(v.Field(i), joinUTF8Path(path, name), seen); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			// bytes, not text
+			return nil
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := validateUTF8Value(v.Index(i), fmt.Sprintf("%s[%d]", path, i), seen); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		// check in key order, so the reported error is deterministic
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		for _, k := range keys {
+			kpath := fmt.Sprintf("%s[%v]", path, k.Interface())
+			if err := validateUTF8Value(k, kpath+"(key)", seen); err != nil {
+				return err
+			}
+			if err := validateUTF8Value(v.MapIndex(k), kpath, seen); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func joinUTF8Path(path, name string) string {
+	if path == "" || name == "" {
+		return path + name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,88 @@
+package util
+
+import (
+	"errors"
+	"testing"
+)
+
+type utf8TestEmbed struct {
+	Alt string `json:"alt"`
+}
+
+type utf8TestRecord struct {
+	LexiconTypeID string            `json:"$type,const=test.record" cborgen:"$type,const=test.record"`
+	Text          string            `json:"text"`
+	Tags          []string          `json:"tags,omitempty"`
+	Images        []*utf8TestEmbed  `json:"images,omitempty"`
+	Extra         map[string]string `json:"extra,omitempty"`
+	Data          []byte            `json:"data,omitempty"`
+	Any           any               `json:"any,omitempty"`
+}
+
+func TestValidateUTF8(t *testing.T) {
+	valid := &utf8TestRecord{
+		LexiconTypeID: "test.record",
+		Text:          "hello, 世界 🌍",
+		Tags:          []string{"one", "twö"},
+		Images:        []*utf8TestEmbed{{Alt: "a cat"}, nil},
+		Extra:         map[string]string{"ключ": "значение"},
+		// byte fields don't need to be text
+		Data: []byte{0xff, 0xfe},
+		Any:  map[string]any{"nested": []any{"ok", 1}},
+	}
+	if err := ValidateUTF8(valid); err != nil {
+		t.Fatalf("expected valid record, got: %v", err)
+	}
+	if err := ValidateUTF8(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		rec    utf8TestRecord
+		path   string
+		offset int
+	}{
+		{utf8TestRecord{Text: "abc\xffdef"}, "text", 3},
+		{utf8TestRecord{Text: "\xc3"}, "text", 0},
+		{utf8TestRecord{Tags: []string{"ok", "ok", "é\xe2\x82"}}, "tags[2]", 2},
+		{utf8TestRecord{Images: []*utf8TestEmbed{{Alt: "ok"}, {Alt: "bad\x80"}}}, "images[1].alt", 3},
+		{utf8TestRecord{Extra: map[string]string{"k": "\xed\xa0\x80"}}, "extra[k]", 0},
+		{utf8TestRecord{Extra: map[string]string{"k\xff": "v"}}, "extra[k\xff](key)", 1},
+		{utf8TestRecord{Any: map[string]any{"nested": []any{"ok", "\xff"}}}, "any[nested][1]", 0},
+	}
+	for _, tc := range cases {
+		err := ValidateUTF8(&tc.rec)
+		var uerr *InvalidUTF8Error
+		if !errors.As(err, &uerr) {
+			t.Fatalf("expected InvalidUTF8Error for %q, got: %v", tc.path, err)
+		}
+		if uerr.Path != tc.path || uerr.Offset != tc.offset {
+			t.Fatalf("expected error at %q byte %d, got %q byte %d", tc.path, tc.offset, uerr.Path, uerr.Offset)
+		}
+	}
+
+	// bare strings
+	if err := ValidateUTF8("ok\xff"); err == nil {
+		t.Fatal("expected error for invalid string")
+	}
+
+	// cyclic values terminate
+	type node struct {
+		Text string
+		Next *node
+	}
+	loop := &node{Text: "ok"}
+	loop.Next = loop
+	if err := ValidateUTF8(loop); err != nil {
+		t.Fatal(err)
+	}
+	m := map[string]any{"text": "ok"}
+	m["self"] = m
+	if err := ValidateUTF8(m); err != nil {
+		t.Fatal(err)
+	}
+	loop.Next = &node{Text: "bad\xff", Next: loop}
+	if err := ValidateUTF8(loop); err == nil {
+		t.Fatal("expected error for invalid string in cyclic value")
+	}
+}
@@ -0,0 +1,18 @@
+package mst
+
+import (
+	"slices"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// SortKeysCollated returns a copy of keys sorted using the Unicode Collation Algorithm (root locale), so that, eg, "éclair" sorts next to "eclair" rather than after "zebra".
+//
+// This is for display purposes only. MST ordering is defined by the protocol as byte-wise comparison of keys, and this must NOT be used for tree construction, walking, diffing, or any other tree operation. The input slice is not modified.
+func SortKeysCollated(keys []string) []string {
+	out := slices.Clone(keys)
+	c := collate.New(language.Und)
+	c.SortStrings(out)
+	return out
+}
@@ -0,0 +1,44 @@
+package mst
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSortKeysCollated(t *testing.T) {
+	assert := assert.New(t)
+
+	keys := []string{
+		"com.example.record/zebra",
+		"com.example.record/éclair",
+		"com.example.record/Eclair",
+		"com.example.record/eclair",
+		"com.example.record/apple",
+		"com.example.record/Ärger",
+	}
+
+	byteOrder := append([]string{}, keys...)
+	sort.Strings(byteOrder)
+	assert.Equal([]string{
+		"com.example.record/Eclair",
+		"com.example.record/apple",
+		"com.example.record/eclair",
+		"com.example.record/zebra",
+		"com.example.record/Ärger",
+		"com.example.record/éclair",
+	}, byteOrder)
+
+	assert.Equal([]string{
+		"com.example.record/apple",
+		"com.example.record/Ärger",
+		"com.example.record/eclair",
+		"com.example.record/Eclair",
+		"com.example.record/éclair",
+		"com.example.record/zebra",
+	}, SortKeysCollated(keys))
+
+	// input is not modified
+	assert.Equal("com.example.record/zebra", keys[0])
+}
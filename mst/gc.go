@@ -0,0 +1,94 @@
+package mst
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/ipfs/go-cid"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	ipld "github.com/ipfs/go-ipld-format"
+	cbg "github.com/whyrusleeping/cbor-gen"
+)
+
+// FindGarbage returns the CIDs of all blocks in the blockstore which are not reachable from any of the live roots. It does not modify the blockstore.
+//
+// Roots will usually be commit CIDs, but can be any DAG-CBOR block (such as an MST root node). Reachability is determined by following all CID links in DAG-CBOR blocks, so commits, MST nodes, and records are all retained. Links to blocks not present in the blockstore (such as blobs) are ignored.
+//
+// This can be used as a read-only first pass to inspect what [GarbageCollect] would remove.
+func FindGarbage(ctx context.Context, bs blockstore.Blockstore, liveRoots []cid.Cid) ([]cid.Cid, error) {
+	// NOTE: blockstores are keyed by multihash, and AllKeysChan may return CIDs with a different codec from the original, so reachability is tracked by multihash
+	live := make(map[string]struct{})
+	for _, root := range liveRoots {
+		if err := markReachable(ctx, bs, root, live); err != nil {
+			return nil, err
+		}
+	}
+
+	keys, err := bs.AllKeysChan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing blockstore keys: %w", err)
+	}
+
+	var garbage []cid.Cid
+	for k := range keys {
+		if _, ok := live[string(k.Hash())]; !ok {
+			garbage = append(garbage, k)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return garbage, nil
+}
+
+// GarbageCollect deletes all blocks from the blockstore which are not reachable from any of the live roots (see [FindGarbage]), and returns the number of blocks removed.
+//
+// Blocks written concurrently with a collection (eg, a commit in progress) may be removed if they are not yet reachable from a live root, so callers should not run this concurrently with writes to the same blockstore. Concurrent reads of live data are safe.
+func GarbageCollect(ctx context.Context, bs blockstore.Blockstore, liveRoots []cid.Cid) (int, error) {
+	garbage, err := FindGarbage(ctx, bs, liveRoots)
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, c := range garbage {
+		if err := bs.DeleteBlock(ctx, c); err != nil {
+			return removed, fmt.Errorf("deleting block %s: %w", c, err)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+func markReachable(ctx context.Context, bs blockstore.Blockstore, root cid.Cid, live map[string]struct{}) error {
+	stack := []cid.Cid{root}
+	for len(stack) > 0 {
+		c := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if _, ok := live[string(c.Hash())]; ok {
+			continue
+		}
+		live[string(c.Hash())] = struct{}{}
+
+		if c.Prefix().Codec != cid.DagCBOR {
+			continue
+		}
+
+		blk, err := bs.Get(ctx, c)
+		if err != nil {
+			if ipld.IsNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("loading block %s: %w", c, err)
+		}
+
+		if err := cbg.ScanForLinks(bytes.NewReader(blk.RawData()), func(link cid.Cid) {
+			stack = append(stack, link)
+		}); err != nil {
+			return fmt.Errorf("scanning block %s for links: %w", c, err)
+		}
+	}
+	return nil
+}
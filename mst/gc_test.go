@@ -0,0 +1,136 @@
+package mst
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/bluesky-social/indigo/util"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	mh "github.com/multiformats/go-multihash"
+)
+
+func putRawBlock(t *testing.T, bs blockstore.Blockstore, data string) cid.Cid {
+	t.Helper()
+	c, err := cid.NewPrefixV1(cid.Raw, mh.SHA2_256).Sum([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	blk, err := blocks.NewBlockWithCid([]byte(data), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bs.Put(context.Background(), blk); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func countBlocks(t *testing.T, bs blockstore.Blockstore) int {
+	t.Helper()
+	keys, err := bs.AllKeysChan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for range keys {
+		n++
+	}
+	return n
+}
+
+func TestGarbageCollect(t *testing.T) {
+	ctx := context.Background()
+	bs := memBs()
+
+	vals := map[string]cid.Cid{}
+	for i := 0; i < 100; i++ {
+		vals[fmt.Sprintf("com.example.record/%04d", i)] = putRawBlock(t, bs, fmt.Sprintf("record %d", i))
+	}
+	tree := cidMapToMst(t, bs, vals)
+	oldRoot := mustCidTree(t, tree)
+
+	// remove some records and add others, leaving old nodes and records as garbage
+	for i := 0; i < 20; i++ {
+		k := fmt.Sprintf("com.example.record/%04d", i)
+		nt, err := tree.Delete(ctx, k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tree = nt
+		delete(vals, k)
+	}
+	for i := 100; i < 110; i++ {
+		k := fmt.Sprintf("com.example.record/%04d", i)
+		v := putRawBlock(t, bs, fmt.Sprintf("record %d", i))
+		nt, err := tree.Add(ctx, k, v, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tree = nt
+		vals[k] = v
+	}
+	newRoot := mustCidTree(t, tree)
+	before := countBlocks(t, bs)
+
+	// read-only pass doesn't delete anything
+	garbage, err := FindGarbage(ctx, bs, []cid.Cid{newRoot})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(garbage) == 0 {
+		t.Fatal("expected some garbage")
+	}
+	if countBlocks(t, bs) != before {
+		t.Fatal("FindGarbage modified blockstore")
+	}
+
+	removed, err := GarbageCollect(ctx, bs, []cid.Cid{newRoot})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != len(garbage) {
+		t.Fatalf("expected %d removed, got %d", len(garbage), removed)
+	}
+	if countBlocks(t, bs) != before-removed {
+		t.Fatal("unexpected block count after GC")
+	}
+
+	// old root and removed records are gone
+	if has, _ := bs.Has(ctx, oldRoot); has {
+		t.Fatal("old root should have been collected")
+	}
+	if has, _ := bs.Has(ctx, putRawBlockCid(t, "record 0")); has {
+		t.Fatal("removed record should have been collected")
+	}
+
+	// all live data survives, read through a fresh tree
+	loaded := LoadMST(util.CborStore(bs), newRoot)
+	assertValues(t, loaded, vals)
+	for _, v := range vals {
+		if has, _ := bs.Has(ctx, v); !has {
+			t.Fatalf("live record %s was collected", v)
+		}
+	}
+
+	// a second pass finds nothing
+	removed, err = GarbageCollect(ctx, bs, []cid.Cid{newRoot})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 0 {
+		t.Fatalf("expected nothing removed, got %d", removed)
+	}
+}
+
+func putRawBlockCid(t *testing.T, data string) cid.Cid {
+	t.Helper()
+	c, err := cid.NewPrefixV1(cid.Raw, mh.SHA2_256).Sum([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
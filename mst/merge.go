@@ -0,0 +1,75 @@
+package mst
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bluesky-social/indigo/util"
+	cid "github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+)
+
+// returns a store that writes blocks with the hash function of the first defined root, so that trees built with a non-default hash keep using it
+func rootStore(bs cbor.IpldBlockstore, roots ...cid.Cid) *cbor.BasicIpldStore {
+	for _, r := range roots {
+		if r.Defined() {
+			return util.CborStoreWithHash(bs, r.Prefix().MhType)
+		}
+	}
+	return util.CborStore(bs)
+}
+
+// Merge computes the union of the key sets of two trees, writes the resulting tree to the blockstore, and returns its root CID. Both input trees must already be present in the blockstore; either root may be cid.Undef, which is treated as an empty tree. New nodes are written with the hash function of a's root CID, or b's if a is undefined.
+//
+// For keys present in both trees with different values, the conflict callback is called to choose the value for the merged tree. It must return a defined CID. Keys with identical values in both trees are not passed to the callback.
+//
+// Because MST structure is fully determined by its contents, the result is identical to a tree built from scratch with the merged key set.
+func Merge(ctx context.Context, bs cbor.IpldBlockstore, a, b cid.Cid, conflict func(key string, av, bv cid.Cid) cid.Cid) (cid.Cid, error) {
+	cst := rootStore(bs, a, b)
+
+	var out *MerkleSearchTree
+	if a == cid.Undef {
+		out = NewEmptyMST(cst)
+	} else {
+		out = LoadMST(cst, a)
+	}
+	if b == cid.Undef {
+		return out.GetPointer(ctx)
+	}
+
+	bt := LoadMST(cst, b)
+	err := bt.WalkLeavesFrom(ctx, "", func(key string, bv cid.Cid) error {
+		av, err := out.Get(ctx, key)
+		switch {
+		case err == ErrNotFound:
+			nt, err := out.Add(ctx, key, bv, -1)
+			if err != nil {
+				return fmt.Errorf("adding key %s: %w", key, err)
+			}
+			out = nt
+		case err != nil:
+			return fmt.Errorf("looking up key %s: %w", key, err)
+		case av.Equals(bv):
+			// same value in both trees
+		default:
+			val := conflict(key, av, bv)
+			if !val.Defined() {
+				return fmt.Errorf("conflict resolution for key %s returned undefined CID", key)
+			}
+			if val.Equals(av) {
+				return nil
+			}
+			nt, err := out.Update(ctx, key, val)
+			if err != nil {
+				return fmt.Errorf("updating key %s: %w", key, err)
+			}
+			out = nt
+		}
+		return nil
+	})
+	if err != nil {
+		return cid.Undef, err
+	}
+
+	return out.GetPointer(ctx)
+}
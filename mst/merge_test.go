@@ -0,0 +1,139 @@
+package mst
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"testing"
+
+	"github.com/bluesky-social/indigo/util"
+
+	"github.com/ipfs/go-cid"
+	mh "github.com/multiformats/go-multihash"
+)
+
+func preferA(key string, av, bv cid.Cid) cid.Cid {
+	return av
+}
+
+func TestMerge(t *testing.T) {
+	ctx := context.Background()
+
+	shared := strToCid("shared")
+	a := map[string]cid.Cid{}
+	b := map[string]cid.Cid{}
+	for i := 0; i < 200; i++ {
+		a[fmt.Sprintf("com.example.record/a%04d", i)] = strToCid(fmt.Sprintf("a%d", i))
+		b[fmt.Sprintf("com.example.record/b%04d", i)] = strToCid(fmt.Sprintf("b%d", i))
+	}
+
+	checkMerge := func(t *testing.T, a, b, expected map[string]cid.Cid) {
+		t.Helper()
+		bs := memBs()
+		ra := mustCidTree(t, cidMapToMst(t, bs, a))
+		rb := mustCidTree(t, cidMapToMst(t, bs, b))
+
+		merged, err := Merge(ctx, bs, ra, rb, preferA)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// same root as a tree built directly from the expected key set
+		fresh := mustCidTree(t, cidMapToMst(t, memBs(), expected))
+		if merged != fresh {
+			t.Fatalf("merged root %s does not match fresh tree %s", merged, fresh)
+		}
+		assertValues(t, LoadMST(util.CborStore(bs), merged), expected)
+	}
+
+	t.Run("disjoint", func(t *testing.T) {
+		expected := maps.Clone(a)
+		maps.Copy(expected, b)
+		checkMerge(t, a, b, expected)
+	})
+
+	t.Run("overlapping", func(t *testing.T) {
+		oa := maps.Clone(a)
+		ob := maps.Clone(b)
+		// conflicting values, and an identical value, for overlapping keys
+		for i := 0; i < 50; i++ {
+			ob[fmt.Sprintf("com.example.record/a%04d", i)] = strToCid(fmt.Sprintf("conflict%d", i))
+		}
+		oa["com.example.record/shared"] = shared
+		ob["com.example.record/shared"] = shared
+
+		expected := maps.Clone(ob)
+		maps.Copy(expected, oa)
+		checkMerge(t, oa, ob, expected)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		checkMerge(t, a, map[string]cid.Cid{}, a)
+		checkMerge(t, map[string]cid.Cid{}, b, b)
+
+		bs := memBs()
+		rb := mustCidTree(t, cidMapToMst(t, bs, b))
+		merged, err := Merge(ctx, bs, cid.Undef, rb, preferA)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if merged != rb {
+			t.Fatalf("merge with undefined root changed tree")
+		}
+	})
+
+	t.Run("undefined resolution", func(t *testing.T) {
+		bs := memBs()
+		ra := mustCidTree(t, cidMapToMst(t, bs, map[string]cid.Cid{"com.example.record/x": strToCid("1")}))
+		rb := mustCidTree(t, cidMapToMst(t, bs, map[string]cid.Cid{"com.example.record/x": strToCid("2")}))
+		_, err := Merge(ctx, bs, ra, rb, func(key string, av, bv cid.Cid) cid.Cid { return cid.Undef })
+		if err == nil {
+			t.Fatal("expected error for undefined conflict resolution")
+		}
+	})
+}
+
+func TestMergeKeepsHashFunc(t *testing.T) {
+	ctx := context.Background()
+	bs := memBs()
+	cst := util.CborStoreWithHash(bs, mh.BLAKE3)
+
+	build := func(prefix string) cid.Cid {
+		tree := NewEmptyMST(cst)
+		for i := 0; i < 100; i++ {
+			nt, err := tree.Add(ctx, fmt.Sprintf("com.example.record/%s%04d", prefix, i), strToCid(fmt.Sprintf("%s%d", prefix, i)), -1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tree = nt
+		}
+		return mustCidTree(t, tree)
+	}
+	ra := build("a")
+	rb := build("b")
+
+	check := func(t *testing.T, root cid.Cid) {
+		t.Helper()
+		nodes, err := treeNodes(ctx, cst, root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for c := range nodes {
+			if c.Prefix().MhType != mh.BLAKE3 {
+				t.Fatalf("node %s does not use blake3", c)
+			}
+		}
+	}
+
+	merged, err := Merge(ctx, bs, ra, rb, preferA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, merged)
+
+	merged, err = Merge(ctx, bs, cid.Undef, rb, preferA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, merged)
+}
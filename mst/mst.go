@@ -44,8 +44,10 @@ package mst
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 
 	"github.com/ipfs/go-cid"
 	cbor "github.com/ipfs/go-ipld-cbor"
@@ -572,6 +574,47 @@ func (mst *MerkleSearchTree) Delete(ctx context.Context, k string) (*MerkleSearc
 	return altered.trimTop(ctx)
 }
 
+// RemoveMany deletes all of the given keys from the tree, and returns the root CID of the resulting tree.
+//
+// The result is identical to calling Delete for each key in turn, which in turn is identical to a tree built from scratch without those keys. Returns an error if any key is not present.
+func (mst *MerkleSearchTree) RemoveMany(ctx context.Context, keys []string) (cid.Cid, error) {
+	cur := mst
+	for _, k := range keys {
+		next, err := cur.Delete(ctx, k)
+		if err != nil {
+			return cid.Undef, fmt.Errorf("removing key %s: %w", k, err)
+		}
+		cur = next
+	}
+	return cur.GetPointer(ctx)
+}
+
+// used to end a walk early, once past the keys of interest
+var errEndOfPrefix = fmt.Errorf("mst: end of prefix")
+
+// RemovePrefix deletes every key starting with prefix (eg, all records in a collection, with a prefix like "app.bsky.feed.like/"), and returns the root CID of the resulting tree and the number of keys removed.
+//
+// The result is identical to removing each of the keys individually. Removing a prefix which matches no keys is not an error, and returns the current root.
+func (mst *MerkleSearchTree) RemovePrefix(ctx context.Context, prefix string) (cid.Cid, int, error) {
+	var keys []string
+	err := mst.WalkLeavesFrom(ctx, prefix, func(key string, val cid.Cid) error {
+		if !strings.HasPrefix(key, prefix) {
+			return errEndOfPrefix
+		}
+		keys = append(keys, key)
+		return nil
+	})
+	if err != nil && !errors.Is(err, errEndOfPrefix) {
+		return cid.Undef, 0, fmt.Errorf("listing keys with prefix %q: %w", prefix, err)
+	}
+
+	root, err := mst.RemoveMany(ctx, keys)
+	if err != nil {
+		return cid.Undef, 0, err
+	}
+	return root, len(keys), nil
+}
+
 // Typescript: MST.deleteRecurse(key) -> MST
 func (mst *MerkleSearchTree) deleteRecurse(ctx context.Context, k string) (*MerkleSearchTree, error) {
 	ix, err := mst.findGtOrEqualLeafIndex(ctx, k)
@@ -605,7 +648,12 @@ func (mst *MerkleSearchTree) deleteRecurse(ctx context.Context, k string) (*Merk
 			if err != nil {
 				return nil, err
 			}
-			return mst.newTree(append(append(entries[:ix-1], mkTreeEntry(merged)), entries[ix+2:]...)), nil
+			// build a fresh slice: appending to entries[:ix-1] would overwrite this (shared, immutable) node's entries in place
+			nents := make([]nodeEntry, 0, len(entries)-2)
+			nents = append(nents, entries[:ix-1]...)
+			nents = append(nents, mkTreeEntry(merged))
+			nents = append(nents, entries[ix+2:]...)
+			return mst.newTree(nents), nil
 		} else {
 			return mst.removeEntry(ctx, ix)
 		}
@@ -884,9 +932,16 @@ func (mst *MerkleSearchTree) appendMerge(ctx context.Context, omst *MerkleSearch
 			return nil, err
 		}
 
-		return mst.newTree(append(append(entries[:len(entries)-1], mkTreeEntry(merged)), tomergeEnts[1:]...)), nil
+		nents := make([]nodeEntry, 0, len(entries)+len(tomergeEnts)-1)
+		nents = append(nents, entries[:len(entries)-1]...)
+		nents = append(nents, mkTreeEntry(merged))
+		nents = append(nents, tomergeEnts[1:]...)
+		return mst.newTree(nents), nil
 	} else {
-		return mst.newTree(append(entries, tomergeEnts...)), nil
+		nents := make([]nodeEntry, 0, len(entries)+len(tomergeEnts))
+		nents = append(nents, entries...)
+		nents = append(nents, tomergeEnts...)
+		return mst.newTree(nents), nil
 	}
 }
 
@@ -936,7 +991,13 @@ func (mst *MerkleSearchTree) findGtOrEqualLeafIndex(ctx context.Context, key str
 // WalkLeavesFrom walks the leaves of the tree, calling the cb callback on each
 // key that's greater than or equal to the provided from key.
 // If cb returns an error, the walk is aborted and the error is returned.
+//
+// The context is checked once per tree node visited, so a long walk over a large tree returns promptly (with the context error) on cancellation.
 func (mst *MerkleSearchTree) WalkLeavesFrom(ctx context.Context, from string, cb func(key string, val cid.Cid) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	index, err := mst.findGtOrEqualLeafIndex(ctx, from)
 	if err != nil {
 		return err
@@ -970,6 +1031,68 @@ func (mst *MerkleSearchTree) WalkLeavesFrom(ctx context.Context, from string, cb
 	return nil
 }
 
+// Entry is a single key/value leaf of the tree
+type Entry struct {
+	Key string
+	Val cid.Cid
+}
+
+// LastN returns the n greatest keys in the tree, in descending order. Only the right-most path of nodes needed to find them is loaded, so this is cheap for small n even on large trees. Returns fewer than n entries if the tree is smaller.
+func (mst *MerkleSearchTree) LastN(ctx context.Context, n int) ([]Entry, error) {
+	out := make([]Entry, 0, max(n, 0))
+	if n <= 0 {
+		return out, nil
+	}
+	err := mst.WalkLeavesBefore(ctx, "", func(key string, val cid.Cid) error {
+		out = append(out, Entry{Key: key, Val: val})
+		if len(out) == n {
+			return errEnoughLeaves
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errEnoughLeaves) {
+		return nil, err
+	}
+	return out, nil
+}
+
+// stops LastN's walk once it has enough entries
+var errEnoughLeaves = fmt.Errorf("mst: enough leaves")
+
+// WalkLeavesBefore calls cb for each leaf with a key strictly less than before (or for every leaf, if before is empty), in descending key order. Subtrees holding only greater keys are not loaded. If cb returns an error, the walk stops and the error is returned (possibly wrapped).
+func (mst *MerkleSearchTree) WalkLeavesBefore(ctx context.Context, before string, cb func(key string, val cid.Cid) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	entries, err := mst.getEntries(ctx)
+	if err != nil {
+		return fmt.Errorf("get entries: %w", err)
+	}
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		switch {
+		case e.isLeaf():
+			if before != "" && e.Key >= before {
+				continue
+			}
+			if err := cb(e.Key, e.Val); err != nil {
+				return err
+			}
+		case e.isTree():
+			// every key in a subtree is greater than the leaf to its left
+			if before != "" && i > 0 && entries[i-1].isLeaf() && entries[i-1].Key >= before {
+				continue
+			}
+			if err := e.Tree.WalkLeavesBefore(ctx, before, cb); err != nil {
+				return fmt.Errorf("walk leaves before (%d): %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
 // TODO: Typescript: MST.list(count?, after?, before?) -> Leaf[]
 // TODO: Typescript: MST.listWithPrefix(prefix, count?) -> Leaf[]
 
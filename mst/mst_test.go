@@ -3,17 +3,20 @@ package mst
 import (
 	"context"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"maps"
 	"math/rand"
 	"os"
 	"regexp"
+	"slices"
 	"sort"
 	"testing"
 
 	"github.com/bluesky-social/indigo/util"
 
+	blocks "github.com/ipfs/go-block-format"
 	"github.com/ipfs/go-cid"
 	"github.com/ipfs/go-datastore"
 	blockstore "github.com/ipfs/go-ipfs-blockstore"
@@ -114,6 +117,44 @@ func assertValues(t *testing.T, mst *MerkleSearchTree, vals map[string]cid.Cid)
 	}
 }
 
+func TestDeleteLeavesOriginalTree(t *testing.T) {
+	ctx := context.Background()
+
+	for seed := int64(0); seed < 20; seed++ {
+		r := rand.New(rand.NewSource(seed))
+
+		all := map[string]cid.Cid{}
+		var keys []string
+		n := 2 + r.Intn(300)
+		for i := 0; i < n; i++ {
+			k := randKey(r.Int63())
+			if _, ok := all[k]; !ok {
+				keys = append(keys, k)
+			}
+			all[k] = strToCid(randStr(r.Int63()))
+		}
+		expected := mustCidTree(t, cidMapToMst(t, memBs(), all))
+
+		// derive a tree with an extra key, then delete original keys from the derived tree only
+		orig := cidMapToMst(t, memBs(), all)
+		derived, err := orig.Add(ctx, randKey(r.Int63()), randCid(), -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, k := range keys[:1+r.Intn(len(keys))] {
+			derived, err = derived.Delete(ctx, k)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if root := mustCidTree(t, orig); root != expected {
+			t.Fatalf("seed %d: original tree changed by deleting keys from a derived tree: %s != %s", seed, root, expected)
+		}
+		assertValues(t, orig, all)
+	}
+}
+
 func mustCid(t *testing.T, s string) cid.Cid {
 	t.Helper()
 	c, err := cid.Decode(s)
@@ -634,3 +675,283 @@ func BenchmarkCountPrefixLen(b *testing.B) {
 		}
 	}
 }
+
+func TestRemoveManyLeavesOriginalTree(t *testing.T) {
+	ctx := context.Background()
+
+	for seed := int64(0); seed < 20; seed++ {
+		r := rand.New(rand.NewSource(seed))
+
+		all := map[string]cid.Cid{}
+		var keys []string
+		n := 2 + r.Intn(300)
+		for i := 0; i < n; i++ {
+			k := randKey(r.Int63())
+			if _, ok := all[k]; !ok {
+				keys = append(keys, k)
+			}
+			all[k] = strToCid(randStr(r.Int63()))
+		}
+		expected := mustCidTree(t, cidMapToMst(t, memBs(), all))
+
+		// derive a tree with an extra key, then remove some of the original keys from the derived tree only
+		orig := cidMapToMst(t, memBs(), all)
+		derived, err := orig.Add(ctx, randKey(r.Int63()), randCid(), -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		remove := keys[:1+r.Intn(len(keys))]
+		if _, err := derived.RemoveMany(ctx, remove); err != nil {
+			t.Fatal(err)
+		}
+
+		if root := mustCidTree(t, orig); root != expected {
+			t.Fatalf("seed %d: original tree changed by removing keys from a derived tree: %s != %s", seed, root, expected)
+		}
+		assertValues(t, orig, all)
+	}
+}
+
+func TestRemoveManyMatchesFreshTree(t *testing.T) {
+	ctx := context.Background()
+
+	for seed := int64(0); seed < 20; seed++ {
+		r := rand.New(rand.NewSource(seed))
+
+		all := map[string]cid.Cid{}
+		n := 1 + r.Intn(300)
+		for i := 0; i < n; i++ {
+			all[randKey(r.Int63())] = strToCid(randStr(r.Int63()))
+		}
+
+		keep := map[string]cid.Cid{}
+		var remove []string
+		for k, v := range all {
+			if r.Intn(3) == 0 {
+				remove = append(remove, k)
+			} else {
+				keep[k] = v
+			}
+		}
+
+		bs := memBs()
+		full := cidMapToMst(t, bs, all)
+		mustCidTree(t, full)
+
+		root, err := full.RemoveMany(ctx, remove)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := mustCidTree(t, cidMapToMst(t, memBs(), keep))
+		if root != expected {
+			t.Fatalf("seed %d: tree after removing %d of %d keys does not match fresh tree: %s != %s", seed, len(remove), len(all), root, expected)
+		}
+		assertValues(t, LoadMST(util.CborStore(bs), root), keep)
+	}
+
+	// removing a key which isn't present is an error
+	full := cidMapToMst(t, memBs(), map[string]cid.Cid{"cats/cats": randCid()})
+	if _, err := full.RemoveMany(ctx, []string{"dogs/dogs"}); err == nil {
+		t.Fatal("expected error removing missing key")
+	}
+}
+
+func TestRemovePrefix(t *testing.T) {
+	ctx := context.Background()
+
+	all := map[string]cid.Cid{}
+	keep := map[string]cid.Cid{}
+	var likes []string
+	for i := int64(0); i < 200; i++ {
+		for _, coll := range []string{"app.bsky.feed.like", "app.bsky.feed.post", "app.bsky.feed.likes", "app.bsky.actor.profile"} {
+			k := fmt.Sprintf("%s/%s", coll, randStr(i))
+			all[k] = strToCid(k)
+			if coll == "app.bsky.feed.like" {
+				likes = append(likes, k)
+			} else {
+				keep[k] = all[k]
+			}
+		}
+	}
+
+	bs := memBs()
+	full := cidMapToMst(t, bs, all)
+	before := mustCidTree(t, full)
+
+	root, n, err := full.RemovePrefix(ctx, "app.bsky.feed.like/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(likes) {
+		t.Fatalf("expected %d keys removed, got %d", len(likes), n)
+	}
+
+	// same as removing the keys individually, and as a tree built without them
+	individually, err := cidMapToMst(t, memBs(), all).RemoveMany(ctx, likes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root != individually {
+		t.Fatalf("prefix removal does not match individual removal: %s != %s", root, individually)
+	}
+	if expected := mustCidTree(t, cidMapToMst(t, memBs(), keep)); root != expected {
+		t.Fatalf("prefix removal does not match fresh tree: %s != %s", root, expected)
+	}
+	assertValues(t, LoadMST(util.CborStore(bs), root), keep)
+
+	// no matching keys is a no-op
+	root, n, err = full.RemovePrefix(ctx, "app.bsky.graph.follow/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 || root != before {
+		t.Fatalf("expected no change, got %d removed and root %s", n, root)
+	}
+}
+
+func TestWalkLeavesFromCancel(t *testing.T) {
+	vals := map[string]cid.Cid{}
+	for i := int64(0); i < 2000; i++ {
+		vals[randKey(i)] = strToCid(randStr(i))
+	}
+	bs := memBs()
+	root := mustCidTree(t, cidMapToMst(t, bs, vals))
+
+	// walk from a freshly loaded tree, so nodes are loaded lazily
+	tree := LoadMST(util.CborStore(bs), root)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	visited := 0
+	err := tree.WalkLeavesFrom(ctx, "", func(key string, val cid.Cid) error {
+		visited++
+		if visited == 10 {
+			cancel()
+		}
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got: %v", err)
+	}
+	if visited >= len(vals) {
+		t.Fatalf("walk visited entire tree (%d leaves) despite cancellation", visited)
+	}
+}
+
+// counts block reads, to check how much of a tree an operation loads
+type countingBs struct {
+	blockstore.Blockstore
+	gets int
+}
+
+func (cb *countingBs) Get(ctx context.Context, c cid.Cid) (blocks.Block, error) {
+	cb.gets++
+	return cb.Blockstore.Get(ctx, c)
+}
+
+func TestLastN(t *testing.T) {
+	ctx := context.Background()
+	vals := map[string]cid.Cid{}
+	var keys []string
+	for i := int64(0); i < 2000; i++ {
+		k := randKey(i)
+		vals[k] = strToCid(randStr(i))
+		keys = append(keys, k)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(keys)))
+
+	bs := memBs()
+	root := mustCidTree(t, cidMapToMst(t, bs, vals))
+	_, total, err := SharedNodes(ctx, bs, cid.Undef, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, n := range []int{0, 1, 10, 100} {
+		cbs := &countingBs{Blockstore: bs}
+		tree := LoadMST(util.CborStore(cbs), root)
+		out, err := tree.LastN(ctx, n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(out) != n {
+			t.Fatalf("LastN(%d) returned %d entries", n, len(out))
+		}
+		for i, e := range out {
+			if e.Key != keys[i] || e.Val != vals[keys[i]] {
+				t.Fatalf("LastN(%d)[%d] = %s, expected %s", n, i, e.Key, keys[i])
+			}
+		}
+		if cbs.gets >= total {
+			t.Fatalf("LastN(%d) loaded %d blocks, whole tree is %d", n, cbs.gets, total)
+		}
+	}
+
+	// asking for more than the tree holds returns everything
+	out, err := LoadMST(util.CborStore(bs), root).LastN(ctx, 5000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != len(keys) || out[0].Key != keys[0] || out[len(out)-1].Key != keys[len(keys)-1] {
+		t.Fatalf("LastN(5000) returned %d entries", len(out))
+	}
+
+	out, err = NewEmptyMST(util.CborStore(memBs())).LastN(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 0 {
+		t.Fatalf("LastN on empty tree returned %d entries", len(out))
+	}
+}
+
+func TestWalkLeavesBefore(t *testing.T) {
+	ctx := context.Background()
+	vals := map[string]cid.Cid{}
+	var keys []string
+	for i := int64(0); i < 2000; i++ {
+		k := randKey(i)
+		vals[k] = strToCid(randStr(i))
+		keys = append(keys, k)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(keys)))
+
+	bs := memBs()
+	root := mustCidTree(t, cidMapToMst(t, bs, vals))
+	_, total, err := SharedNodes(ctx, bs, cid.Undef, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errStop := fmt.Errorf("stop")
+	// bounds at, between, and outside the keys
+	for _, before := range []string{keys[0], keys[1000], keys[1000] + "a", keys[len(keys)-1], "a", "zzz"} {
+		start := sort.Search(len(keys), func(i int) bool { return keys[i] < before })
+
+		cbs := &countingBs{Blockstore: bs}
+		var out []string
+		err := LoadMST(util.CborStore(cbs), root).WalkLeavesBefore(ctx, before, func(key string, val cid.Cid) error {
+			if val != vals[key] {
+				t.Fatalf("wrong value for key %s", key)
+			}
+			out = append(out, key)
+			if len(out) == 10 {
+				return errStop
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, errStop) {
+			t.Fatal(err)
+		}
+
+		expected := keys[start:min(start+10, len(keys))]
+		if !slices.Equal(out, expected) {
+			t.Fatalf("WalkLeavesBefore(%q) = %v, expected %v", before, out, expected)
+		}
+		// a short page only loads the nodes on the way to it
+		if cbs.gets >= total/2 {
+			t.Fatalf("WalkLeavesBefore(%q) loaded %d blocks, whole tree is %d", before, cbs.gets, total)
+		}
+	}
+}
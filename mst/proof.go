@@ -0,0 +1,108 @@
+package mst
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/bluesky-social/indigo/util"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+)
+
+var ErrKeyPresent = fmt.Errorf("mst: key present")
+
+// AbsenceProof returns the tree nodes which demonstrate that key is not in the tree: the path of nodes from the root down to the node covering the gap where the key would be. That final node contains the neighboring keys (if any) on either side of the gap, with no subtree pointer between them.
+//
+// The tree is persisted (as with GetPointer) if it has un-stored changes. Returns ErrKeyPresent if the key is in the tree. The proof can be checked against the root CID with VerifyAbsenceProof.
+func (mst *MerkleSearchTree) AbsenceProof(ctx context.Context, key string) ([]blocks.Block, error) {
+	if err := ensureValidMstKey(key); err != nil {
+		return nil, err
+	}
+	// ensures all nodes have a valid pointer and are written to the store
+	if _, err := mst.GetPointer(ctx); err != nil {
+		return nil, err
+	}
+
+	var proof []blocks.Block
+	node := mst
+	for {
+		blk, err := node.nodeBlock(ctx)
+		if err != nil {
+			return nil, err
+		}
+		proof = append(proof, blk)
+
+		entries, err := node.getEntries(ctx)
+		if err != nil {
+			return nil, err
+		}
+		index, err := node.findGtOrEqualLeafIndex(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if index < len(entries) && entries[index].isLeaf() && entries[index].Key == key {
+			return nil, ErrKeyPresent
+		}
+		if index > 0 && entries[index-1].isTree() {
+			node = entries[index-1].Tree
+			continue
+		}
+		return proof, nil
+	}
+}
+
+// returns the stored (CBOR-encoded) block for this node, which must have a valid pointer
+func (mst *MerkleSearchTree) nodeBlock(ctx context.Context) (blocks.Block, error) {
+	var nd NodeData
+	if err := mst.cst.Get(ctx, mst.pointer, &nd); err != nil {
+		return nil, fmt.Errorf("loading node %s: %w", mst.pointer, err)
+	}
+	buf := new(bytes.Buffer)
+	if err := nd.MarshalCBOR(buf); err != nil {
+		return nil, err
+	}
+	c, err := mst.pointer.Prefix().Sum(buf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	if !c.Equals(mst.pointer) {
+		return nil, fmt.Errorf("node %s did not re-encode to the same CID", mst.pointer)
+	}
+	return blocks.NewBlockWithCid(buf.Bytes(), c)
+}
+
+// VerifyAbsenceProof checks that proof (as returned by AbsenceProof) demonstrates that key is not present in the tree with the given root CID. Returns nil if absence is proven, ErrKeyPresent if the proof shows the key is present, or another error if the proof is invalid or incomplete.
+func VerifyAbsenceProof(ctx context.Context, root cid.Cid, key string, proof []blocks.Block) error {
+	if err := ensureValidMstKey(key); err != nil {
+		return err
+	}
+
+	bs := blockstore.NewBlockstore(datastore.NewMapDatastore())
+	for _, blk := range proof {
+		c, err := blk.Cid().Prefix().Sum(blk.RawData())
+		if err != nil {
+			return fmt.Errorf("hashing proof block %s: %w", blk.Cid(), err)
+		}
+		if !c.Equals(blk.Cid()) {
+			return fmt.Errorf("proof block %s does not match its CID", blk.Cid())
+		}
+		if err := bs.Put(ctx, blk); err != nil {
+			return err
+		}
+	}
+
+	t := LoadMST(util.CborStore(bs), root)
+	_, err := t.Get(ctx, key)
+	switch {
+	case err == ErrNotFound:
+		return nil
+	case err == nil:
+		return ErrKeyPresent
+	default:
+		return fmt.Errorf("invalid absence proof: %w", err)
+	}
+}
@@ -0,0 +1,71 @@
+package mst
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+)
+
+func TestAbsenceProof(t *testing.T) {
+	ctx := context.Background()
+
+	vals := map[string]cid.Cid{}
+	for i := 0; i < 400; i += 2 {
+		vals[fmt.Sprintf("com.example.record/k%04d", i)] = strToCid(fmt.Sprintf("v%d", i))
+	}
+	bs := memBs()
+	tree := cidMapToMst(t, bs, vals)
+	root := mustCidTree(t, tree)
+
+	other := cidMapToMst(t, bs, map[string]cid.Cid{"com.example.record/k0000": strToCid("v0")})
+	otherRoot := mustCidTree(t, other)
+
+	for _, tc := range []struct {
+		name string
+		key  string
+	}{
+		{"between", "com.example.record/k0101"},
+		{"beyond max", "com.example.record/zzzz"},
+		{"before min", "app.example.record/k0000"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			proof, err := tree.AbsenceProof(ctx, tc.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(proof) == 0 || proof[0].Cid() != root {
+				t.Fatal("proof should start with the root node")
+			}
+			if err := VerifyAbsenceProof(ctx, root, tc.key, proof); err != nil {
+				t.Fatalf("valid proof failed verification: %v", err)
+			}
+
+			if err := VerifyAbsenceProof(ctx, otherRoot, tc.key, proof); err == nil {
+				t.Fatal("proof verified against the wrong root")
+			}
+			if len(proof) > 1 {
+				err := VerifyAbsenceProof(ctx, root, tc.key, proof[:len(proof)-1])
+				if err == nil || errors.Is(err, ErrKeyPresent) {
+					t.Fatalf("incomplete proof should fail verification, got: %v", err)
+				}
+			}
+		})
+	}
+
+	if _, err := tree.AbsenceProof(ctx, "com.example.record/k0100"); !errors.Is(err, ErrKeyPresent) {
+		t.Fatalf("expected ErrKeyPresent for present key, got: %v", err)
+	}
+
+	// the empty tree proves absence of anything
+	empty := NewEmptyMST(tree.cst)
+	proof, err := empty.AbsenceProof(ctx, "com.example.record/k0101")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyAbsenceProof(ctx, mustCidTree(t, empty), "com.example.record/k0101", proof); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,65 @@
+package mst
+
+import (
+	"context"
+	"fmt"
+
+	cid "github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+)
+
+// SharedNodes compares the tree nodes (not records) of two trees, returning how many nodes of the new tree also appear in the old tree (shared), and how many are new (changed). Either root may be cid.Undef, treated as an empty tree.
+//
+// Changing a single record should only change the nodes along its path, so this is useful for asserting that updates are structurally efficient.
+func SharedNodes(ctx context.Context, bs cbor.IpldBlockstore, oldRoot, newRoot cid.Cid) (shared int, changed int, err error) {
+	cst := rootStore(bs, oldRoot, newRoot)
+
+	oldNodes, err := treeNodes(ctx, cst, oldRoot)
+	if err != nil {
+		return 0, 0, fmt.Errorf("walking old tree: %w", err)
+	}
+	newNodes, err := treeNodes(ctx, cst, newRoot)
+	if err != nil {
+		return 0, 0, fmt.Errorf("walking new tree: %w", err)
+	}
+
+	for c := range newNodes {
+		if oldNodes[c] {
+			shared++
+		} else {
+			changed++
+		}
+	}
+	return shared, changed, nil
+}
+
+// returns the set of all node CIDs in the tree
+func treeNodes(ctx context.Context, cst cbor.IpldStore, root cid.Cid) (map[cid.Cid]bool, error) {
+	nodes := make(map[cid.Cid]bool)
+	if !root.Defined() {
+		return nodes, nil
+	}
+	queue := []cid.Cid{root}
+	for len(queue) > 0 {
+		c := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+		if nodes[c] {
+			continue
+		}
+		nodes[c] = true
+
+		var nd NodeData
+		if err := cst.Get(ctx, c, &nd); err != nil {
+			return nil, fmt.Errorf("loading node %s: %w", c, err)
+		}
+		if nd.Left != nil {
+			queue = append(queue, *nd.Left)
+		}
+		for _, e := range nd.Entries {
+			if e.Tree != nil {
+				queue = append(queue, *e.Tree)
+			}
+		}
+	}
+	return nodes, nil
+}
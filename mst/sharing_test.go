@@ -0,0 +1,66 @@
+package mst
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+)
+
+func TestSharedNodes(t *testing.T) {
+	ctx := context.Background()
+	bs := memBs()
+
+	vals := map[string]cid.Cid{}
+	for i := 0; i < 1000; i++ {
+		vals[fmt.Sprintf("com.example.record/k%04d", i)] = strToCid(fmt.Sprintf("v%d", i))
+	}
+	tree := cidMapToMst(t, bs, vals)
+	oldRoot := mustCidTree(t, tree)
+
+	updated, err := tree.Update(ctx, "com.example.record/k0500", strToCid("changed"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	newRoot := mustCidTree(t, updated)
+
+	shared, changed, err := SharedNodes(ctx, bs, oldRoot, newRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldNodes, err := treeNodes(ctx, tree.cst, oldRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("nodes: %d, shared: %d, changed: %d", len(oldNodes), shared, changed)
+
+	// only the path from the root to the record changes
+	if changed < 1 || changed > 8 {
+		t.Fatalf("expected only a few changed nodes, got %d", changed)
+	}
+	if shared+changed != len(oldNodes) {
+		t.Fatalf("update should not change the node count: %d + %d != %d", shared, changed, len(oldNodes))
+	}
+	if shared < 10*changed {
+		t.Fatalf("expected most nodes to be shared, got %d shared and %d changed", shared, changed)
+	}
+
+	// identical trees share everything
+	shared, changed, err = SharedNodes(ctx, bs, oldRoot, oldRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shared != len(oldNodes) || changed != 0 {
+		t.Fatalf("identical trees: got %d shared and %d changed", shared, changed)
+	}
+
+	// everything is new relative to an empty tree
+	shared, changed, err = SharedNodes(ctx, bs, cid.Undef, newRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shared != 0 || changed != len(oldNodes) {
+		t.Fatalf("from empty tree: got %d shared and %d changed", shared, changed)
+	}
+}
@@ -0,0 +1,212 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	comatproto "github.com/bluesky-social/indigo/api/atproto"
+	atrepo "github.com/bluesky-social/indigo/atproto/repo"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	lexutil "github.com/bluesky-social/indigo/lex/util"
+	"github.com/bluesky-social/indigo/mst"
+	"github.com/bluesky-social/indigo/util"
+
+	"github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	ipld "github.com/ipfs/go-ipld-format"
+	car "github.com/ipld/go-car"
+	carutil "github.com/ipld/go-car/util"
+	cbg "github.com/whyrusleeping/cbor-gen"
+)
+
+// GenerateCommitEvents synthesizes firehose commit messages from the commit history in a repo CAR file, for offline testing of firehose consumers.
+//
+// The commit chain is followed back via `prev` links for as long as the previous commits are present in the CAR. Current repos do not link to previous commits, so usually there is a single commit, which is emitted as a "create" op for every record. Events are returned oldest first, with sequence numbers starting at 1. Each event's ops are the difference from the previous commit, and its blocks contain the commit and the full tree (MST nodes and records) at that commit, so events can be verified independently.
+func GenerateCommitEvents(ctx context.Context, r io.Reader) ([]*comatproto.SyncSubscribeRepos_Commit, error) {
+	bs := atrepo.NewTinyBlockstore()
+	root, err := IngestRepo(ctx, bs, r)
+	if err != nil {
+		return nil, fmt.Errorf("reading repo CAR: %w", err)
+	}
+
+	type chainEntry struct {
+		cid    cid.Cid
+		commit SignedCommit
+	}
+	var chain []chainEntry
+	next := root
+	if err := WalkCommits(ctx, bs, root, func(sc *SignedCommit) error {
+		chain = append(chain, chainEntry{cid: next, commit: *sc})
+		if sc.Prev != nil {
+			next = *sc.Prev
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	slices.Reverse(chain)
+
+	out := make([]*comatproto.SyncSubscribeRepos_Commit, 0, len(chain))
+	prevData := cid.Undef
+	var since *string
+	for i, ent := range chain {
+		diffs, err := mst.DiffTrees(ctx, bs, prevData, ent.commit.Data)
+		if err != nil {
+			return nil, fmt.Errorf("diffing commit %s: %w", ent.cid, err)
+		}
+		ops, err := diffOpsToRepoOps(diffs)
+		if err != nil {
+			return nil, err
+		}
+
+		blocks, err := commitSnapshotCAR(ctx, bs, ent.cid, ent.commit.Data)
+		if err != nil {
+			return nil, err
+		}
+
+		evt := &comatproto.SyncSubscribeRepos_Commit{
+			Repo:   ent.commit.Did,
+			Rev:    ent.commit.Rev,
+			Seq:    int64(i + 1),
+			Since:  since,
+			Time:   syntax.DatetimeNow().String(),
+			Commit: lexutil.LexLink(ent.cid),
+			Blocks: blocks,
+			Ops:    ops,
+			Blobs:  []lexutil.LexLink{},
+		}
+		if prevData.Defined() {
+			pd := lexutil.LexLink(prevData)
+			evt.PrevData = &pd
+		}
+		out = append(out, evt)
+
+		prevData = ent.commit.Data
+		rev := ent.commit.Rev
+		since = &rev
+	}
+
+	return out, nil
+}
+
+// WalkCommits calls cb for each commit in the history of a repo, starting with head and following `prev` links back to earlier commits. The walk stops at a commit with no `prev` (current repos do not link to previous commits), or when the previous commit is not in the blockstore (history truncated, eg in a CAR export). The head commit itself must be present.
+//
+// If cb returns ErrDoneIterating, the walk stops without error; any other error is returned.
+func WalkCommits(ctx context.Context, bs cbor.IpldBlockstore, head cid.Cid, cb func(*SignedCommit) error) error {
+	cst := util.CborStore(bs)
+	next := head
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var sc SignedCommit
+		if err := cst.Get(ctx, next, &sc); err != nil {
+			if ipld.IsNotFound(err) && next != head {
+				return nil
+			}
+			return fmt.Errorf("loading commit %s: %w", next, err)
+		}
+		if err := cb(&sc); err != nil {
+			if errors.Is(err, ErrDoneIterating) {
+				return nil
+			}
+			return err
+		}
+		if sc.Prev == nil {
+			return nil
+		}
+		next = *sc.Prev
+	}
+}
+
+// OpsSinceCommit diffs the repo's current tree (including any uncommitted changes) against the data root of the prior commit prev, returning the record changes as firehose ops: "create" (with cid), "update" (with cid and prev), and "delete" (with prev). This is what's needed for the ops of a #commit message.
+//
+// If prev is cid.Undef, every record is a "create". The prior commit must be in the repo's blockstore.
+func (r *Repo) OpsSinceCommit(ctx context.Context, prev cid.Cid) ([]*comatproto.SyncSubscribeRepos_RepoOp, error) {
+	diffs, err := r.DiffSince(ctx, prev)
+	if err != nil {
+		return nil, fmt.Errorf("diffing against commit %s: %w", prev, err)
+	}
+	return diffOpsToRepoOps(diffs)
+}
+
+func diffOpsToRepoOps(diffs []*mst.DiffOp) ([]*comatproto.SyncSubscribeRepos_RepoOp, error) {
+	ops := make([]*comatproto.SyncSubscribeRepos_RepoOp, 0, len(diffs))
+	for _, d := range diffs {
+		op := &comatproto.SyncSubscribeRepos_RepoOp{Path: d.Rpath}
+		switch d.Op {
+		case "add":
+			op.Action = "create"
+			nc := lexutil.LexLink(d.NewCid)
+			op.Cid = &nc
+		case "mut":
+			op.Action = "update"
+			nc := lexutil.LexLink(d.NewCid)
+			oc := lexutil.LexLink(d.OldCid)
+			op.Cid = &nc
+			op.Prev = &oc
+		case "del":
+			op.Action = "delete"
+			oc := lexutil.LexLink(d.OldCid)
+			op.Prev = &oc
+		default:
+			return nil, fmt.Errorf("unexpected diff op: %s", d.Op)
+		}
+		ops = append(ops, op)
+	}
+	return ops, nil
+}
+
+// writes a CAR with the commit as root, followed by the tree (MST nodes and records) under it. Earlier commits linked by `prev` are not included, nor are any blocks missing from the store (eg, blobs).
+func commitSnapshotCAR(ctx context.Context, bs cbor.IpldBlockstore, commit cid.Cid, data cid.Cid) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := car.WriteHeader(&car.CarHeader{
+		Roots:   []cid.Cid{commit},
+		Version: 1,
+	}, buf); err != nil {
+		return nil, err
+	}
+
+	blk, err := bs.Get(ctx, commit)
+	if err != nil {
+		return nil, err
+	}
+	if err := carutil.LdWrite(buf, commit.Bytes(), blk.RawData()); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[cid.Cid]bool)
+	queue := []cid.Cid{data}
+	for len(queue) > 0 {
+		c := queue[0]
+		queue = queue[1:]
+		if seen[c] {
+			continue
+		}
+		seen[c] = true
+
+		blk, err := bs.Get(ctx, c)
+		if err != nil {
+			if ipld.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		if err := carutil.LdWrite(buf, c.Bytes(), blk.RawData()); err != nil {
+			return nil, err
+		}
+		if c.Prefix().Codec != cid.DagCBOR {
+			continue
+		}
+		if err := cbg.ScanForLinks(bytes.NewReader(blk.RawData()), func(link cid.Cid) {
+			queue = append(queue, link)
+		}); err != nil {
+			return nil, fmt.Errorf("scanning block %s for links: %w", c, err)
+		}
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,297 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	comatproto "github.com/bluesky-social/indigo/api/atproto"
+	"github.com/bluesky-social/indigo/api/bsky"
+	atrepo "github.com/bluesky-social/indigo/atproto/repo"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/bluesky-social/indigo/util"
+
+	blocks "github.com/ipfs/go-block-format"
+	cid "github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/assert"
+	cbg "github.com/whyrusleeping/cbor-gen"
+)
+
+func testSigner(ctx context.Context, did string, b []byte) ([]byte, error) {
+	return []byte("fake signature"), nil
+}
+
+// exports every block reachable from head, including earlier commits, with head as the CAR root
+func repoHistoryCar(t *testing.T, bs *atrepo.TinyBlockstore, head cid.Cid) []byte {
+	t.Helper()
+	ctx := context.Background()
+	var blks []blocks.Block
+	seen := make(map[cid.Cid]bool)
+	queue := []cid.Cid{head}
+	for len(queue) > 0 {
+		c := queue[0]
+		queue = queue[1:]
+		if seen[c] {
+			continue
+		}
+		seen[c] = true
+		blk, err := bs.Get(ctx, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		blks = append(blks, blk)
+		if err := cbg.ScanForLinks(bytes.NewReader(blk.RawData()), func(l cid.Cid) {
+			queue = append(queue, l)
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return writeTestCar(t, blks)
+}
+
+func TestGenerateCommitEvents(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	r := testRepoWithRecords(t, "app.bsky.feed.post/3jzfcijpj2z2a", "app.bsky.feed.post/3jzfcijpj2z2b")
+	bs := r.Blockstore().(*atrepo.TinyBlockstore)
+	first, firstRev, err := r.Commit(ctx, testSigner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	firstData := r.DataCid()
+
+	edited := bsky.FeedPost{Text: "edited", CreatedAt: "2024-01-02T03:04:05.006Z"}
+	if _, err := r.UpdateRecord(ctx, "app.bsky.feed.post/3jzfcijpj2z2b", &edited); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.DeleteRecord(ctx, "app.bsky.feed.post/3jzfcijpj2z2a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := r.CreateRecord(ctx, "app.bsky.feed.like", &bsky.FeedLike{CreatedAt: "2024-01-02T03:04:05.006Z"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := r.Commit(ctx, testSigner); err != nil {
+		t.Fatal(err)
+	}
+	// Commit no longer links to the previous commit, so do that by hand
+	sc := r.SignedCommit()
+	sc.Prev = &first
+	head, err := r.cst.Put(ctx, &sc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	evts, err := GenerateCommitEvents(ctx, bytes.NewReader(repoHistoryCar(t, bs, head)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.Len(evts, 2) {
+		return
+	}
+
+	assert.Equal(int64(1), evts[0].Seq)
+	assert.Equal(firstRev, evts[0].Rev)
+	assert.Equal(first, cid.Cid(evts[0].Commit))
+	assert.Nil(evts[0].Since)
+	assert.Nil(evts[0].PrevData)
+	assert.Len(evts[0].Ops, 2)
+	for _, op := range evts[0].Ops {
+		assert.Equal("create", op.Action)
+	}
+
+	assert.Equal(int64(2), evts[1].Seq)
+	assert.Equal(sc.Rev, evts[1].Rev)
+	assert.Equal(head, cid.Cid(evts[1].Commit))
+	if assert.NotNil(evts[1].Since) {
+		assert.Equal(firstRev, *evts[1].Since)
+	}
+	if assert.NotNil(evts[1].PrevData) {
+		assert.Equal(firstData, cid.Cid(*evts[1].PrevData))
+	}
+	actions := make(map[string]string)
+	for _, op := range evts[1].Ops {
+		actions[op.Path] = op.Action
+	}
+	assert.Len(actions, 3)
+	assert.Equal("delete", actions["app.bsky.feed.post/3jzfcijpj2z2a"])
+	assert.Equal("update", actions["app.bsky.feed.post/3jzfcijpj2z2b"])
+
+	// each event should stand alone, including inverting ops against prevData
+	for _, evt := range evts {
+		_, err := atrepo.VerifyCommitMessage(ctx, evt)
+		assert.NoError(err)
+	}
+
+	// with history truncated, the head commit is emitted with a create for every record
+	evts, err = GenerateCommitEvents(ctx, bytes.NewReader(evts[1].Blocks))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(evts, 1) {
+		assert.Len(evts[0].Ops, 2)
+		assert.Nil(evts[0].PrevData)
+		_, err := atrepo.VerifyCommitMessage(ctx, evts[0])
+		assert.NoError(err)
+	}
+}
+
+func TestWalkCommits(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	bs := atrepo.NewTinyBlockstore()
+	cst := util.CborStore(bs)
+	data := r0DataCid(t)
+
+	// chain of four commits, each linking to the one before
+	var prev *cid.Cid
+	var revs []string
+	clk := syntax.NewTIDClock(0)
+	for i := 0; i < 4; i++ {
+		sc := SignedCommit{
+			Did:     "did:plc:abc123",
+			Version: ATP_REPO_VERSION,
+			Prev:    prev,
+			Data:    data,
+			Rev:     clk.Next().String(),
+			Sig:     []byte("fake signature"),
+		}
+		c, err := cst.Put(ctx, &sc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		prev = &c
+		revs = append(revs, sc.Rev)
+	}
+	head := *prev
+
+	var seen []string
+	assert.NoError(WalkCommits(ctx, bs, head, func(sc *SignedCommit) error {
+		seen = append(seen, sc.Rev)
+		return nil
+	}))
+	assert.Equal([]string{revs[3], revs[2], revs[1], revs[0]}, seen)
+
+	// stopping early
+	seen = nil
+	assert.NoError(WalkCommits(ctx, bs, head, func(sc *SignedCommit) error {
+		seen = append(seen, sc.Rev)
+		if len(seen) == 2 {
+			return ErrDoneIterating
+		}
+		return nil
+	}))
+	assert.Len(seen, 2)
+
+	// callback errors are returned
+	boom := errors.New("boom")
+	assert.ErrorIs(WalkCommits(ctx, bs, head, func(sc *SignedCommit) error { return boom }), boom)
+
+	// truncated history: the previous commit isn't in the blockstore
+	missing := head
+	truncated := SignedCommit{Did: "did:plc:abc123", Version: ATP_REPO_VERSION, Prev: &missing, Data: data, Rev: clk.Next().String()}
+	other := atrepo.NewTinyBlockstore()
+	tc, err := util.CborStore(other).Put(ctx, &truncated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen = nil
+	assert.NoError(WalkCommits(ctx, other, tc, func(sc *SignedCommit) error {
+		seen = append(seen, sc.Rev)
+		return nil
+	}))
+	assert.Equal([]string{truncated.Rev}, seen)
+
+	// the head itself must be present
+	assert.Error(WalkCommits(ctx, other, head, func(sc *SignedCommit) error { return nil }))
+}
+
+// data root of an empty repo, for synthetic commits
+func r0DataCid(t *testing.T) cid.Cid {
+	t.Helper()
+	r := testRepoWithRecords(t)
+	if _, _, err := r.Commit(context.Background(), testSigner); err != nil {
+		t.Fatal(err)
+	}
+	return r.DataCid()
+}
+
+func TestOpsSinceCommit(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	r := testRepoWithRecords(t, "app.bsky.feed.post/3jzfcijpj2z2a", "app.bsky.feed.post/3jzfcijpj2z2b")
+	first, _, err := r.Commit(ctx, testSigner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, deletedCid, err := r.HasRecord(ctx, "app.bsky.feed.post", "3jzfcijpj2z2a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, oldCid, err := r.HasRecord(ctx, "app.bsky.feed.post", "3jzfcijpj2z2b")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// no changes since the commit
+	ops, err := r.OpsSinceCommit(ctx, first)
+	assert.NoError(err)
+	assert.Empty(ops)
+
+	edited := bsky.FeedPost{Text: "edited", CreatedAt: "2024-01-02T03:04:05.006Z"}
+	newCid, err := r.UpdateRecord(ctx, "app.bsky.feed.post/3jzfcijpj2z2b", &edited)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.DeleteRecord(ctx, "app.bsky.feed.post/3jzfcijpj2z2a"); err != nil {
+		t.Fatal(err)
+	}
+	likeCid, likeRkey, err := r.CreateRecord(ctx, "app.bsky.feed.like", &bsky.FeedLike{CreatedAt: "2024-01-02T03:04:05.006Z"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := r.Commit(ctx, testSigner); err != nil {
+		t.Fatal(err)
+	}
+
+	ops, err = r.OpsSinceCommit(ctx, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byPath := make(map[string]*comatproto.SyncSubscribeRepos_RepoOp)
+	for _, op := range ops {
+		byPath[op.Path] = op
+	}
+	assert.Len(byPath, 3)
+
+	if op := byPath["app.bsky.feed.like/"+likeRkey]; assert.NotNil(op) {
+		assert.Equal("create", op.Action)
+		assert.Equal(likeCid, cid.Cid(*op.Cid))
+		assert.Nil(op.Prev)
+	}
+	if op := byPath["app.bsky.feed.post/3jzfcijpj2z2b"]; assert.NotNil(op) {
+		assert.Equal("update", op.Action)
+		assert.Equal(newCid, cid.Cid(*op.Cid))
+		assert.Equal(oldCid, cid.Cid(*op.Prev))
+	}
+	if op := byPath["app.bsky.feed.post/3jzfcijpj2z2a"]; assert.NotNil(op) {
+		assert.Equal("delete", op.Action)
+		assert.Nil(op.Cid)
+		assert.Equal(deletedCid, cid.Cid(*op.Prev))
+	}
+
+	// with no prior commit, every record is a create
+	ops, err = r.OpsSinceCommit(ctx, cid.Undef)
+	assert.NoError(err)
+	assert.Len(ops, 2)
+	for _, op := range ops {
+		assert.Equal("create", op.Action)
+	}
+
+	// the prior commit must be in the blockstore
+	_, err = r.OpsSinceCommit(ctx, r0DataCid(t))
+	assert.Error(err)
+}
@@ -0,0 +1,278 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/bluesky-social/indigo/mst"
+	"github.com/bluesky-social/indigo/util"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"go.opentelemetry.io/otel"
+)
+
+// Returned (wrapped) by ImportCARConcurrent when a commit references a block which is not in the CAR file
+var ErrMissingBlock = errors.New("block referenced by commit missing from CAR")
+
+// CommitApplyFunc is called by ImportCARConcurrent for each commit in the CAR, in rev order, with the record operations since the previously applied commit (for the first commit, every record is an "add").
+type CommitApplyFunc func(ctx context.Context, commit cid.Cid, sc *SignedCommit, ops []*mst.DiffOp) error
+
+// ImportCARConcurrent reads a CAR file containing one or more commits of a single repo into bs, and applies each commit via the apply callback (which may be nil). Returns the repo opened at the final commit, which must be the root of the CAR.
+//
+// Blocks are read into the blockstore in a separate goroutine, while commits are checked as they are found: before a commit is applied, its entire tree (MST nodes and records) must be present, waiting for blocks still being read. Commits are applied in rev order. The first commit of a chain linked by `prev` (one with no `prev`, or whose `prev` is not in the file) is applied once a commit following it has been found, and a commit whose `prev` is the last applied commit is applied as soon as its tree is complete; any others are applied once the whole file has been read.
+//
+// bs must be safe for concurrent use.
+func ImportCARConcurrent(ctx context.Context, bs cbor.IpldBlockstore, r io.Reader, apply CommitApplyFunc) (*Repo, error) {
+	ctx, span := otel.Tracer("repo").Start(ctx, "ImportCARConcurrent")
+	defer span.End()
+
+	// stops the reader if we return early
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ci := &carImport{present: make(map[cid.Cid]bool)}
+	ci.cond = sync.NewCond(&ci.lk)
+	go ci.read(ctx, bs, r)
+
+	cst := util.CborStore(bs)
+	complete := make(map[cid.Cid]bool)
+
+	var last *foundCommit
+	prevData := cid.Undef
+	applyNext := func(fc foundCommit) error {
+		if last != nil && fc.sc.Rev <= last.sc.Rev {
+			return fmt.Errorf("commit %s rev %q is not after previous commit rev %q", fc.cid, fc.sc.Rev, last.sc.Rev)
+		}
+		diffs, err := mst.DiffTrees(ctx, bs, prevData, fc.sc.Data)
+		if err != nil {
+			return fmt.Errorf("diffing commit %s: %w", fc.cid, err)
+		}
+		if apply != nil {
+			if err := apply(ctx, fc.cid, &fc.sc, diffs); err != nil {
+				return err
+			}
+		}
+		last = &fc
+		prevData = fc.sc.Data
+		return nil
+	}
+
+	var pending []foundCommit
+	for i := 0; ; i++ {
+		fc, ok, err := ci.commit(i)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		if err := ci.checkTree(ctx, cst, fc.sc.Data, complete); err != nil {
+			return nil, fmt.Errorf("commit %s: %w", fc.cid, err)
+		}
+		pending = append(pending, fc)
+
+		// apply pending commits as soon as they can be ordered: first the start of the commit chain, then any which directly follow the last applied commit
+		for {
+			var j int
+			if last == nil {
+				j = slices.IndexFunc(pending, func(p foundCommit) bool {
+					return ci.isChainStart(p, pending)
+				})
+			} else {
+				j = slices.IndexFunc(pending, func(p foundCommit) bool {
+					return p.sc.Prev != nil && *p.sc.Prev == last.cid
+				})
+			}
+			if j < 0 {
+				break
+			}
+			next := pending[j]
+			pending = slices.Delete(pending, j, j+1)
+			if err := applyNext(next); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// the whole file has been read; apply the rest in rev order
+	slices.SortStableFunc(pending, func(a, b foundCommit) int {
+		return strings.Compare(a.sc.Rev, b.sc.Rev)
+	})
+	for _, fc := range pending {
+		if err := applyNext(fc); err != nil {
+			return nil, err
+		}
+	}
+
+	if last == nil {
+		return nil, fmt.Errorf("no commits found in CAR")
+	}
+	if len(ci.roots) == 0 || ci.roots[0] != last.cid {
+		return nil, fmt.Errorf("CAR root does not match latest commit %s (rev %s)", last.cid, last.sc.Rev)
+	}
+	return OpenRepo(ctx, bs, last.cid)
+}
+
+type foundCommit struct {
+	cid cid.Cid
+	sc  SignedCommit
+}
+
+// shared state between the CAR reader goroutine and the commit applier
+type carImport struct {
+	lk   sync.Mutex
+	cond *sync.Cond
+
+	present map[cid.Cid]bool
+	// commits found so far, in file order
+	commits []foundCommit
+
+	done  bool
+	err   error
+	roots []cid.Cid
+}
+
+func (ci *carImport) read(ctx context.Context, bs cbor.IpldBlockstore, r io.Reader) {
+	roots, err := WalkCAR(ctx, r, func(c cid.Cid, data []byte) error {
+		blk, err := blocks.NewBlockWithCid(data, c)
+		if err != nil {
+			return err
+		}
+		if err := bs.Put(ctx, blk); err != nil {
+			return fmt.Errorf("copying block to store: %w", err)
+		}
+		sc, isCommit := parseCommitBlock(c, data)
+
+		ci.lk.Lock()
+		ci.present[c] = true
+		if isCommit {
+			ci.commits = append(ci.commits, foundCommit{cid: c, sc: *sc})
+		}
+		ci.lk.Unlock()
+		ci.cond.Broadcast()
+		return nil
+	})
+
+	ci.lk.Lock()
+	ci.done = true
+	ci.err = err
+	ci.roots = roots
+	ci.lk.Unlock()
+	ci.cond.Broadcast()
+}
+
+// returns the i-th commit in file order, waiting for it to be read. Returns false if the file ended with fewer commits, or the error if reading failed.
+func (ci *carImport) commit(i int) (foundCommit, bool, error) {
+	ci.lk.Lock()
+	defer ci.lk.Unlock()
+	for len(ci.commits) <= i && !ci.done {
+		ci.cond.Wait()
+	}
+	if len(ci.commits) > i {
+		return ci.commits[i], true, nil
+	}
+	return foundCommit{}, false, ci.err
+}
+
+// whether fc is known to be the earliest commit of a chain linked by `prev`: another pending commit follows it, and its own `prev` is either absent, or not in the file (which is only known once the whole file has been read).
+//
+// Commits without `prev` which nothing follows (eg, repos which don't link commits) can't be ordered until the whole file has been read.
+func (ci *carImport) isChainStart(fc foundCommit, pending []foundCommit) bool {
+	followed := slices.ContainsFunc(pending, func(p foundCommit) bool {
+		return p.sc.Prev != nil && *p.sc.Prev == fc.cid
+	})
+	if !followed {
+		return false
+	}
+	if fc.sc.Prev == nil {
+		return true
+	}
+
+	ci.lk.Lock()
+	defer ci.lk.Unlock()
+	if !ci.done {
+		return false
+	}
+	return !slices.ContainsFunc(ci.commits, func(c foundCommit) bool {
+		return c.cid == *fc.sc.Prev
+	})
+}
+
+// waits for the block to be read. Returns false if the file ended without it, or the error if reading failed.
+func (ci *carImport) waitFor(c cid.Cid) (bool, error) {
+	ci.lk.Lock()
+	defer ci.lk.Unlock()
+	for !ci.present[c] && !ci.done {
+		ci.cond.Wait()
+	}
+	if ci.present[c] {
+		return true, nil
+	}
+	return false, ci.err
+}
+
+// checks that every MST node and record under root has been read, waiting for blocks as needed. Nodes whose subtrees are known to be complete are recorded in complete, so trees shared between commits are only walked once.
+func (ci *carImport) checkTree(ctx context.Context, cst cbor.IpldStore, root cid.Cid, complete map[cid.Cid]bool) error {
+	if complete[root] {
+		return nil
+	}
+	ok, err := ci.waitFor(root)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: MST node %s", ErrMissingBlock, root)
+	}
+
+	var nd mst.NodeData
+	if err := cst.Get(ctx, root, &nd); err != nil {
+		return fmt.Errorf("loading MST node %s: %w", root, err)
+	}
+	if nd.Left != nil {
+		if err := ci.checkTree(ctx, cst, *nd.Left, complete); err != nil {
+			return err
+		}
+	}
+	for _, e := range nd.Entries {
+		ok, err := ci.waitFor(e.Val)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("%w: record %s", ErrMissingBlock, e.Val)
+		}
+		if e.Tree != nil {
+			if err := ci.checkTree(ctx, cst, *e.Tree, complete); err != nil {
+				return err
+			}
+		}
+	}
+	complete[root] = true
+	return nil
+}
+
+// decodes the block as a signed commit, if it looks like one. Unknown fields are ignored when decoding, so other blocks (like MST nodes) decode without error; check for the required fields.
+func parseCommitBlock(c cid.Cid, data []byte) (*SignedCommit, bool) {
+	if c.Prefix().Codec != cid.DagCBOR {
+		return nil, false
+	}
+	var sc SignedCommit
+	if err := sc.UnmarshalCBOR(bytes.NewReader(data)); err != nil {
+		return nil, false
+	}
+	if sc.Version != ATP_REPO_VERSION && sc.Version != ATP_REPO_VERSION_2 {
+		return nil, false
+	}
+	if !strings.HasPrefix(sc.Did, "did:") || !sc.Data.Defined() || len(sc.Sig) == 0 {
+		return nil, false
+	}
+	return &sc, true
+}
@@ -0,0 +1,219 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/bluesky-social/indigo/api/bsky"
+	"github.com/bluesky-social/indigo/mst"
+
+	blocks "github.com/ipfs/go-block-format"
+	cid "github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	"github.com/ipld/go-car"
+	carutil "github.com/ipld/go-car/util"
+	"github.com/stretchr/testify/assert"
+)
+
+// returns the blocks of a commit snapshot: the commit, followed by its tree
+func commitBlocks(t *testing.T, r *Repo, commit cid.Cid, data cid.Cid) []blocks.Block {
+	t.Helper()
+	ctx := context.Background()
+	snapshot, err := commitSnapshotCAR(ctx, r.Blockstore(), commit, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var blks []blocks.Block
+	if _, err := WalkCAR(ctx, bytes.NewReader(snapshot), func(c cid.Cid, b []byte) error {
+		blk, err := blocks.NewBlockWithCid(b, c)
+		blks = append(blks, blk)
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return blks
+}
+
+func TestImportCARConcurrent(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	r := testRepoWithRecords(t, "app.bsky.feed.post/3jzfcijpj2z2a", "app.bsky.feed.post/3jzfcijpj2z2b")
+	c1, rev1, err := r.Commit(ctx, testSigner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap1 := commitBlocks(t, r, c1, r.DataCid())
+
+	edited := bsky.FeedPost{Text: "edited", CreatedAt: "2024-01-02T03:04:05.006Z"}
+	if _, err := r.UpdateRecord(ctx, "app.bsky.feed.post/3jzfcijpj2z2b", &edited); err != nil {
+		t.Fatal(err)
+	}
+	c2, rev2, err := r.Commit(ctx, testSigner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap2 := commitBlocks(t, r, c2, r.DataCid())
+
+	if err := r.DeleteRecord(ctx, "app.bsky.feed.post/3jzfcijpj2z2a"); err != nil {
+		t.Fatal(err)
+	}
+	post := bsky.FeedPost{Text: "third", CreatedAt: "2024-01-02T03:04:05.006Z"}
+	if _, err := r.PutRecord(ctx, "app.bsky.feed.post/3jzfcijpj2z2c", &post); err != nil {
+		t.Fatal(err)
+	}
+	c3, rev3, err := r.Commit(ctx, testSigner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap3 := commitBlocks(t, r, c3, r.DataCid())
+
+	// the head commit comes first (as the CAR root), but its tree comes last; the older commits are out of order
+	var all []blocks.Block
+	all = append(all, snap3[0])
+	all = append(all, snap2...)
+	all = append(all, snap1...)
+	all = append(all, snap3[1:]...)
+	carBytes := writeTestCar(t, all)
+
+	newStore := func() blockstore.Blockstore {
+		return blockstore.NewBlockstore(dssync.MutexWrap(datastore.NewMapDatastore()))
+	}
+
+	var revs []string
+	var ops [][]*mst.DiffOp
+	out, err := ImportCARConcurrent(ctx, newStore(), bytes.NewReader(carBytes), func(ctx context.Context, commit cid.Cid, sc *SignedCommit, diffs []*mst.DiffOp) error {
+		revs = append(revs, sc.Rev)
+		ops = append(ops, diffs)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal([]string{rev1, rev2, rev3}, revs)
+	assert.Len(ops[0], 2)
+	assert.Len(ops[1], 1)
+	assert.Equal("mut", ops[1][0].Op)
+	assert.Len(ops[2], 2)
+
+	// final root matches the source repo
+	assert.Equal(r.DataCid(), out.DataCid())
+	sc := out.SignedCommit()
+	assert.Equal(rev3, sc.Rev)
+	assert.NoError(out.VerifyDataRoot(ctx))
+
+	// missing a record block
+	var missing []blocks.Block
+	for _, blk := range all {
+		if blk.Cid() != ops[2][1].NewCid && blk.Cid() != ops[2][0].NewCid {
+			missing = append(missing, blk)
+		}
+	}
+	_, err = ImportCARConcurrent(ctx, newStore(), bytes.NewReader(writeTestCar(t, missing)), nil)
+	assert.ErrorIs(err, ErrMissingBlock)
+
+	// the CAR root is not the latest commit
+	var stale []blocks.Block
+	stale = append(stale, snap1...)
+	stale = append(stale, snap2...)
+	_, err = ImportCARConcurrent(ctx, newStore(), bytes.NewReader(writeTestCar(t, stale)), nil)
+	assert.ErrorContains(err, "CAR root")
+
+	// no commits at all
+	_, err = ImportCARConcurrent(ctx, newStore(), bytes.NewReader(writeTestCar(t, snap1[1:])), nil)
+	assert.Error(err)
+}
+
+func TestImportCARConcurrentOverlapsReading(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	// a history of three commits linked by prev, oldest first, each followed by its tree
+	r := testRepoWithRecords(t, "app.bsky.feed.post/3jzfcijpj2z2a")
+	c1, _, err := r.Commit(ctx, testSigner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commits := []cid.Cid{c1}
+	snaps := [][]blocks.Block{commitBlocks(t, r, c1, r.DataCid())}
+	for _, rkey := range []string{"3jzfcijpj2z2b", "3jzfcijpj2z2c"} {
+		post := bsky.FeedPost{Text: rkey, CreatedAt: "2024-01-02T03:04:05.006Z"}
+		if _, err := r.PutRecord(ctx, "app.bsky.feed.post/"+rkey, &post); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := r.Commit(ctx, testSigner); err != nil {
+			t.Fatal(err)
+		}
+		// Commit doesn't link to the previous commit, so do that by hand
+		sc := r.SignedCommit()
+		sc.Prev = &commits[len(commits)-1]
+		c, err := r.cst.Put(ctx, &sc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		commits = append(commits, c)
+		snaps = append(snaps, commitBlocks(t, r, c, r.DataCid()))
+	}
+
+	buf := new(bytes.Buffer)
+	if err := car.WriteHeader(&car.CarHeader{Roots: []cid.Cid{commits[2]}, Version: 1}, buf); err != nil {
+		t.Fatal(err)
+	}
+	var split int
+	for i, snap := range snaps {
+		for _, blk := range snap {
+			if err := carutil.LdWrite(buf, blk.Cid().Bytes(), blk.RawData()); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if i == 1 {
+			split = buf.Len()
+		}
+	}
+	carBytes := buf.Bytes()
+
+	// only the first two commits are written until the first apply happens
+	pr, pw := io.Pipe()
+	applied := make(chan cid.Cid, len(commits))
+	type result struct {
+		r   *Repo
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		bs := blockstore.NewBlockstore(dssync.MutexWrap(datastore.NewMapDatastore()))
+		out, err := ImportCARConcurrent(ctx, bs, pr, func(ctx context.Context, commit cid.Cid, sc *SignedCommit, diffs []*mst.DiffOp) error {
+			applied <- commit
+			return nil
+		})
+		done <- result{out, err}
+	}()
+
+	if _, err := pw.Write(carBytes[:split]); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case c := <-applied:
+		assert.Equal(commits[0], c)
+	case <-time.After(5 * time.Second):
+		pw.Close()
+		t.Fatal("no commit applied before the end of the CAR was read")
+	}
+
+	if _, err := pw.Write(carBytes[split:]); err != nil {
+		t.Fatal(err)
+	}
+	pw.Close()
+	res := <-done
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+	assert.Equal(commits[1], <-applied)
+	assert.Equal(commits[2], <-applied)
+	assert.Equal(r.DataCid(), res.r.DataCid())
+}
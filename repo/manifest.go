@@ -0,0 +1,127 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ipfs/go-cid"
+	"go.opentelemetry.io/otel"
+)
+
+// ErrManifestMismatch is wrapped by errors from [VerifyCARManifest] when the CAR does not match the manifest
+var ErrManifestMismatch = errors.New("CAR does not match manifest")
+
+// CARManifest describes an exported repo CAR file, for integrity verification with [VerifyCARManifest]. It is intended to be stored alongside the CAR as JSON.
+type CARManifest struct {
+	// CID of the signed commit, which is the CAR root
+	Root string `json:"root"`
+	// total length of the CAR file in bytes
+	Size int64 `json:"size"`
+	// hex-encoded SHA-256 digest of the entire CAR file
+	SHA256 string `json:"sha256"`
+	// every block in the CAR, in file order
+	Blocks []ManifestBlock `json:"blocks"`
+}
+
+type ManifestBlock struct {
+	CID string `json:"cid"`
+	// length of the block data in bytes, not including the CID and length prefix
+	Len int `json:"len"`
+}
+
+// ExportManifest writes a CAR file of the current commit and its tree (MST nodes and records) to w, and returns a manifest describing it.
+//
+// The repo must have been committed (or opened from a commit), with no uncommitted changes.
+func (r *Repo) ExportManifest(ctx context.Context, w io.Writer) (*CARManifest, error) {
+	ctx, span := otel.Tracer("repo").Start(ctx, "ExportManifest")
+	defer span.End()
+
+	if r.dirty || !r.repoCid.Defined() {
+		return nil, fmt.Errorf("repo has uncommitted changes")
+	}
+
+	data, err := commitSnapshotCAR(ctx, r.bs, r.repoCid, r.sc.Data)
+	if err != nil {
+		return nil, fmt.Errorf("writing repo CAR: %w", err)
+	}
+
+	digest := sha256.Sum256(data)
+	manifest := &CARManifest{
+		Root:   r.repoCid.String(),
+		Size:   int64(len(data)),
+		SHA256: hex.EncodeToString(digest[:]),
+	}
+	if _, err := WalkCAR(ctx, bytes.NewReader(data), func(c cid.Cid, blk []byte) error {
+		manifest.Blocks = append(manifest.Blocks, ManifestBlock{CID: c.String(), Len: len(blk)})
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("reading back repo CAR: %w", err)
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// VerifyCARManifest re-reads a CAR file and checks it against a manifest from [Repo.ExportManifest]: the root, every block CID (including that the block data hashes to its CID) and length, and the size and digest of the whole file. Mismatches return an error wrapping [ErrManifestMismatch].
+func VerifyCARManifest(ctx context.Context, r io.Reader, manifest *CARManifest) error {
+	h := sha256.New()
+	cr := &countingReader{r: io.TeeReader(r, h)}
+
+	i := 0
+	roots, err := WalkCAR(ctx, cr, func(c cid.Cid, blk []byte) error {
+		if i >= len(manifest.Blocks) {
+			return fmt.Errorf("%w: unexpected block %s", ErrManifestMismatch, c)
+		}
+		want := manifest.Blocks[i]
+		i++
+		if c.String() != want.CID || len(blk) != want.Len {
+			return fmt.Errorf("%w: block %d is %s (%d bytes), expected %s (%d bytes)", ErrManifestMismatch, i-1, c, len(blk), want.CID, want.Len)
+		}
+		computed, err := c.Prefix().Sum(blk)
+		if err != nil {
+			return err
+		}
+		if !computed.Equals(c) {
+			return fmt.Errorf("%w: block data does not match CID %s", ErrManifestMismatch, c)
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrManifestMismatch) {
+			return err
+		}
+		return fmt.Errorf("%w: %w", ErrManifestMismatch, err)
+	}
+
+	if len(roots) != 1 || roots[0].String() != manifest.Root {
+		return fmt.Errorf("%w: CAR roots %v, expected %s", ErrManifestMismatch, roots, manifest.Root)
+	}
+	if i != len(manifest.Blocks) {
+		return fmt.Errorf("%w: CAR has %d blocks, expected %d", ErrManifestMismatch, i, len(manifest.Blocks))
+	}
+	if cr.n != manifest.Size {
+		return fmt.Errorf("%w: CAR is %d bytes, expected %d", ErrManifestMismatch, cr.n, manifest.Size)
+	}
+	if digest := hex.EncodeToString(h.Sum(nil)); digest != manifest.SHA256 {
+		return fmt.Errorf("%w: CAR digest %s, expected %s", ErrManifestMismatch, digest, manifest.SHA256)
+	}
+	return nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
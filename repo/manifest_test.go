@@ -0,0 +1,81 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportManifest(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	r := testRepoWithRecords(t,
+		"app.bsky.feed.post/3jzfcijpj2z2a",
+		"app.bsky.feed.post/3jzfcijpj2z2b",
+		"app.bsky.feed.like/3jzfcijpj2z2c",
+	)
+
+	// uncommitted repo can't be exported
+	_, err := r.ExportManifest(ctx, &bytes.Buffer{})
+	assert.Error(err)
+
+	root, _, err := r.Commit(ctx, testSigner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	manifest, err := r.ExportManifest(ctx, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	carBytes := buf.Bytes()
+	assert.Equal(root.String(), manifest.Root)
+	assert.Equal(int64(len(carBytes)), manifest.Size)
+	// commit, at least one MST node, and three records
+	assert.GreaterOrEqual(len(manifest.Blocks), 5)
+	assert.Equal(root.String(), manifest.Blocks[0].CID)
+
+	// manifest survives a JSON round-trip
+	mj, err := json.Marshal(manifest)
+	assert.NoError(err)
+	var decoded CARManifest
+	assert.NoError(json.Unmarshal(mj, &decoded))
+	assert.NoError(VerifyCARManifest(ctx, bytes.NewReader(carBytes), &decoded))
+
+	// the CAR can be read back as a repo
+	r2, err := ReadRepoFromCar(ctx, bytes.NewReader(carBytes))
+	assert.NoError(err)
+	assert.Equal(r.DataCid(), r2.DataCid())
+
+	// truncated partway through a block
+	err = VerifyCARManifest(ctx, bytes.NewReader(carBytes[:len(carBytes)-10]), manifest)
+	assert.ErrorIs(err, ErrManifestMismatch)
+
+	// truncated at a block boundary: drop the final block entirely
+	last := manifest.Blocks[len(manifest.Blocks)-1]
+	// each block section is a varint length, followed by a 36-byte CIDv1 and the data
+	section := 36 + last.Len
+	cut := len(carBytes) - (len(binary.AppendUvarint(nil, uint64(section))) + section)
+	err = VerifyCARManifest(ctx, bytes.NewReader(carBytes[:cut]), manifest)
+	assert.ErrorIs(err, ErrManifestMismatch)
+	assert.ErrorContains(err, "blocks")
+
+	// same length, but corrupted contents
+	corrupt := bytes.Clone(carBytes)
+	corrupt[len(corrupt)-1] ^= 0xff
+	err = VerifyCARManifest(ctx, bytes.NewReader(corrupt), manifest)
+	assert.ErrorIs(err, ErrManifestMismatch)
+
+	// uncommitted changes after export
+	if err := r.DeleteRecord(ctx, "app.bsky.feed.like/3jzfcijpj2z2c"); err != nil {
+		t.Fatal(err)
+	}
+	_, err = r.ExportManifest(ctx, &bytes.Buffer{})
+	assert.Error(err)
+}
@@ -0,0 +1,228 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+
+	"github.com/ipfs/go-cid"
+	"go.opentelemetry.io/otel"
+)
+
+// A single record create to apply to a repo. The path must not already exist in the repo.
+type CreateOp struct {
+	Path   string
+	Record CborMarshaler
+}
+
+// Checks that a batch of operations could be applied to this repo, without mutating any state.
+//
+// Each op's path must be a valid "<collection>/<rkey>" repo path which is not already in the repo, paths must not repeat within the batch, and each record must marshal to CBOR so that a CID can be computed. Returns the first failure found, annotated with the op index and path.
+func (r *Repo) ValidateOps(ctx context.Context, ops []CreateOp) error {
+	seen := make(map[string]bool, len(ops))
+	for i, op := range ops {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if _, err := r.validateOp(op); err != nil {
+			return fmt.Errorf("invalid op %d (%q): %w", i, op.Path, err)
+		}
+		if seen[op.Path] {
+			return fmt.Errorf("invalid op %d (%q): duplicate path in batch", i, op.Path)
+		}
+		seen[op.Path] = true
+
+		coll, rkey, _ := syntax.ParseRepoPath(op.Path)
+		exists, _, err := r.HasRecord(ctx, coll.String(), rkey.String())
+		if err != nil {
+			return fmt.Errorf("invalid op %d (%q): %w", i, op.Path, err)
+		}
+		if exists {
+			return fmt.Errorf("invalid op %d (%q): record already exists", i, op.Path)
+		}
+	}
+	return nil
+}
+
+// validates a single op, returning the CID its record would be stored under
+func (r *Repo) validateOp(op CreateOp) (cid.Cid, error) {
+	if _, _, err := syntax.ParseRepoPath(op.Path); err != nil {
+		return cid.Undef, fmt.Errorf("bad record path: %w", err)
+	}
+	if op.Record == nil {
+		return cid.Undef, fmt.Errorf("missing record")
+	}
+	buf := new(bytes.Buffer)
+	if err := op.Record.MarshalCBOR(buf); err != nil {
+		return cid.Undef, fmt.Errorf("failed to marshal record: %w", err)
+	}
+	c, err := cid.NewPrefixV1(cid.DagCBOR, r.HashFunc()).Sum(buf.Bytes())
+	if err != nil {
+		return cid.Undef, fmt.Errorf("failed to compute record CID: %w", err)
+	}
+	return c, nil
+}
+
+// Applies a batch of record writes to the repo, returning the resulting MST root CID.
+//
+// The batch is atomic: if any op fails, the in-memory repo tree is reverted to its state before the call. Blocks already written to the blockstore by earlier ops are not removed. Does not create a commit; callers still need to call Commit.
+func ApplyOps(ctx context.Context, r *Repo, ops []CreateOp) (cid.Cid, error) {
+	ctx, span := otel.Tracer("repo").Start(ctx, "ApplyOps")
+	defer span.End()
+
+	t, err := r.getMst(ctx)
+	if err != nil {
+		return cid.Undef, fmt.Errorf("failed to get mst: %w", err)
+	}
+	origDirty := r.dirty
+
+	rollback := func() {
+		r.mst = t
+		r.dirty = origDirty
+	}
+
+	for i, op := range ops {
+		if _, _, err := syntax.ParseRepoPath(op.Path); err != nil {
+			rollback()
+			return cid.Undef, fmt.Errorf("op %d (%q): bad record path: %w", i, op.Path, err)
+		}
+		if op.Record == nil {
+			rollback()
+			return cid.Undef, fmt.Errorf("op %d (%q): missing record", i, op.Path)
+		}
+		if _, err := r.PutRecord(ctx, op.Path, op.Record); err != nil {
+			rollback()
+			return cid.Undef, fmt.Errorf("op %d (%q): %w", i, op.Path, err)
+		}
+	}
+
+	nt, err := r.getMst(ctx)
+	if err != nil {
+		rollback()
+		return cid.Undef, err
+	}
+	root, err := nt.GetPointer(ctx)
+	if err != nil {
+		rollback()
+		return cid.Undef, fmt.Errorf("failed to compute repo root: %w", err)
+	}
+	return root, nil
+}
+
+// Type of a Write, matching the com.atproto.repo.applyWrites operations
+type WriteAction string
+
+const (
+	WriteCreate WriteAction = "create"
+	WriteUpdate WriteAction = "update"
+	WriteDelete WriteAction = "delete"
+)
+
+// A single record create, update, or delete to apply with ApplyWrites.
+type Write struct {
+	Action     WriteAction
+	Collection string
+	// Optional for creates, which generate a TID record key if empty. Required otherwise.
+	Rkey string
+	// The record, for creates and updates. Must be nil for deletes.
+	Value CborMarshaler
+}
+
+// The outcome of a single Write. For creates, Rkey is the generated record key if one was not supplied. Cid is the new record CID, or cid.Undef for deletes.
+type WriteResult struct {
+	Action     WriteAction
+	Collection string
+	Rkey       string
+	Cid        cid.Cid
+}
+
+func (w *Write) validate() error {
+	if _, err := syntax.ParseNSID(w.Collection); err != nil {
+		return fmt.Errorf("bad collection: %w", err)
+	}
+	if w.Rkey != "" {
+		if _, err := syntax.ParseRecordKey(w.Rkey); err != nil {
+			return fmt.Errorf("bad record key: %w", err)
+		}
+	}
+	switch w.Action {
+	case WriteCreate:
+	case WriteUpdate, WriteDelete:
+		if w.Rkey == "" {
+			return fmt.Errorf("missing record key")
+		}
+	default:
+		return fmt.Errorf("unknown write action: %q", w.Action)
+	}
+	if w.Action == WriteDelete && w.Value != nil {
+		return fmt.Errorf("delete with record value")
+	}
+	if w.Action != WriteDelete && w.Value == nil {
+		return fmt.Errorf("missing record")
+	}
+	return nil
+}
+
+// ApplyWrites applies a batch of record creates, updates, and deletes to the repo, like the com.atproto.repo.applyWrites endpoint, returning the resulting MST root CID and a result for each write.
+//
+// Creates fail if the record already exists; updates and deletes fail if it does not. Writes are applied in order, so a later write can modify a record created earlier in the batch.
+//
+// As with ApplyOps, the batch is atomic: every write is validated before any is applied, and if any write fails the in-memory repo tree is reverted. Does not create a commit; callers still need to call Commit, so that the whole batch lands in a single commit.
+func (r *Repo) ApplyWrites(ctx context.Context, writes []Write) (cid.Cid, []WriteResult, error) {
+	ctx, span := otel.Tracer("repo").Start(ctx, "ApplyWrites")
+	defer span.End()
+
+	for i := range writes {
+		if err := writes[i].validate(); err != nil {
+			return cid.Undef, nil, fmt.Errorf("write %d (%s %s): %w", i, writes[i].Action, writes[i].Collection, err)
+		}
+	}
+
+	t, err := r.getMst(ctx)
+	if err != nil {
+		return cid.Undef, nil, fmt.Errorf("failed to get mst: %w", err)
+	}
+	origDirty := r.dirty
+
+	rollback := func() {
+		r.mst = t
+		r.dirty = origDirty
+	}
+
+	results := make([]WriteResult, len(writes))
+	for i, w := range writes {
+		res := WriteResult{Action: w.Action, Collection: w.Collection, Rkey: w.Rkey}
+		var err error
+		switch w.Action {
+		case WriteCreate:
+			if res.Rkey == "" {
+				res.Cid, res.Rkey, err = r.CreateRecord(ctx, w.Collection, w.Value)
+			} else {
+				res.Cid, err = r.PutRecord(ctx, w.Collection+"/"+w.Rkey, w.Value)
+			}
+		case WriteUpdate:
+			res.Cid, err = r.UpdateRecord(ctx, w.Collection+"/"+w.Rkey, w.Value)
+		case WriteDelete:
+			err = r.DeleteRecord(ctx, w.Collection+"/"+w.Rkey)
+		}
+		if err != nil {
+			rollback()
+			return cid.Undef, nil, fmt.Errorf("write %d (%s %s/%s): %w", i, w.Action, w.Collection, res.Rkey, err)
+		}
+		results[i] = res
+	}
+
+	nt, err := r.getMst(ctx)
+	if err != nil {
+		rollback()
+		return cid.Undef, nil, err
+	}
+	root, err := nt.GetPointer(ctx)
+	if err != nil {
+		rollback()
+		return cid.Undef, nil, fmt.Errorf("failed to compute repo root: %w", err)
+	}
+	return root, results, nil
+}
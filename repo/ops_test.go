@@ -0,0 +1,211 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/bluesky-social/indigo/api/bsky"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/assert"
+)
+
+// record type which always fails to serialize
+type brokenRecord struct{}
+
+func (br *brokenRecord) MarshalCBOR(w io.Writer) error {
+	return errors.New("broken record")
+}
+
+func TestValidateOps(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	r := testRepoWithRecords(t, "app.bsky.feed.post/3jzfcijpj2z2a")
+	origMst := r.mst
+	origDirty := r.dirty
+
+	post := &bsky.FeedPost{Text: "hello", CreatedAt: "2024-01-02T03:04:05.006Z"}
+	good := []CreateOp{
+		{Path: "app.bsky.feed.post/3jzfcijpj2z2b", Record: post},
+		{Path: "app.bsky.actor.profile/self", Record: &bsky.ActorProfile{}},
+	}
+	assert.NoError(r.ValidateOps(ctx, good))
+	assert.NoError(r.ValidateOps(ctx, nil))
+
+	err := r.ValidateOps(ctx, append(good, CreateOp{Path: "app.bsky.feed.post", Record: post}))
+	assert.ErrorContains(err, "invalid op 2")
+	assert.ErrorContains(err, "bad record path")
+
+	err = r.ValidateOps(ctx, append(good, CreateOp{Path: "app.bsky.feed.post/a/b", Record: post}))
+	assert.ErrorContains(err, "bad record path")
+
+	err = r.ValidateOps(ctx, []CreateOp{{Path: "app.bsky.feed.post/3jzfcijpj2z2c", Record: &brokenRecord{}}})
+	assert.ErrorContains(err, "invalid op 0")
+	assert.ErrorContains(err, "broken record")
+
+	err = r.ValidateOps(ctx, []CreateOp{{Path: "app.bsky.feed.post/3jzfcijpj2z2c"}})
+	assert.ErrorContains(err, "missing record")
+
+	err = r.ValidateOps(ctx, append(good, good[0]))
+	assert.ErrorContains(err, "duplicate path")
+
+	// ops are creates, so an existing path fails both validation and apply
+	existing := []CreateOp{{Path: "app.bsky.feed.post/3jzfcijpj2z2a", Record: post}}
+	err = r.ValidateOps(ctx, existing)
+	assert.ErrorContains(err, "invalid op 0")
+	assert.ErrorContains(err, "already exists")
+	_, err = ApplyOps(ctx, r, existing)
+	assert.Error(err)
+
+	// validation never touches repo state
+	assert.Same(origMst, r.mst)
+	assert.Equal(origDirty, r.dirty)
+	_, _, err = r.GetRecord(ctx, "app.bsky.feed.post/3jzfcijpj2z2b")
+	assert.Error(err)
+}
+
+func TestApplyOps(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	r := testRepoWithRecords(t, "app.bsky.feed.post/3jzfcijpj2z2a")
+	t0, err := r.getMst(ctx)
+	assert.NoError(err)
+	origRoot, err := t0.GetPointer(ctx)
+	assert.NoError(err)
+
+	post := &bsky.FeedPost{Text: "hello", CreatedAt: "2024-01-02T03:04:05.006Z"}
+	failing := []CreateOp{
+		{Path: "app.bsky.feed.post/3jzfcijpj2z2b", Record: post},
+		{Path: "app.bsky.feed.post/3jzfcijpj2z2c", Record: post},
+		{Path: "app.bsky.feed.post/3jzfcijpj2z2d", Record: &brokenRecord{}},
+		{Path: "app.bsky.feed.post/3jzfcijpj2z2e", Record: post},
+	}
+	_, err = ApplyOps(ctx, r, failing)
+	assert.ErrorContains(err, "op 2")
+
+	// root unchanged, and none of the earlier ops are visible
+	t1, err := r.getMst(ctx)
+	assert.NoError(err)
+	root, err := t1.GetPointer(ctx)
+	assert.NoError(err)
+	assert.Equal(origRoot, root)
+	for _, op := range failing {
+		_, _, err := r.GetRecord(ctx, op.Path)
+		assert.Error(err)
+	}
+
+	root, err = ApplyOps(ctx, r, failing[:2])
+	assert.NoError(err)
+	assert.NotEqual(origRoot, root)
+	for _, op := range failing[:2] {
+		_, _, err := r.GetRecord(ctx, op.Path)
+		assert.NoError(err)
+	}
+}
+
+func TestApplyWrites(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	r := testRepoWithRecords(t, "app.bsky.feed.post/3jzfcijpj2z2a", "app.bsky.feed.like/3jzfcijpj2z2b")
+	post := &bsky.FeedPost{Text: "hello", CreatedAt: "2024-01-02T03:04:05.006Z"}
+	edited := &bsky.FeedPost{Text: "hello again", CreatedAt: "2024-01-02T03:04:05.006Z"}
+
+	root, results, err := r.ApplyWrites(ctx, []Write{
+		{Action: WriteCreate, Collection: "app.bsky.feed.post", Value: post},
+		{Action: WriteCreate, Collection: "app.bsky.actor.profile", Rkey: "self", Value: &bsky.ActorProfile{}},
+		{Action: WriteUpdate, Collection: "app.bsky.feed.post", Rkey: "3jzfcijpj2z2a", Value: edited},
+		{Action: WriteDelete, Collection: "app.bsky.feed.like", Rkey: "3jzfcijpj2z2b"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(results, 4)
+
+	// generated record key
+	_, err = syntax.ParseTID(results[0].Rkey)
+	assert.NoError(err)
+	assert.Equal("self", results[1].Rkey)
+	for i, path := range []string{"app.bsky.feed.post/" + results[0].Rkey, "app.bsky.actor.profile/self", "app.bsky.feed.post/3jzfcijpj2z2a"} {
+		c, _, err := r.GetRecordBytes(ctx, path)
+		assert.NoError(err)
+		assert.Equal(results[i].Cid, c)
+	}
+	assert.Equal(WriteDelete, results[3].Action)
+	assert.Equal(cid.Undef, results[3].Cid)
+	ok, _, err := r.HasRecord(ctx, "app.bsky.feed.like", "3jzfcijpj2z2b")
+	assert.NoError(err)
+	assert.False(ok)
+
+	_, rec, err := r.GetRecord(ctx, "app.bsky.feed.post/3jzfcijpj2z2a")
+	assert.NoError(err)
+	assert.Equal("hello again", rec.(*bsky.FeedPost).Text)
+	assert.Equal(root, mustDataRoot(t, r))
+
+	// the whole batch lands in a single commit
+	if _, _, err := r.Commit(ctx, testSigner); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(root, r.DataCid())
+}
+
+func TestApplyWritesInvalid(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	r := testRepoWithRecords(t, "app.bsky.feed.post/3jzfcijpj2z2a", "app.bsky.feed.like/3jzfcijpj2z2b")
+	origRoot := mustDataRoot(t, r)
+	post := &bsky.FeedPost{Text: "hello", CreatedAt: "2024-01-02T03:04:05.006Z"}
+	valid := []Write{
+		{Action: WriteCreate, Collection: "app.bsky.feed.post", Rkey: "3jzfcijpj2z2c", Value: post},
+		{Action: WriteDelete, Collection: "app.bsky.feed.like", Rkey: "3jzfcijpj2z2b"},
+	}
+
+	invalid := []struct {
+		write Write
+		err   string
+	}{
+		{Write{Action: WriteCreate, Collection: "not a collection", Value: post}, "bad collection"},
+		{Write{Action: WriteCreate, Collection: "app.bsky.feed.post", Rkey: "..", Value: post}, "bad record key"},
+		{Write{Action: WriteUpdate, Collection: "app.bsky.feed.post", Value: post}, "missing record key"},
+		{Write{Action: WriteCreate, Collection: "app.bsky.feed.post"}, "missing record"},
+		{Write{Action: WriteDelete, Collection: "app.bsky.feed.post", Rkey: "3jzfcijpj2z2a", Value: post}, "delete with record value"},
+		{Write{Action: "upsert", Collection: "app.bsky.feed.post", Rkey: "3jzfcijpj2z2a", Value: post}, "unknown write action"},
+		// these only fail when applied
+		{Write{Action: WriteCreate, Collection: "app.bsky.feed.post", Rkey: "3jzfcijpj2z2a", Value: post}, "write 2"},
+		{Write{Action: WriteUpdate, Collection: "app.bsky.feed.post", Rkey: "3jzfcijpj2z2z", Value: post}, "write 2"},
+		{Write{Action: WriteDelete, Collection: "app.bsky.feed.post", Rkey: "3jzfcijpj2z2z"}, "write 2"},
+		{Write{Action: WriteCreate, Collection: "app.bsky.feed.post", Rkey: "3jzfcijpj2z2d", Value: &brokenRecord{}}, "write 2"},
+	}
+	for _, tc := range invalid {
+		batch := append(append([]Write{}, valid...), tc.write)
+		_, results, err := r.ApplyWrites(ctx, batch)
+		assert.ErrorContains(err, tc.err)
+		assert.Nil(results)
+
+		// none of the valid writes were applied
+		assert.Equal(origRoot, mustDataRoot(t, r))
+		ok, _, err := r.HasRecord(ctx, "app.bsky.feed.like", "3jzfcijpj2z2b")
+		assert.NoError(err)
+		assert.True(ok)
+	}
+}
+
+// current (possibly uncommitted) MST root of the repo
+func mustDataRoot(t *testing.T, r *Repo) cid.Cid {
+	t.Helper()
+	tree, err := r.getMst(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := tree.GetPointer(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
@@ -3,14 +3,20 @@ package repo
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"slices"
+	"strings"
 
+	"github.com/bluesky-social/indigo/atproto/atcrypto"
+	"github.com/bluesky-social/indigo/atproto/atdata"
 	"github.com/bluesky-social/indigo/atproto/repo"
 	"github.com/bluesky-social/indigo/atproto/syntax"
 	lexutil "github.com/bluesky-social/indigo/lex/util"
 	"github.com/bluesky-social/indigo/mst"
 	"github.com/bluesky-social/indigo/util"
+	blocks "github.com/ipfs/go-block-format"
 	"github.com/ipfs/go-cid"
 	cbor "github.com/ipfs/go-ipld-cbor"
 	"github.com/ipld/go-car"
@@ -47,11 +53,18 @@ type Repo struct {
 
 	repoCid cid.Cid
 
+	// multihash function for new blocks, matching cst
+	hashFunc uint64
+
 	mst *mst.MerkleSearchTree
 
 	dirty bool
 
 	clk *syntax.TIDClock
+
+	// lazily-built reverse index from record CID to path, valid for the tree in cidIndexTree
+	cidIndex     map[cid.Cid]string
+	cidIndexTree *mst.MerkleSearchTree
 }
 
 // Returns a copy of commit without the Sig field. Helpful when verifying signature.
@@ -77,29 +90,129 @@ func (uc *UnsignedCommit) BytesForSigning() ([]byte, error) {
 }
 
 func IngestRepo(ctx context.Context, bs cbor.IpldBlockstore, r io.Reader) (cid.Cid, error) {
+	return IngestRepoWithLimits(ctx, bs, r, CARLimits{})
+}
+
+// IngestRepoWithLimits is like IngestRepo, but aborts with a [*CARLimitError] if the CAR file exceeds the given limits. Blocks read before the limit was hit are left in the blockstore.
+//
+// This should be used when importing untrusted repositories.
+func IngestRepoWithLimits(ctx context.Context, bs cbor.IpldBlockstore, r io.Reader, limits CARLimits) (cid.Cid, error) {
 	ctx, span := otel.Tracer("repo").Start(ctx, "Ingest")
 	defer span.End()
 
+	roots, err := WalkCARWithLimits(ctx, r, limits, func(c cid.Cid, data []byte) error {
+		blk, err := blocks.NewBlockWithCid(data, c)
+		if err != nil {
+			return err
+		}
+		if err := bs.Put(ctx, blk); err != nil {
+			return fmt.Errorf("copying block to store: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return cid.Undef, err
+	}
+
+	return roots[0], nil
+}
+
+// Limits on the size of a CAR file being read. Zero values mean no limit.
+type CARLimits struct {
+	// Maximum total bytes read from the input, including the header
+	MaxBytes int64
+	// Maximum number of blocks
+	MaxBlocks int
+}
+
+// Returned when a CAR file exceeds one of the configured [CARLimits]
+type CARLimitError struct {
+	// Which limit was exceeded: "bytes" or "blocks"
+	Limit string
+	Max   int64
+}
+
+func (e *CARLimitError) Error() string {
+	return fmt.Sprintf("CAR file exceeded limit of %d %s", e.Max, e.Limit)
+}
+
+// counts bytes as they are read, and fails once more than max bytes have been read
+type limitedCARReader struct {
+	r        io.Reader
+	max      int64
+	n        int64
+	exceeded bool
+}
+
+func (lr *limitedCARReader) Read(p []byte) (int, error) {
+	if lr.exceeded {
+		return 0, &CARLimitError{Limit: "bytes", Max: lr.max}
+	}
+	// read at most one byte past the limit, to detect overflow
+	if remaining := lr.max - lr.n + 1; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err := lr.r.Read(p)
+	lr.n += int64(n)
+	if lr.n > lr.max {
+		lr.exceeded = true
+		return n, &CARLimitError{Limit: "bytes", Max: lr.max}
+	}
+	return n, err
+}
+
+// WalkCAR streams blocks from a CAR file to the callback, in file order, without retaining them in memory. Returns the roots from the CAR header.
+//
+// If the callback returns an error, reading stops and that error is returned.
+func WalkCAR(ctx context.Context, r io.Reader, cb func(cid.Cid, []byte) error) ([]cid.Cid, error) {
+	return WalkCARWithLimits(ctx, r, CARLimits{}, cb)
+}
+
+// WalkCARWithLimits is like WalkCAR, but stops with a [*CARLimitError] as soon as the input exceeds the given limits. Bytes are counted as they are read, so oversized input is never fully buffered.
+func WalkCARWithLimits(ctx context.Context, r io.Reader, limits CARLimits, cb func(cid.Cid, []byte) error) ([]cid.Cid, error) {
+	var lr *limitedCARReader
+	if limits.MaxBytes > 0 {
+		lr = &limitedCARReader{r: r, max: limits.MaxBytes}
+		r = lr
+	}
+	// the CAR reader may wrap read errors, so check for a hit limit directly
+	limitErr := func(err error) error {
+		if lr != nil && lr.exceeded {
+			return &CARLimitError{Limit: "bytes", Max: lr.max}
+		}
+		return err
+	}
+
 	br, err := car.NewCarReader(r)
 	if err != nil {
-		return cid.Undef, fmt.Errorf("opening CAR block reader: %w", err)
+		return nil, limitErr(fmt.Errorf("opening CAR block reader: %w", err))
 	}
 
+	count := 0
 	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		blk, err := br.Next()
 		if err != nil {
 			if err == io.EOF {
 				break
 			}
-			return cid.Undef, fmt.Errorf("reading block from CAR: %w", err)
+			return nil, limitErr(fmt.Errorf("reading block from CAR: %w", err))
 		}
 
-		if err := bs.Put(ctx, blk); err != nil {
-			return cid.Undef, fmt.Errorf("copying block to store: %w", err)
+		count++
+		if limits.MaxBlocks > 0 && count > limits.MaxBlocks {
+			return nil, &CARLimitError{Limit: "blocks", Max: int64(limits.MaxBlocks)}
+		}
+
+		if err := cb(blk.Cid(), blk.RawData()); err != nil {
+			return nil, err
 		}
 	}
 
-	return br.Header.Roots[0], nil
+	return br.Header.Roots, nil
 }
 
 func ReadRepoFromCar(ctx context.Context, r io.Reader) (*Repo, error) {
@@ -113,7 +226,14 @@ func ReadRepoFromCar(ctx context.Context, r io.Reader) (*Repo, error) {
 }
 
 func NewRepo(ctx context.Context, did string, bs cbor.IpldBlockstore) *Repo {
-	cst := util.CborStore(bs)
+	return NewRepoWithHash(ctx, did, bs, util.DefaultHashFunc)
+}
+
+// NewRepoWithHash is like NewRepo, but computes record, MST node, and commit CIDs with the given multihash function instead of sha2-256. Repos opened with [OpenRepo] keep using the hash function of their commit CID.
+//
+// Only sha2-256 repos are valid in atproto; other hash functions are for experimentation.
+func NewRepoWithHash(ctx context.Context, did string, bs cbor.IpldBlockstore, hashFunc uint64) *Repo {
+	cst := util.CborStoreWithHash(bs, hashFunc)
 	clk := syntax.NewTIDClock(0)
 
 	t := mst.NewEmptyMST(cst)
@@ -123,17 +243,20 @@ func NewRepo(ctx context.Context, did string, bs cbor.IpldBlockstore) *Repo {
 	}
 
 	return &Repo{
-		cst:   cst,
-		bs:    bs,
-		mst:   t,
-		sc:    sc,
-		dirty: true,
-		clk:   &clk,
+		cst:      cst,
+		bs:       bs,
+		hashFunc: hashFunc,
+		mst:      t,
+		sc:       sc,
+		dirty:    true,
+		clk:      &clk,
 	}
 }
 
+// OpenRepo loads the repo with the given commit CID. Any new blocks are written using the same hash function as the commit CID, so that the repo stays consistent.
 func OpenRepo(ctx context.Context, bs cbor.IpldBlockstore, root cid.Cid) (*Repo, error) {
-	cst := util.CborStore(bs)
+	hashFunc := root.Prefix().MhType
+	cst := util.CborStoreWithHash(bs, hashFunc)
 	clk := syntax.NewTIDClock(0)
 
 	var sc SignedCommit
@@ -146,11 +269,12 @@ func OpenRepo(ctx context.Context, bs cbor.IpldBlockstore, root cid.Cid) (*Repo,
 	}
 
 	return &Repo{
-		sc:      sc,
-		bs:      bs,
-		cst:     cst,
-		repoCid: root,
-		clk:     &clk,
+		sc:       sc,
+		bs:       bs,
+		cst:      cst,
+		repoCid:  root,
+		hashFunc: hashFunc,
+		clk:      &clk,
 	}, nil
 }
 
@@ -175,10 +299,114 @@ func (r *Repo) DataCid() cid.Cid {
 	return r.sc.Data
 }
 
+// HashFunc returns the multihash function used for new record, MST node, and commit CIDs in this repo
+func (r *Repo) HashFunc() uint64 {
+	return r.hashFunc
+}
+
 func (r *Repo) SignedCommit() SignedCommit {
 	return r.sc
 }
 
+// Returned by [Repo.VerifyDataRoot] when the commit's data CID does not match the MST root recomputed from the tree's contents
+type DataRootMismatchError struct {
+	Commit   cid.Cid
+	Computed cid.Cid
+}
+
+func (e *DataRootMismatchError) Error() string {
+	return fmt.Sprintf("commit data CID %s does not match computed MST root %s", e.Commit, e.Computed)
+}
+
+// VerifyDataRoot checks that the current commit's data CID is the root of the canonical MST for the records it contains. The tree is walked from the commit's data CID and rebuilt from scratch, and the resulting root compared against the commit, returning a [DataRootMismatchError] on mismatch. This catches corrupted blocks and non-canonical tree structure; missing blocks result in a (non-mismatch) error.
+//
+// Uncommitted changes are not considered. The rebuilt tree is not written to the repo's blockstore.
+func (r *Repo) VerifyDataRoot(ctx context.Context) error {
+	ctx, span := otel.Tracer("repo").Start(ctx, "VerifyDataRoot")
+	defer span.End()
+
+	if !r.sc.Data.Defined() {
+		return fmt.Errorf("repo has no commit data CID")
+	}
+
+	fresh := mst.NewEmptyMST(util.CborStoreWithHash(repo.NewTinyBlockstore(), r.HashFunc()))
+	if err := mst.LoadMST(r.cst, r.sc.Data).WalkLeavesFrom(ctx, "", func(k string, v cid.Cid) error {
+		nt, err := fresh.Add(ctx, k, v, -1)
+		if err != nil {
+			return fmt.Errorf("rebuilding tree at %q: %w", k, err)
+		}
+		fresh = nt
+		return nil
+	}); err != nil {
+		return fmt.Errorf("walking repo tree: %w", err)
+	}
+
+	computed, err := fresh.GetPointer(ctx)
+	if err != nil {
+		return fmt.Errorf("computing MST root: %w", err)
+	}
+	if computed != r.sc.Data {
+		return &DataRootMismatchError{Commit: r.sc.Data, Computed: computed}
+	}
+	return nil
+}
+
+// FullVerify runs all available integrity checks on the current commit, returning an error joining (see [errors.Join]) the failures of each check, or nil if all pass:
+//
+//   - the commit signature is valid for key
+//   - the tree is well-formed: all nodes and records are present, and keys are valid repo paths, in strictly ascending order
+//   - the commit's data CID matches the tree's contents (see [Repo.VerifyDataRoot])
+//
+// Uncommitted changes are not considered.
+func (r *Repo) FullVerify(ctx context.Context, key atcrypto.PublicKey) error {
+	ctx, span := otel.Tracer("repo").Start(ctx, "FullVerify")
+	defer span.End()
+
+	var errs []error
+	if err := r.verifySignature(key); err != nil {
+		errs = append(errs, fmt.Errorf("commit signature: %w", err))
+	}
+	if err := r.verifyTreeStructure(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("tree structure: %w", err))
+	}
+	if err := r.VerifyDataRoot(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("data root: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Repo) verifySignature(key atcrypto.PublicKey) error {
+	if len(r.sc.Sig) == 0 {
+		return fmt.Errorf("commit is not signed")
+	}
+	b, err := r.sc.Unsigned().BytesForSigning()
+	if err != nil {
+		return err
+	}
+	return key.HashAndVerify(b, r.sc.Sig)
+}
+
+func (r *Repo) verifyTreeStructure(ctx context.Context) error {
+	if !r.sc.Data.Defined() {
+		return fmt.Errorf("repo has no commit data CID")
+	}
+
+	prev := ""
+	return mst.LoadMST(r.cst, r.sc.Data).WalkLeavesFrom(ctx, "", func(k string, v cid.Cid) error {
+		if _, _, err := syntax.ParseRepoPath(k); err != nil {
+			return fmt.Errorf("invalid key %q: %w", k, err)
+		}
+		if k <= prev {
+			return fmt.Errorf("key %q out of order after %q", k, prev)
+		}
+		prev = k
+		if _, err := r.bs.Get(ctx, v); err != nil {
+			return fmt.Errorf("loading record %s: %w", k, err)
+		}
+		return nil
+	})
+}
+
 func (r *Repo) Blockstore() cbor.IpldBlockstore {
 	return r.bs
 }
@@ -328,6 +556,7 @@ func (r *Repo) Commit(ctx context.Context, signer func(context.Context, string,
 	}
 
 	r.sc = nsc
+	r.repoCid = nsccid
 	r.dirty = false
 
 	return nsccid, nsc.Rev, nil
@@ -360,6 +589,197 @@ func (r *Repo) ForEach(ctx context.Context, prefix string, cb func(k string, v c
 	return nil
 }
 
+// A single record reference within a repository, as returned by listing methods
+type RecordEntry struct {
+	Collection string
+	Rkey       string
+	Cid        cid.Cid
+}
+
+// Path returns the full repository path ("collection/rkey") of the record
+func (re *RecordEntry) Path() string {
+	return re.Collection + "/" + re.Rkey
+}
+
+// ListRecords returns up to limit records from a collection, in reverse record key order (newest first, for TID record keys), matching the semantics of the `com.atproto.repo.listRecords` endpoint.
+//
+// If cursor is non-empty, only records with record keys strictly lower than the cursor are returned. The returned cursor is the record key of the last record returned, or empty if there are no more records.
+func (r *Repo) ListRecords(ctx context.Context, collection string, limit int, cursor string) ([]RecordEntry, string, error) {
+	ctx, span := otel.Tracer("repo").Start(ctx, "ListRecords")
+	defer span.End()
+
+	if limit <= 0 {
+		return nil, "", fmt.Errorf("list limit must be positive")
+	}
+
+	t, err := r.getMst(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("getting repo mst: %w", err)
+	}
+
+	// walk the collection in descending key order, starting below the cursor. '0' sorts immediately after '/', so every key in the collection is below collection+"0".
+	prefix := collection + "/"
+	before := collection + "0"
+	if cursor != "" {
+		before = prefix + cursor
+	}
+	out := make([]RecordEntry, 0, limit)
+	more := false
+	if err := t.WalkLeavesBefore(ctx, before, func(k string, v cid.Cid) error {
+		if !strings.HasPrefix(k, prefix) {
+			return ErrDoneIterating
+		}
+		if len(out) == limit {
+			more = true
+			return ErrDoneIterating
+		}
+		out = append(out, RecordEntry{Collection: collection, Rkey: k[len(prefix):], Cid: v})
+		return nil
+	}); err != nil && !errors.Is(err, ErrDoneIterating) {
+		return nil, "", err
+	}
+
+	next := ""
+	if more {
+		next = out[len(out)-1].Rkey
+	}
+	return out, next, nil
+}
+
+// CollectionStats returns the number of records in each collection of the repo, keyed by collection NSID.
+//
+// The tree is walked once, in key order, and only the per-collection counts are held in memory.
+func (r *Repo) CollectionStats(ctx context.Context) (map[string]int, error) {
+	ctx, span := otel.Tracer("repo").Start(ctx, "CollectionStats")
+	defer span.End()
+
+	t, err := r.getMst(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting repo mst: %w", err)
+	}
+
+	counts := make(map[string]int)
+	if err := t.WalkLeavesFrom(ctx, "", func(k string, v cid.Cid) error {
+		collection, _, ok := strings.Cut(k, "/")
+		if !ok {
+			return fmt.Errorf("invalid record path in repo: %q", k)
+		}
+		counts[collection]++
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// OpsSince returns the records in the repo created after the given rev, in path order. Record creation time is taken from TID record keys, which sort in time order, so records with non-TID keys (eg, "self") are not included. Updates and deletions of older records are not visible this way; use [Repo.DiffSince] with a known commit to get a complete set of changes.
+func (r *Repo) OpsSince(ctx context.Context, sinceRev string) ([]RecordEntry, error) {
+	ctx, span := otel.Tracer("repo").Start(ctx, "OpsSince")
+	defer span.End()
+
+	since, err := syntax.ParseTID(sinceRev)
+	if err != nil {
+		return nil, fmt.Errorf("invalid since rev: %w", err)
+	}
+
+	t, err := r.getMst(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting repo mst: %w", err)
+	}
+
+	var out []RecordEntry
+	if err := t.WalkLeavesFrom(ctx, "", func(k string, v cid.Cid) error {
+		collection, rkey, ok := strings.Cut(k, "/")
+		if !ok {
+			return fmt.Errorf("invalid record path in repo: %q", k)
+		}
+		tid, err := syntax.ParseTID(rkey)
+		if err != nil {
+			return nil
+		}
+		if tid.String() > since.String() {
+			out = append(out, RecordEntry{Collection: collection, Rkey: rkey, Cid: v})
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PathForCID returns the path ("collection/rkey") of a record in the repo with the given CID, and whether one was found. If multiple records have identical contents (and thus CID), the lowest path is returned.
+//
+// The first call walks the whole tree to build an in-memory index, which is re-used by later calls until the repo is modified.
+func (r *Repo) PathForCID(ctx context.Context, c cid.Cid) (string, bool, error) {
+	ctx, span := otel.Tracer("repo").Start(ctx, "PathForCID")
+	defer span.End()
+
+	t, err := r.getMst(ctx)
+	if err != nil {
+		return "", false, fmt.Errorf("getting repo mst: %w", err)
+	}
+
+	// trees are immutable, and replaced on every change, so the index is valid as long as the tree is the same
+	if r.cidIndex == nil || r.cidIndexTree != t {
+		index := make(map[cid.Cid]string)
+		if err := t.WalkLeavesFrom(ctx, "", func(k string, v cid.Cid) error {
+			// keys are walked in order, so keep the first path seen
+			if _, ok := index[v]; !ok {
+				index[v] = k
+			}
+			return nil
+		}); err != nil {
+			return "", false, err
+		}
+		r.cidIndex = index
+		r.cidIndexTree = t
+	}
+
+	path, ok := r.cidIndex[c]
+	return path, ok, nil
+}
+
+// ReferencedBlobs returns the de-duplicated set of blob CIDs referenced by any record in the repo (including uncommitted changes), sorted by CID string. Blobs which were uploaded but do not appear in this set are candidates for garbage collection.
+//
+// Every record block is loaded and parsed, so this is proportional to total repo size.
+func (r *Repo) ReferencedBlobs(ctx context.Context) ([]cid.Cid, error) {
+	ctx, span := otel.Tracer("repo").Start(ctx, "ReferencedBlobs")
+	defer span.End()
+
+	t, err := r.getMst(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting repo mst: %w", err)
+	}
+
+	seen := make(map[cid.Cid]bool)
+	var out []cid.Cid
+	if err := t.WalkLeavesFrom(ctx, "", func(k string, v cid.Cid) error {
+		blk, err := r.bs.Get(ctx, v)
+		if err != nil {
+			return fmt.Errorf("loading record %s: %w", k, err)
+		}
+		obj, err := atdata.UnmarshalCBOR(blk.RawData())
+		if err != nil {
+			return fmt.Errorf("parsing record %s: %w", k, err)
+		}
+		for _, b := range atdata.ExtractBlobs(obj) {
+			c := cid.Cid(b.Ref)
+			if !seen[c] {
+				seen[c] = true
+				out = append(out, c)
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(out, func(a, b cid.Cid) int {
+		return strings.Compare(a.String(), b.String())
+	})
+	return out, nil
+}
+
 func (r *Repo) GetRecord(ctx context.Context, rpath string) (cid.Cid, cbg.CBORMarshaler, error) {
 	ctx, span := otel.Tracer("repo").Start(ctx, "GetRecord")
 	defer span.End()
@@ -405,6 +825,72 @@ func (r *Repo) GetRecordBytes(ctx context.Context, rpath string) (cid.Cid, *[]by
 	return cc, &raw, nil
 }
 
+// HasRecord reports whether a record exists at the given collection and record key, and if so returns its CID. Only the MST is walked; the record block itself is not loaded (or even required to be present).
+func (r *Repo) HasRecord(ctx context.Context, collection, rkey string) (bool, cid.Cid, error) {
+	ctx, span := otel.Tracer("repo").Start(ctx, "HasRecord")
+	defer span.End()
+
+	t, err := r.getMst(ctx)
+	if err != nil {
+		return false, cid.Undef, fmt.Errorf("getting repo mst: %w", err)
+	}
+
+	cc, err := t.Get(ctx, collection+"/"+rkey)
+	if errors.Is(err, mst.ErrNotFound) {
+		return false, cid.Undef, nil
+	}
+	if err != nil {
+		return false, cid.Undef, fmt.Errorf("resolving rpath within mst: %w", err)
+	}
+	return true, cc, nil
+}
+
+// GetRecords fetches several records at once, decoded as generic data (see [atdata.UnmarshalCBOR]). Records are keyed by path in the first map; paths which could not be fetched (including paths not present in the repo, which wrap [mst.ErrNotFound]) are keyed in the second map instead.
+//
+// Lookups go through the repo's MST, which retains nodes once loaded, so nodes shared by several paths are only read from the blockstore once. Record blocks shared by several paths are also only loaded once.
+func (r *Repo) GetRecords(ctx context.Context, paths []string) (map[string]map[string]any, map[string]error) {
+	ctx, span := otel.Tracer("repo").Start(ctx, "GetRecords")
+	defer span.End()
+
+	records := make(map[string]map[string]any, len(paths))
+	errs := make(map[string]error)
+
+	t, err := r.getMst(ctx)
+	if err != nil {
+		for _, p := range paths {
+			errs[p] = fmt.Errorf("getting repo mst: %w", err)
+		}
+		return records, errs
+	}
+
+	recordBytes := make(map[cid.Cid][]byte)
+	for _, p := range paths {
+		cc, err := t.Get(ctx, p)
+		if err != nil {
+			errs[p] = fmt.Errorf("resolving rpath within mst: %w", err)
+			continue
+		}
+		raw, ok := recordBytes[cc]
+		if !ok {
+			blk, err := r.bs.Get(ctx, cc)
+			if err != nil {
+				errs[p] = fmt.Errorf("loading record: %w", err)
+				continue
+			}
+			raw = blk.RawData()
+			recordBytes[cc] = raw
+		}
+		// decoded separately for each path, so callers can modify records independently
+		rec, err := atdata.UnmarshalCBOR(raw)
+		if err != nil {
+			errs[p] = fmt.Errorf("parsing record: %w", err)
+			continue
+		}
+		records[p] = rec
+	}
+	return records, errs
+}
+
 func (r *Repo) DiffSince(ctx context.Context, oldrepo cid.Cid) ([]*mst.DiffOp, error) {
 	ctx, span := otel.Tracer("repo").Start(ctx, "DiffSince")
 	defer span.End()
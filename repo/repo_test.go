@@ -1,12 +1,28 @@
 package repo
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"testing"
 
+	"github.com/bluesky-social/indigo/api/bsky"
+	"github.com/bluesky-social/indigo/atproto/atcrypto"
+	atrepo "github.com/bluesky-social/indigo/atproto/repo"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	lexutil "github.com/bluesky-social/indigo/lex/util"
+	"github.com/bluesky-social/indigo/mst"
+
+	blocks "github.com/ipfs/go-block-format"
 	cid "github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	ipld "github.com/ipfs/go-ipld-format"
+	"github.com/ipld/go-car"
+	carutil "github.com/ipld/go-car/util"
+	mh "github.com/multiformats/go-multihash"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestRepo(t *testing.T) {
@@ -30,3 +46,686 @@ func TestRepo(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func writeTestCar(t *testing.T, blks []blocks.Block) []byte {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	if err := car.WriteHeader(&car.CarHeader{
+		Roots:   []cid.Cid{blks[0].Cid()},
+		Version: 1,
+	}, buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, blk := range blks {
+		if err := carutil.LdWrite(buf, blk.Cid().Bytes(), blk.RawData()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+func TestWalkCAR(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	var blks []blocks.Block
+	for i := 0; i < 10; i++ {
+		blks = append(blks, blocks.NewBlock([]byte(fmt.Sprintf("block %d", i))))
+	}
+	carBytes := writeTestCar(t, blks)
+
+	var seen []cid.Cid
+	roots, err := WalkCAR(ctx, bytes.NewReader(carBytes), func(c cid.Cid, data []byte) error {
+		seen = append(seen, c)
+		return nil
+	})
+	assert.NoError(err)
+	assert.Equal([]cid.Cid{blks[0].Cid()}, roots)
+	assert.Equal(len(blks), len(seen))
+	for i, blk := range blks {
+		assert.Equal(blk.Cid(), seen[i])
+	}
+
+	// callback errors stop the walk early
+	count := 0
+	stop := fmt.Errorf("stop")
+	_, err = WalkCAR(ctx, bytes.NewReader(carBytes), func(c cid.Cid, data []byte) error {
+		count++
+		if count == 3 {
+			return stop
+		}
+		return nil
+	})
+	assert.ErrorIs(err, stop)
+	assert.Equal(3, count)
+}
+
+func TestWalkCARLimits(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	var blks []blocks.Block
+	for i := 0; i < 10; i++ {
+		blks = append(blks, blocks.NewBlock([]byte(fmt.Sprintf("block %d", i))))
+	}
+	carBytes := writeTestCar(t, blks)
+	noop := func(c cid.Cid, data []byte) error { return nil }
+
+	// limits which exactly fit the file
+	_, err := WalkCARWithLimits(ctx, bytes.NewReader(carBytes), CARLimits{MaxBytes: int64(len(carBytes)), MaxBlocks: len(blks)}, noop)
+	assert.NoError(err)
+
+	var limitErr *CARLimitError
+	_, err = WalkCARWithLimits(ctx, bytes.NewReader(carBytes), CARLimits{MaxBytes: int64(len(carBytes)) - 1}, noop)
+	assert.ErrorAs(err, &limitErr)
+	assert.Equal("bytes", limitErr.Limit)
+	assert.Equal(int64(len(carBytes))-1, limitErr.Max)
+
+	// limit hit while still reading the header
+	_, err = WalkCARWithLimits(ctx, bytes.NewReader(carBytes), CARLimits{MaxBytes: 10}, noop)
+	assert.ErrorAs(err, &limitErr)
+	assert.Equal("bytes", limitErr.Limit)
+
+	count := 0
+	_, err = WalkCARWithLimits(ctx, bytes.NewReader(carBytes), CARLimits{MaxBlocks: 5}, func(c cid.Cid, data []byte) error {
+		count++
+		return nil
+	})
+	assert.ErrorAs(err, &limitErr)
+	assert.Equal("blocks", limitErr.Limit)
+	assert.Equal(int64(5), limitErr.Max)
+	assert.Equal(5, count)
+
+	bs := atrepo.NewTinyBlockstore()
+	_, err = IngestRepoWithLimits(ctx, bs, bytes.NewReader(carBytes), CARLimits{MaxBlocks: 3})
+	assert.ErrorAs(err, &limitErr)
+	root, err := IngestRepoWithLimits(ctx, bs, bytes.NewReader(carBytes), CARLimits{MaxBlocks: 10})
+	assert.NoError(err)
+	assert.Equal(blks[0].Cid(), root)
+}
+
+// creates an in-memory repo containing a post record at each of the given paths
+func testRepoWithRecords(t *testing.T, paths ...string) *Repo {
+	t.Helper()
+	ctx := context.Background()
+	bs := atrepo.NewTinyBlockstore()
+	r := NewRepo(ctx, "did:plc:abc123", bs)
+	for _, p := range paths {
+		post := bsky.FeedPost{Text: "post " + p, CreatedAt: "2024-01-02T03:04:05.006Z"}
+		if _, err := r.PutRecord(ctx, p, &post); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return r
+}
+
+// blockstore wrapper which counts how many times each block is fetched
+type countingBlockstore struct {
+	cbor.IpldBlockstore
+	gets map[cid.Cid]int
+}
+
+func (cb *countingBlockstore) Get(ctx context.Context, c cid.Cid) (blocks.Block, error) {
+	cb.gets[c]++
+	return cb.IpldBlockstore.Get(ctx, c)
+}
+
+func TestHasRecord(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	r := testRepoWithRecords(t, "app.bsky.feed.post/3jzfcijpj2z2a", "app.bsky.feed.like/3jzfcijpj2z2b")
+	if _, _, err := r.Commit(ctx, testSigner); err != nil {
+		t.Fatal(err)
+	}
+	expected, _, err := r.GetRecordBytes(ctx, "app.bsky.feed.post/3jzfcijpj2z2a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// re-open, so the tree is loaded through the recording blockstore
+	counter := &countingBlockstore{IpldBlockstore: r.bs, gets: map[cid.Cid]int{}}
+	r, err = OpenRepo(ctx, counter, r.repoCid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok, cc, err := r.HasRecord(ctx, "app.bsky.feed.post", "3jzfcijpj2z2a")
+	assert.NoError(err)
+	assert.True(ok)
+	assert.Equal(expected, cc)
+	assert.Zero(counter.gets[expected], "record block should not be loaded")
+
+	ok, cc, err = r.HasRecord(ctx, "app.bsky.feed.post", "3jzfcijpj2z2b")
+	assert.NoError(err)
+	assert.False(ok)
+	assert.Equal(cid.Undef, cc)
+
+	ok, _, err = r.HasRecord(ctx, "app.bsky.feed.repost", "3jzfcijpj2z2a")
+	assert.NoError(err)
+	assert.False(ok)
+}
+
+func TestOpsSince(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	clk := syntax.NewTIDClock(0)
+	var rkeys []string
+	for i := 0; i < 6; i++ {
+		rkeys = append(rkeys, clk.Next().String())
+	}
+	r := testRepoWithRecords(t,
+		"app.bsky.feed.post/"+rkeys[0],
+		"app.bsky.feed.like/"+rkeys[1],
+		"app.bsky.feed.post/"+rkeys[2],
+		"app.bsky.feed.post/"+rkeys[3],
+		"app.bsky.feed.repost/"+rkeys[4],
+		"app.bsky.feed.like/"+rkeys[5],
+		// non-TID record keys are never included
+		"app.bsky.actor.profile/self",
+	)
+
+	paths := func(entries []RecordEntry) []string {
+		out := make([]string, len(entries))
+		for i, e := range entries {
+			assert.True(e.Cid.Defined())
+			out[i] = e.Path()
+		}
+		return out
+	}
+
+	out, err := r.OpsSince(ctx, rkeys[2])
+	assert.NoError(err)
+	assert.Equal([]string{
+		"app.bsky.feed.like/" + rkeys[5],
+		"app.bsky.feed.post/" + rkeys[3],
+		"app.bsky.feed.repost/" + rkeys[4],
+	}, paths(out))
+
+	// the since rev itself is excluded
+	out, err = r.OpsSince(ctx, rkeys[5])
+	assert.NoError(err)
+	assert.Empty(out)
+
+	// a rev from before all records returns every TID-keyed record
+	out, err = r.OpsSince(ctx, syntax.NewTID(0, 0).String())
+	assert.NoError(err)
+	assert.Len(out, 6)
+
+	_, err = r.OpsSince(ctx, "not-a-rev")
+	assert.Error(err)
+}
+
+func TestListRecords(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	var paths []string
+	clk := syntax.NewTIDClock(0)
+	var rkeys []string
+	for i := 0; i < 7; i++ {
+		rkey := clk.Next().String()
+		rkeys = append(rkeys, rkey)
+		paths = append(paths, "app.bsky.feed.post/"+rkey)
+	}
+	paths = append(paths, "app.bsky.feed.like/"+clk.Next().String(), "app.bsky.feed.repost/"+clk.Next().String())
+	r := testRepoWithRecords(t, paths...)
+
+	rkeysOf := func(entries []RecordEntry) []string {
+		out := make([]string, len(entries))
+		for i, e := range entries {
+			assert.Equal("app.bsky.feed.post", e.Collection)
+			out[i] = e.Rkey
+		}
+		return out
+	}
+
+	page, cursor, err := r.ListRecords(ctx, "app.bsky.feed.post", 3, "")
+	assert.NoError(err)
+	assert.Equal([]string{rkeys[6], rkeys[5], rkeys[4]}, rkeysOf(page))
+	assert.Equal(rkeys[4], cursor)
+
+	page, cursor, err = r.ListRecords(ctx, "app.bsky.feed.post", 3, cursor)
+	assert.NoError(err)
+	assert.Equal([]string{rkeys[3], rkeys[2], rkeys[1]}, rkeysOf(page))
+	assert.Equal(rkeys[1], cursor)
+
+	page, cursor, err = r.ListRecords(ctx, "app.bsky.feed.post", 3, cursor)
+	assert.NoError(err)
+	assert.Equal([]string{rkeys[0]}, rkeysOf(page))
+	assert.Equal("", cursor)
+
+	// exactly filling a page doesn't return a cursor
+	page, cursor, err = r.ListRecords(ctx, "app.bsky.feed.post", 7, "")
+	assert.NoError(err)
+	assert.Equal(7, len(page))
+	assert.Equal("", cursor)
+	assert.Equal("app.bsky.feed.post/"+rkeys[6], page[0].Path())
+
+	page, cursor, err = r.ListRecords(ctx, "app.bsky.feed.threadgate", 3, "")
+	assert.NoError(err)
+	assert.Empty(page)
+	assert.Equal("", cursor)
+
+	_, _, err = r.ListRecords(ctx, "app.bsky.feed.post", 0, "")
+	assert.Error(err)
+}
+
+func TestCollectionStats(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	clk := syntax.NewTIDClock(0)
+	var paths []string
+	for i := 0; i < 5; i++ {
+		paths = append(paths, "app.bsky.feed.post/"+clk.Next().String())
+	}
+	for i := 0; i < 3; i++ {
+		paths = append(paths, "app.bsky.feed.like/"+clk.Next().String())
+	}
+	paths = append(paths, "app.bsky.actor.profile/self")
+	r := testRepoWithRecords(t, paths...)
+
+	stats, err := r.CollectionStats(ctx)
+	assert.NoError(err)
+	assert.Equal(map[string]int{
+		"app.bsky.feed.post":     5,
+		"app.bsky.feed.like":     3,
+		"app.bsky.actor.profile": 1,
+	}, stats)
+
+	empty := testRepoWithRecords(t)
+	stats, err = empty.CollectionStats(ctx)
+	assert.NoError(err)
+	assert.Empty(stats)
+}
+
+func TestReferencedBlobs(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	blobCid := func(s string) cid.Cid {
+		c, err := cid.NewPrefixV1(cid.Raw, mh.SHA2_256).Sum([]byte(s))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	avatar := blobCid("avatar")
+	banner := blobCid("banner")
+	photo := blobCid("photo")
+
+	r := testRepoWithRecords(t, "app.bsky.feed.post/3jzfcijpj2z2a")
+	profile := bsky.ActorProfile{
+		Avatar: &lexutil.LexBlob{Ref: lexutil.LexLink(avatar), MimeType: "image/jpeg", Size: 1234},
+		Banner: &lexutil.LexBlob{Ref: lexutil.LexLink(banner), MimeType: "image/jpeg", Size: 5678},
+	}
+	if _, err := r.PutRecord(ctx, "app.bsky.actor.profile/self", &profile); err != nil {
+		t.Fatal(err)
+	}
+	// two posts with the same image, and one which also re-uses the avatar
+	for _, p := range []struct {
+		rkey   string
+		images []cid.Cid
+	}{
+		{"3jzfcijpj2z2b", []cid.Cid{photo}},
+		{"3jzfcijpj2z2c", []cid.Cid{photo, avatar}},
+	} {
+		embed := bsky.EmbedImages{}
+		for _, c := range p.images {
+			embed.Images = append(embed.Images, &bsky.EmbedImages_Image{
+				Image: &lexutil.LexBlob{Ref: lexutil.LexLink(c), MimeType: "image/png", Size: 100},
+			})
+		}
+		post := bsky.FeedPost{
+			Text:      "with images",
+			CreatedAt: "2024-01-02T03:04:05.006Z",
+			Embed:     &bsky.FeedPost_Embed{EmbedImages: &embed},
+		}
+		if _, err := r.PutRecord(ctx, "app.bsky.feed.post/"+p.rkey, &post); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	refs, err := r.ReferencedBlobs(ctx)
+	assert.NoError(err)
+	assert.ElementsMatch([]cid.Cid{avatar, banner, photo}, refs)
+
+	empty := testRepoWithRecords(t, "app.bsky.feed.post/3jzfcijpj2z2a")
+	refs, err = empty.ReferencedBlobs(ctx)
+	assert.NoError(err)
+	assert.Empty(refs)
+}
+
+func TestVerifyDataRoot(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	r := testRepoWithRecords(t, "app.bsky.feed.post/3jzfcijpj2z2a", "app.bsky.feed.post/3jzfcijpj2z2b")
+	if _, _, err := r.Commit(ctx, testSigner); err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(r.VerifyDataRoot(ctx))
+
+	empty := testRepoWithRecords(t)
+	if _, _, err := empty.Commit(ctx, testSigner); err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(empty.VerifyDataRoot(ctx))
+
+	// overwrite the root node block with the root of a different tree
+	bs := r.Blockstore().(*atrepo.TinyBlockstore)
+	other := NewRepo(ctx, "did:plc:abc123", bs)
+	post := bsky.FeedPost{Text: "other", CreatedAt: "2024-01-02T03:04:05.006Z"}
+	if _, err := other.PutRecord(ctx, "app.bsky.feed.post/3jzfcijpj2z2c", &post); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := other.Commit(ctx, testSigner); err != nil {
+		t.Fatal(err)
+	}
+	otherRoot, err := bs.Get(ctx, other.DataCid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	corrupt, err := blocks.NewBlockWithCid(otherRoot.RawData(), r.DataCid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bs.Put(ctx, corrupt); err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.VerifyDataRoot(ctx)
+	var mismatch *DataRootMismatchError
+	if assert.ErrorAs(err, &mismatch) {
+		assert.Equal(r.DataCid(), mismatch.Commit)
+		assert.Equal(other.DataCid(), mismatch.Computed)
+	}
+
+	// a data CID which isn't in the blockstore at all
+	missing, err := otherRoot.Cid().Prefix().Sum([]byte("missing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.sc.Data = missing
+	err = r.VerifyDataRoot(ctx)
+	assert.Error(err)
+	assert.False(errors.As(err, &mismatch))
+}
+
+func TestPathForCID(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	paths := []string{
+		"app.bsky.feed.post/3jzfcijpj2z2a",
+		"app.bsky.feed.post/3jzfcijpj2z2b",
+		"app.bsky.feed.like/3jzfcijpj2z2c",
+	}
+	r := testRepoWithRecords(t, paths...)
+
+	for _, p := range paths {
+		c, _, err := r.GetRecordBytes(ctx, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		found, ok, err := r.PathForCID(ctx, c)
+		assert.NoError(err)
+		assert.True(ok)
+		assert.Equal(p, found)
+	}
+
+	missing, err := cid.NewPrefixV1(cid.DagCBOR, mh.SHA2_256).Sum([]byte("missing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, ok, err := r.PathForCID(ctx, missing)
+	assert.NoError(err)
+	assert.False(ok)
+
+	// the index follows changes to the repo
+	post := bsky.FeedPost{Text: "new", CreatedAt: "2024-01-02T03:04:05.006Z"}
+	c, err := r.PutRecord(ctx, "app.bsky.feed.post/3jzfcijpj2z2d", &post)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found, ok, err := r.PathForCID(ctx, c)
+	assert.NoError(err)
+	assert.True(ok)
+	assert.Equal("app.bsky.feed.post/3jzfcijpj2z2d", found)
+
+	// identical records resolve to the lowest path
+	if _, err := r.UpdateRecord(ctx, "app.bsky.feed.post/3jzfcijpj2z2a", &post); err != nil {
+		t.Fatal(err)
+	}
+	found, ok, err = r.PathForCID(ctx, c)
+	assert.NoError(err)
+	assert.True(ok)
+	assert.Equal("app.bsky.feed.post/3jzfcijpj2z2a", found)
+
+	if err := r.DeleteRecord(ctx, "app.bsky.feed.post/3jzfcijpj2z2a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.DeleteRecord(ctx, "app.bsky.feed.post/3jzfcijpj2z2d"); err != nil {
+		t.Fatal(err)
+	}
+	_, ok, err = r.PathForCID(ctx, c)
+	assert.NoError(err)
+	assert.False(ok)
+}
+
+func TestRepoHashFunc(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	paths := []string{"app.bsky.feed.post/3jzfcijpj2z2a", "app.bsky.feed.post/3jzfcijpj2z2b", "app.bsky.feed.like/3jzfcijpj2z2c"}
+	build := func(hashFunc uint64) *Repo {
+		r := NewRepoWithHash(ctx, "did:plc:abc123", atrepo.NewTinyBlockstore(), hashFunc)
+		for _, p := range paths {
+			post := bsky.FeedPost{Text: "post " + p, CreatedAt: "2024-01-02T03:04:05.006Z"}
+			if _, err := r.PutRecord(ctx, p, &post); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, _, err := r.Commit(ctx, testSigner); err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	// the default is sha2-256, and CIDs match those from before the hash function was configurable
+	def := testRepoWithRecords(t, paths...)
+	if _, _, err := def.Commit(ctx, testSigner); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(uint64(mh.SHA2_256), def.HashFunc())
+	assert.Equal("bafyreifi4ul7atufhdnvkurckhagtxabslsph2shwcwiwrruddplgirzc4", def.DataCid().String())
+	assert.Equal(def.DataCid(), build(mh.SHA2_256).DataCid())
+
+	// an alternative hash function is used for records, nodes, and the commit
+	b3 := build(mh.BLAKE3)
+	assert.NotEqual(def.DataCid(), b3.DataCid())
+	assert.Equal(uint64(mh.BLAKE3), b3.DataCid().Prefix().MhType)
+	assert.Equal(uint64(mh.BLAKE3), b3.repoCid.Prefix().MhType)
+	rc, _, err := b3.GetRecordBytes(ctx, paths[0])
+	assert.NoError(err)
+	assert.Equal(uint64(mh.BLAKE3), rc.Prefix().MhType)
+	assert.NoError(b3.VerifyDataRoot(ctx))
+
+	// re-opening the repo keeps the hash function for new blocks
+	reopened, err := OpenRepo(ctx, b3.Blockstore(), b3.repoCid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(uint64(mh.BLAKE3), reopened.HashFunc())
+	post := bsky.FeedPost{Text: "another", CreatedAt: "2024-01-02T03:04:05.006Z"}
+	rc, err = reopened.PutRecord(ctx, "app.bsky.feed.post/3jzfcijpj2z2d", &post)
+	assert.NoError(err)
+	assert.Equal(uint64(mh.BLAKE3), rc.Prefix().MhType)
+	if _, _, err := reopened.Commit(ctx, testSigner); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(uint64(mh.BLAKE3), reopened.DataCid().Prefix().MhType)
+	assert.NoError(reopened.VerifyDataRoot(ctx))
+}
+
+func TestGetRecords(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	var paths []string
+	for i := 0; i < 50; i++ {
+		paths = append(paths, fmt.Sprintf("app.bsky.feed.post/3jzfcijpj2z%02d", i))
+	}
+	r := testRepoWithRecords(t, paths...)
+	root, _, err := r.Commit(ctx, testSigner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// re-open, so that tree nodes are loaded lazily
+	bs := &countingBlockstore{IpldBlockstore: r.Blockstore(), gets: map[cid.Cid]int{}}
+	r, err = OpenRepo(ctx, bs, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{paths[3], paths[17], paths[42], paths[3]}
+	missing := []string{"app.bsky.feed.post/3jzfcijpj2zzz", "app.bsky.feed.like/3jzfcijpj2z03"}
+	records, errs := r.GetRecords(ctx, append(want, missing...))
+
+	assert.Len(records, 3)
+	for _, p := range want {
+		if assert.Contains(records, p) {
+			assert.Equal("post "+p, records[p]["text"])
+			assert.Equal("app.bsky.feed.post", records[p]["$type"])
+		}
+	}
+	assert.Len(errs, 2)
+	for _, p := range missing {
+		assert.ErrorIs(errs[p], mst.ErrNotFound)
+	}
+
+	// no block was read more than once
+	for c, n := range bs.gets {
+		assert.Equal(1, n, c.String())
+	}
+}
+
+// hides a block, as if it were missing from the store
+type hidingBlockstore struct {
+	*atrepo.TinyBlockstore
+	hidden cid.Cid
+}
+
+func (hb *hidingBlockstore) Get(ctx context.Context, c cid.Cid) (blocks.Block, error) {
+	if c == hb.hidden {
+		return nil, ipld.ErrNotFound{Cid: c}
+	}
+	return hb.TinyBlockstore.Get(ctx, c)
+}
+
+func TestFullVerify(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	priv, err := atcrypto.GeneratePrivateKeyK256()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := priv.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := func(ctx context.Context, did string, b []byte) ([]byte, error) {
+		return priv.HashAndSign(b)
+	}
+
+	build := func(paths ...string) (*Repo, cid.Cid) {
+		r := testRepoWithRecords(t, paths...)
+		root, _, err := r.Commit(ctx, signer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r, root
+	}
+
+	// clean repo
+	r, root := build("app.bsky.feed.post/3jzfcijpj2z2a", "app.bsky.feed.post/3jzfcijpj2z2b")
+	assert.NoError(r.FullVerify(ctx, pub))
+
+	// wrong key, or unsigned
+	otherPriv, err := atcrypto.GeneratePrivateKeyK256()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPub, err := otherPriv.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.FullVerify(ctx, otherPub)
+	assert.ErrorContains(err, "commit signature")
+	assert.NotContains(err.Error(), "tree structure")
+	assert.NotContains(err.Error(), "data root")
+
+	unsigned, _ := build("app.bsky.feed.post/3jzfcijpj2z2a")
+	unsigned.sc.Sig = nil
+	assert.ErrorContains(unsigned.FullVerify(ctx, pub), "commit is not signed")
+
+	// a key which is a valid MST key, but not a valid repo path
+	badPath, _ := build("app.bsky.feed.post/3jzfcijpj2z2a", "notansid/3jzfcijpj2z2b")
+	err = badPath.FullVerify(ctx, pub)
+	assert.ErrorContains(err, "tree structure")
+	assert.ErrorContains(err, "notansid")
+	assert.NotContains(err.Error(), "commit signature")
+
+	// a missing record block
+	recCid, _, err := r.GetRecordBytes(ctx, "app.bsky.feed.post/3jzfcijpj2z2b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hiding := &hidingBlockstore{TinyBlockstore: r.Blockstore().(*atrepo.TinyBlockstore), hidden: recCid}
+	missingRec, err := OpenRepo(ctx, hiding, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = missingRec.FullVerify(ctx, pub)
+	assert.ErrorContains(err, "tree structure")
+	assert.ErrorContains(err, "loading record")
+
+	// data root mismatch, with a validly-signed commit
+	mismatch, _ := build("app.bsky.feed.post/3jzfcijpj2z2a")
+	other := testRepoWithRecords(t, "app.bsky.feed.post/3jzfcijpj2z2c")
+	if _, _, err := other.Commit(ctx, signer); err != nil {
+		t.Fatal(err)
+	}
+	otherRoot, err := other.Blockstore().Get(ctx, other.DataCid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	corrupt, err := blocks.NewBlockWithCid(otherRoot.RawData(), mismatch.DataCid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mismatch.Blockstore().Put(ctx, corrupt); err != nil {
+		t.Fatal(err)
+	}
+	// copy over the record the corrupt root points at, so the tree itself is well-formed
+	otherRecCid, _, err := other.GetRecordBytes(ctx, "app.bsky.feed.post/3jzfcijpj2z2c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherRec, err := other.Blockstore().Get(ctx, otherRecCid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mismatch.Blockstore().Put(ctx, otherRec); err != nil {
+		t.Fatal(err)
+	}
+	err = mismatch.FullVerify(ctx, pub)
+	var rootErr *DataRootMismatchError
+	assert.ErrorAs(err, &rootErr)
+	assert.NotContains(err.Error(), "commit signature")
+	assert.NotContains(err.Error(), "tree structure")
+}
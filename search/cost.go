@@ -0,0 +1,98 @@
+package search
+
+import (
+	"strings"
+	"time"
+)
+
+// Thresholds for scores returned by [EstimateCost]. Queries scoring below CostModerate are cheap; at or above CostExpensive, a gateway may want to reject or rate-limit them.
+const (
+	CostModerate  = 40
+	CostExpensive = 100
+)
+
+// EstimateCost returns a rough, unitless estimate of how expensive a post search will be to execute, so that callers can reject expensive queries before sending them. Compare against [CostModerate] and [CostExpensive].
+//
+// Wildcard terms, broad or unbounded time ranges, a lack of selective filters (author, mentions, URL, domain, quoted record, tags), large or deep pages, and optional extras (aggregations, collapsing, explanations, fuzzy matching) all increase the score. The estimate only looks at the params, and does not parse query string syntax; for the most accurate estimate, call it after merging parsed query params.
+func EstimateCost(q PostSearchParams) int {
+	cost := 5
+
+	for _, term := range strings.Fields(q.Query) {
+		cost += 2
+		switch {
+		case strings.HasPrefix(term, "*"):
+			// leading wildcards can't use the term index
+			cost += 30
+		case strings.Contains(term, "*"):
+			cost += 10
+		}
+	}
+
+	// selective filters narrow the candidate set substantially
+	selective := len(q.Tags)
+	if q.TagsMatchAny && selective > 1 {
+		// any one of several tags narrows less than requiring all of them
+		selective = 1
+	}
+	if q.Author != nil || len(q.Authors) > 0 {
+		selective++
+	}
+	if q.Mentions != nil {
+		selective++
+	}
+	if q.URL != "" {
+		selective++
+	}
+	if q.Domain != "" {
+		selective++
+	}
+	if q.QuotesURI != "" {
+		selective++
+	}
+	if selective == 0 {
+		cost += 20
+	} else {
+		cost -= min(selective*5, 10)
+	}
+
+	// time ranges: an unbounded start covers the whole index; otherwise scale with the width of the window
+	since := q.effectiveSince()
+	if since == nil {
+		cost += 20
+	} else {
+		until := time.Now()
+		if q.Until != nil {
+			until = q.Until.Time()
+		}
+		days := int(until.Sub(since.Time()).Hours() / 24)
+		cost += min(max(days/30, 0), 20)
+	}
+
+	size := q.Size
+	if size <= 0 {
+		size = 25
+	}
+	cost += size / 10
+	cost += q.Offset / 100
+
+	if q.Histogram == "hour" {
+		cost += 15
+	} else if q.Histogram != "" {
+		cost += 10
+	}
+	if q.CollapseField != "" {
+		cost += 5 + max(q.CollapseMax-1, 0)
+	}
+	if q.Explain {
+		cost += 25
+	}
+	for _, f := range q.QueryFlags {
+		// fuzzy matching expands every term to its edit-distance neighbors
+		if f := strings.ToUpper(strings.TrimSpace(f)); f == "FUZZY" || f == "ALL" {
+			cost += 15
+			break
+		}
+	}
+
+	return max(cost, 1)
+}
@@ -0,0 +1,56 @@
+package search
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEstimateCost(t *testing.T) {
+	assert := assert.New(t)
+
+	author := syntax.DID("did:plc:abc123")
+	since := syntax.DatetimeNow()
+	lastYear := syntax.Datetime(time.Now().AddDate(-1, 0, 0).UTC().Format(syntax.AtprotoDatetimeLayout))
+
+	narrow := PostSearchParams{Query: "hello", Author: &author, Since: &since, Size: 10}
+	broad := PostSearchParams{Query: "hello", Size: 10}
+
+	assert.Less(EstimateCost(narrow), CostModerate)
+	assert.Greater(EstimateCost(broad), EstimateCost(narrow))
+
+	// each dimension makes a query more expensive, holding the others fixed
+	wildcard := broad
+	wildcard.Query = "hel*"
+	assert.Greater(EstimateCost(wildcard), EstimateCost(broad))
+	leading := broad
+	leading.Query = "*llo"
+	assert.Greater(EstimateCost(leading), EstimateCost(wildcard))
+
+	wideRange := narrow
+	wideRange.Since = &lastYear
+	assert.Greater(EstimateCost(wideRange), EstimateCost(narrow))
+
+	bigPage := narrow
+	bigPage.Size = 250
+	assert.Greater(EstimateCost(bigPage), EstimateCost(narrow))
+
+	moreFilters := broad
+	moreFilters.Tags = []string{"art"}
+	assert.Less(EstimateCost(moreFilters), EstimateCost(broad))
+
+	extras := narrow
+	extras.Histogram = "hour"
+	extras.Explain = true
+	assert.Greater(EstimateCost(extras), EstimateCost(narrow))
+
+	fuzzy := narrow
+	fuzzy.QueryFlags = []string{"AND", "FUZZY"}
+	assert.Greater(EstimateCost(fuzzy), EstimateCost(narrow))
+
+	expensive := PostSearchParams{Query: "*a *b", Size: 250, Offset: 5000, Histogram: "hour"}
+	assert.GreaterOrEqual(EstimateCost(expensive), CostExpensive)
+}
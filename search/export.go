@@ -0,0 +1,89 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/bluesky-social/indigo/atproto/identity"
+)
+
+// page size used by StreamSearchResults when params don't specify one
+const exportPageSize = 100
+
+// StreamSearchResults runs a post search and writes the source document of each hit to w as a line of JSON, paging through results with "search_after" rather than offsets, so exports are not limited by the maximum result window. Stops after limit hits, or when results are exhausted; limit <= 0 means no limit. Returns the number of hits written.
+//
+// params.Size sets the page size (default 100); params.Offset, aggregations, explanations, and suggestions are ignored. Results are ordered by params as usual, with document ID as a final tie-breaker so that paging is stable.
+func StreamSearchResults(ctx context.Context, dir identity.Directory, searcher Searcher, index string, params PostSearchParams, w io.Writer, limit int) (int, error) {
+	ctx, span := tracer.Start(ctx, "StreamSearchResults")
+	defer span.End()
+
+	pageSize := params.Size
+	if pageSize <= 0 {
+		pageSize = exportPageSize
+	}
+	params.Offset = 0
+	params.Histogram = ""
+	params.Explain = false
+	params.Suggest = false
+	if err := checkParams(0, pageSize); err != nil {
+		return 0, err
+	}
+
+	if strings.TrimSpace(params.Query) != "" {
+		queryStringParams := ParsePostQuery(ctx, dir, params.Query, params.Viewer)
+		params.Update(&queryStringParams)
+	}
+
+	written := 0
+	var searchAfter []interface{}
+	for {
+		size := pageSize
+		if limit > 0 {
+			size = min(size, limit-written)
+		}
+		params.Size = size
+
+		query, err := postSearchQuery(&params)
+		if err != nil {
+			return written, err
+		}
+		delete(query, "from")
+		if searchAfter != nil {
+			query["search_after"] = searchAfter
+		}
+
+		resp, err := doSearch(ctx, searcher, index, query)
+		if err != nil {
+			return written, err
+		}
+
+		var line bytes.Buffer
+		for _, hit := range resp.Hits.Hits {
+			if limit > 0 && written >= limit {
+				break
+			}
+			line.Reset()
+			if err := json.Compact(&line, hit.Source); err != nil {
+				return written, fmt.Errorf("invalid source for hit %s: %w", hit.ID, err)
+			}
+			line.WriteByte('\n')
+			if _, err := w.Write(line.Bytes()); err != nil {
+				return written, err
+			}
+			written++
+		}
+
+		hits := resp.Hits.Hits
+		if len(hits) < size || (limit > 0 && written >= limit) {
+			return written, nil
+		}
+		searchAfter = hits[len(hits)-1].Sort
+		if len(searchAfter) == 0 {
+			return written, fmt.Errorf("search response missing sort values for paging")
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/bluesky-social/indigo/atproto/identity"
+
+	"github.com/opensearch-project/opensearch-go/v2/opensearchapi"
+	"github.com/stretchr/testify/assert"
+)
+
+// Searcher which replies with successive canned pages, and records each request
+type pagingSearcher struct {
+	pages   []string
+	queries []map[string]interface{}
+}
+
+func (ps *pagingSearcher) Search(ctx context.Context, index string, body io.Reader) (*opensearchapi.Response, error) {
+	var q map[string]interface{}
+	if err := json.NewDecoder(body).Decode(&q); err != nil {
+		return nil, err
+	}
+	ps.queries = append(ps.queries, q)
+	page := `{"hits": {"hits": []}}`
+	if len(ps.queries) <= len(ps.pages) {
+		page = ps.pages[len(ps.queries)-1]
+	}
+	return &opensearchapi.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(strings.NewReader(page)),
+	}, nil
+}
+
+func TestStreamSearchResults(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	dir := identity.NewMockDirectory()
+
+	pages := []string{
+		`{"hits": {"hits": [
+			{"_id": "a", "_source": {"text": "one"}, "sort": [1704164645000, "a"]},
+			{"_id": "b", "_source": {
+				"text": "two"
+			}, "sort": [1704164644000, "b"]}
+		]}}`,
+		`{"hits": {"hits": [
+			{"_id": "c", "_source": {"text": "three"}, "sort": [1704164643000, "c"]}
+		]}}`,
+	}
+
+	fake := &pagingSearcher{pages: pages}
+	var buf bytes.Buffer
+	n, err := StreamSearchResults(ctx, &dir, fake, "palomar_post", PostSearchParams{Query: "hello", Size: 2, Offset: 40}, &buf, 0)
+	assert.NoError(err)
+	assert.Equal(3, n)
+	assert.Equal("{\"text\":\"one\"}\n{\"text\":\"two\"}\n{\"text\":\"three\"}\n", buf.String())
+
+	// second page was short, so no third request
+	if assert.Len(fake.queries, 2) {
+		first, second := fake.queries[0], fake.queries[1]
+		assert.NotContains(first, "search_after")
+		assert.NotContains(first, "from")
+		assert.Equal(float64(2), first["size"])
+		assert.Equal([]interface{}{
+			map[string]interface{}{"created_at": map[string]interface{}{"order": "desc"}},
+			map[string]interface{}{"_id": map[string]interface{}{"order": "asc"}},
+		}, first["sort"])
+		assert.Equal([]interface{}{float64(1704164644000), "b"}, second["search_after"])
+	}
+
+	// stops at the caller's limit, shrinking the final page
+	fake = &pagingSearcher{pages: pages}
+	buf.Reset()
+	n, err = StreamSearchResults(ctx, &dir, fake, "palomar_post", PostSearchParams{Query: "hello", Size: 2}, &buf, 1)
+	assert.NoError(err)
+	assert.Equal(1, n)
+	assert.Equal("{\"text\":\"one\"}\n", buf.String())
+	if assert.Len(fake.queries, 1) {
+		assert.Equal(float64(1), fake.queries[0]["size"])
+	}
+
+	// a full page without sort values can't be paged
+	fake = &pagingSearcher{pages: []string{`{"hits": {"hits": [{"_id": "a", "_source": {}}]}}`}}
+	_, err = StreamSearchResults(ctx, &dir, fake, "palomar_post", PostSearchParams{Query: "hello", Size: 1}, io.Discard, 0)
+	assert.Error(err)
+}
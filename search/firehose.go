@@ -191,8 +191,10 @@ func (idx *Indexer) discoverRepos() {
 
 func (idx *Indexer) handleCreateOrUpdate(ctx context.Context, rawDID string, rev string, path string, recB *[]byte, rcid *cid.Cid) error {
 	logger := idx.logger.With("func", "handleCreateOrUpdate", "did", rawDID, "rev", rev, "path", path)
-	// Since this gets called in a backfill job, we need to check if the path is a post or profile
-	if !strings.Contains(path, "app.bsky.feed.post") && !strings.Contains(path, "app.bsky.actor.profile") {
+	// Since this gets called in a backfill job, we need to check if the path is an indexed record type
+	collection, _, _ := strings.Cut(path, "/")
+	index, err := idx.router.IndexForType(collection)
+	if err != nil {
 		return nil
 	}
 
@@ -231,6 +233,7 @@ func (idx *Indexer) handleCreateOrUpdate(ctx context.Context, rawDID string, rev
 			record: rec,
 			rcid:   *rcid,
 			rkey:   rkey.String(),
+			index:  index,
 		}
 
 		// Send the job to the bulk indexer
@@ -253,6 +256,7 @@ func (idx *Indexer) handleCreateOrUpdate(ctx context.Context, rawDID string, rev
 			ident:  ident,
 			record: rec,
 			rcid:   *rcid,
+			index:  index,
 		}
 
 		// Send the job to the bulk indexer
@@ -264,8 +268,10 @@ func (idx *Indexer) handleCreateOrUpdate(ctx context.Context, rawDID string, rev
 }
 
 func (idx *Indexer) handleDelete(ctx context.Context, rawDID, rev, path string) error {
-	// Since this gets called in a backfill job, we need to check if the path is a post or profile
-	if !strings.Contains(path, "app.bsky.feed.post") && !strings.Contains(path, "app.bsky.actor.profile") {
+	// Since this gets called in a backfill job, we need to check if the path is an indexed record type
+	collection, _, _ := strings.Cut(path, "/")
+	index, err := idx.router.IndexForType(collection)
+	if err != nil {
 		return nil
 	}
 
@@ -274,14 +280,14 @@ func (idx *Indexer) handleDelete(ctx context.Context, rawDID, rev, path string)
 		return fmt.Errorf("invalid DID in event: %w", err)
 	}
 
-	switch {
+	switch collection {
 	// TODO: handle profile deletes, its an edge case, but worth doing still
-	case strings.Contains(path, "app.bsky.feed.post"):
-		if err := idx.deletePost(ctx, did, path); err != nil {
+	case PostCollection:
+		if err := idx.deletePost(ctx, index, did, path); err != nil {
 			return err
 		}
 		postsDeleted.Inc()
-	case strings.Contains(path, "app.bsky.actor.profile"):
+	case ProfileCollection:
 		// profilesDeleted.Inc()
 	}
 
@@ -315,7 +321,8 @@ func (idx *Indexer) processTooBigCommit(ctx context.Context, evt *comatproto.Syn
 	}
 
 	return r.ForEach(ctx, "", func(k string, v cid.Cid) error {
-		if strings.HasPrefix(k, "app.bsky.feed.post") || strings.HasPrefix(k, "app.bsky.actor.profile") {
+		collection, _, _ := strings.Cut(k, "/")
+		if index, err := idx.router.IndexForType(collection); err == nil {
 			rcid, rec, err := r.GetRecord(ctx, k)
 			if err != nil {
 				// TODO: handle this case (instead of return nil)
@@ -342,6 +349,7 @@ func (idx *Indexer) processTooBigCommit(ctx context.Context, evt *comatproto.Syn
 					record: rec,
 					rcid:   rcid,
 					rkey:   rkey.String(),
+					index:  index,
 				}
 
 				// Send the job to the bulk indexer
@@ -355,6 +363,7 @@ func (idx *Indexer) processTooBigCommit(ctx context.Context, evt *comatproto.Syn
 					ident:  ident,
 					record: rec,
 					rcid:   rcid,
+					index:  index,
 				}
 
 				// Send the job to the bulk indexer
@@ -246,6 +246,7 @@ func (s *Server) handleSearchActorsSkeleton(e echo.Context) error {
 		Typeahead: typeahead,
 		Offset:    offset,
 		Size:      limit,
+		SortField: s.profileSort,
 	}
 
 	viewerStr := e.QueryParam("viewer")
@@ -282,7 +283,7 @@ func (s *Server) SearchPosts(ctx context.Context, params *PostSearchParams) (*ap
 	ctx, span := tracer.Start(ctx, "SearchPosts")
 	defer span.End()
 
-	resp, err := DoSearchPosts(ctx, s.dir, s.escli, s.postIndex, params)
+	resp, err := DoSearchPosts(ctx, s.dir, s.searcher, s.postIndex, params)
 	if err != nil {
 		return nil, err
 	}
@@ -341,9 +342,9 @@ func (s *Server) SearchProfiles(ctx context.Context, params *ActorSearchParams)
 		myQ.Follows = nil
 
 		if myQ.Typeahead {
-			globalResp, globalErr = DoSearchProfilesTypeahead(ctx, s.escli, s.profileIndex, &myQ)
+			globalResp, globalErr = DoSearchProfilesTypeahead(ctx, s.searcher, s.profileIndex, &myQ)
 		} else {
-			globalResp, globalErr = DoSearchProfiles(ctx, s.dir, s.escli, s.profileIndex, &myQ)
+			globalResp, globalErr = DoSearchProfiles(ctx, s.dir, s.searcher, s.profileIndex, &myQ)
 		}
 	}(*params)
 
@@ -353,9 +354,9 @@ func (s *Server) SearchProfiles(ctx context.Context, params *ActorSearchParams)
 		go func(myQ ActorSearchParams) {
 			defer wg.Done()
 			if myQ.Typeahead {
-				personalizedResp, personalizedErr = DoSearchProfilesTypeahead(ctx, s.escli, s.profileIndex, &myQ)
+				personalizedResp, personalizedErr = DoSearchProfilesTypeahead(ctx, s.searcher, s.profileIndex, &myQ)
 			} else {
-				personalizedResp, personalizedErr = DoSearchProfiles(ctx, s.dir, s.escli, s.profileIndex, &myQ)
+				personalizedResp, personalizedErr = DoSearchProfiles(ctx, s.dir, s.searcher, s.profileIndex, &myQ)
 			}
 		}(*params)
 	}
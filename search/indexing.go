@@ -31,6 +31,7 @@ type Indexer struct {
 	escli        *es.Client
 	postIndex    string
 	profileIndex string
+	router       *IndexRouter
 	db           *gorm.DB
 	relayhost    string
 	relayXRPC    *xrpc.Client
@@ -64,6 +65,8 @@ type ProfileIndexJob struct {
 	ident  *identity.Identity
 	record *appbsky.ActorProfile
 	rcid   cid.Cid
+	// target index, from the router; the default profile index if empty
+	index string
 }
 
 type PostIndexJob struct {
@@ -71,6 +74,8 @@ type PostIndexJob struct {
 	record *appbsky.FeedPost
 	rcid   cid.Cid
 	rkey   string
+	// target index, from the router; the default post index if empty
+	index string
 }
 
 type PagerankIndexJob struct {
@@ -107,6 +112,7 @@ func NewIndexer(db *gorm.DB, escli *es.Client, dir identity.Directory, config In
 		escli:               escli,
 		profileIndex:        config.ProfileIndex,
 		postIndex:           config.PostIndex,
+		router:              NewIndexRouter(config.PostIndex, config.ProfileIndex),
 		db:                  db,
 		relayhost:           config.RelayHost,
 		relayXRPC:           relayXRPC,
@@ -332,7 +338,7 @@ func (idx *Indexer) runPagerankIndexer(ctx context.Context) {
 	}
 }
 
-func (idx *Indexer) deletePost(ctx context.Context, did syntax.DID, recordPath string) error {
+func (idx *Indexer) deletePost(ctx context.Context, index string, did syntax.DID, recordPath string) error {
 	ctx, span := tracer.Start(ctx, "deletePost")
 	defer span.End()
 	span.SetAttributes(attribute.String("repo", did.String()), attribute.String("path", recordPath))
@@ -353,7 +359,7 @@ func (idx *Indexer) deletePost(ctx context.Context, did syntax.DID, recordPath s
 	docID := fmt.Sprintf("%s_%s", did.String(), rkey)
 	logger.Info("deleting post from index", "docID", docID)
 	req := esapi.DeleteRequest{
-		Index:      idx.postIndex,
+		Index:      index,
 		DocumentID: docID,
 		Refresh:    "true",
 	}
@@ -397,7 +403,11 @@ func (idx *Indexer) indexPosts(ctx context.Context, jobs []*PostIndexJob) error
 			return err
 		}
 
-		indexScript := []byte(fmt.Sprintf(`{"index":{"_id":"%s"}}%s`, doc.DocId(), "\n"))
+		index := job.index
+		if index == "" {
+			index = idx.postIndex
+		}
+		indexScript := []byte(fmt.Sprintf(`{"index":{"_index":"%s","_id":"%s"}}%s`, index, doc.DocId(), "\n"))
 		docBytes = append(docBytes, "\n"...)
 
 		buf.Grow(len(indexScript) + len(docBytes))
@@ -448,7 +458,11 @@ func (idx *Indexer) indexProfiles(ctx context.Context, jobs []*ProfileIndexJob)
 			return err
 		}
 
-		indexScript := []byte(fmt.Sprintf(`{"index":{"_id":"%s"}}%s`, job.ident.DID.String(), "\n"))
+		index := job.index
+		if index == "" {
+			index = idx.profileIndex
+		}
+		indexScript := []byte(fmt.Sprintf(`{"index":{"_index":"%s","_id":"%s"}}%s`, index, job.ident.DID.String(), "\n"))
 		docBytes = append(docBytes, "\n"...)
 
 		buf.Grow(len(indexScript) + len(docBytes))
@@ -535,6 +549,48 @@ func (idx *Indexer) indexPageranks(ctx context.Context, pageranks []*PagerankInd
 	return nil
 }
 
+// UpdateProfileDoc applies a partial update to the profile document for the given DID, changing only the provided fields (eg, "avatar_cid"). If the document does not exist yet, it is created from the fields (doc_as_upsert). Errors are logged to logger, or slog.Default() if nil.
+func UpdateProfileDoc(ctx context.Context, escli *es.Client, logger *slog.Logger, index, did string, fields map[string]any) error {
+	ctx, span := tracer.Start(ctx, "UpdateProfileDoc")
+	defer span.End()
+	span.SetAttributes(attribute.String("repo", did), attribute.Int("fields", len(fields)))
+
+	if len(fields) == 0 {
+		return fmt.Errorf("no fields to update")
+	}
+
+	b, err := json.Marshal(map[string]any{
+		"doc":           fields,
+		"doc_as_upsert": true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal profile update: %w", err)
+	}
+
+	req := esapi.UpdateRequest{
+		Index:      index,
+		DocumentID: did,
+		Body:       bytes.NewReader(b),
+	}
+	res, err := req.Do(ctx, escli)
+	if err != nil {
+		return fmt.Errorf("failed to send profile update: %w", err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read profile update response: %w", err)
+	}
+	if res.IsError() {
+		if logger == nil {
+			logger = slog.Default()
+		}
+		logger.Warn("opensearch profile update error", "index", index, "did", did, "status_code", res.StatusCode, "body", string(body))
+		return fmt.Errorf("profile update error, code=%d", res.StatusCode)
+	}
+	return nil
+}
+
 func (idx *Indexer) updateUserHandle(ctx context.Context, did syntax.DID, handle string) error {
 	ctx, span := tracer.Start(ctx, "updateUserHandle")
 	defer span.End()
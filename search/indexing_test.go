@@ -0,0 +1,122 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	appbsky "github.com/bluesky-social/indigo/api/bsky"
+
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multihash"
+	es "github.com/opensearch-project/opensearch-go/v2"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+func TestUpdateProfileDoc(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	var method, path string
+	var body map[string]any
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		path = r.URL.Path
+		raw, _ := io.ReadAll(r.Body)
+		body = nil
+		json.Unmarshal(raw, &body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(`{"result": "updated"}`))
+	}))
+	defer srv.Close()
+	escli, err := es.NewClient(es.Config{Addresses: []string{srv.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = UpdateProfileDoc(ctx, escli, nil, "palomar_profile", "did:plc:abc123", map[string]any{"avatar_cid": "bafyabc"})
+	assert.NoError(err)
+	assert.Equal(http.MethodPost, method)
+	assert.Equal("/palomar_profile/_update/did:plc:abc123", path)
+	assert.Equal(map[string]any{
+		"doc":           map[string]any{"avatar_cid": "bafyabc"},
+		"doc_as_upsert": true,
+	}, body)
+
+	status = http.StatusBadRequest
+	err = UpdateProfileDoc(ctx, escli, nil, "palomar_profile", "did:plc:abc123", map[string]any{"avatar_cid": "bafyabc"})
+	assert.ErrorContains(err, "code=400")
+
+	assert.Error(UpdateProfileDoc(ctx, escli, nil, "palomar_profile", "did:plc:abc123", nil))
+}
+
+func TestIndexerRoutesByType(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	var paths []string
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		paths = append(paths, r.URL.Path)
+		bodies = append(bodies, string(raw))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+	escli, err := es.NewClient(es.Config{Addresses: []string{srv.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := NewIndexRouter("palomar_post", "palomar_profile")
+	router.Route(PostCollection, "routed_post")
+	idx := &Indexer{
+		escli:        escli,
+		postIndex:    "palomar_post",
+		profileIndex: "palomar_profile",
+		router:       router,
+		logger:       slog.Default(),
+		indexLimiter: rate.NewLimiter(rate.Inf, 1),
+		postQueue:    make(chan *PostIndexJob, 1),
+	}
+
+	post := appbsky.FeedPost{LexiconTypeID: PostCollection, Text: "hello", CreatedAt: "2024-01-02T03:04:05.006Z"}
+	buf := new(bytes.Buffer)
+	if err := post.MarshalCBOR(buf); err != nil {
+		t.Fatal(err)
+	}
+	recB := buf.Bytes()
+	rcid, err := cid.NewPrefixV1(cid.DagCBOR, multihash.SHA2_256).Sum(recB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// creates are queued for the routed index, and bulk indexed there
+	assert.NoError(idx.handleCreateOrUpdate(ctx, "did:plc:abc123", "3jzfcijpj2z2a", PostCollection+"/3jzfcijpj2z2a", &recB, &rcid))
+	job := <-idx.postQueue
+	assert.Equal("routed_post", job.index)
+	assert.NoError(idx.indexPosts(ctx, []*PostIndexJob{job}))
+	assert.Contains(bodies[0], `{"index":{"_index":"routed_post","_id":"did:plc:abc123_3jzfcijpj2z2a"}}`)
+
+	// jobs without a routed index go to the default index
+	job.index = ""
+	assert.NoError(idx.indexPosts(ctx, []*PostIndexJob{job}))
+	assert.Contains(bodies[1], `{"index":{"_index":"palomar_post","_id":"did:plc:abc123_3jzfcijpj2z2a"}}`)
+
+	// deletes go to the routed index
+	assert.NoError(idx.handleDelete(ctx, "did:plc:abc123", "3jzfcijpj2z2b", PostCollection+"/3jzfcijpj2z2a"))
+	assert.Equal("/routed_post/_doc/did:plc:abc123_3jzfcijpj2z2a", paths[2])
+
+	// unrouted types are skipped
+	assert.NoError(idx.handleDelete(ctx, "did:plc:abc123", "3jzfcijpj2z2b", "app.bsky.feed.like/3jzfcijpj2z2a"))
+	assert.Len(paths, 3)
+}
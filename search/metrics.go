@@ -52,6 +52,16 @@ var profilesDeleted = promauto.NewCounter(prometheus.CounterOpts{
 	Help: "Number of profiles deleted",
 })
 
+var searchCacheHits = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "search_query_cache_hits",
+	Help: "Number of search queries answered from the response cache",
+})
+
+var searchCacheMisses = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "search_query_cache_misses",
+	Help: "Number of search queries not found in the response cache",
+})
+
 var currentSeq = promauto.NewGauge(prometheus.GaugeOpts{
 	Name: "search_current_seq",
 	Help: "Current sequence number",
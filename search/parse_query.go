@@ -3,6 +3,7 @@ package search
 import (
 	"context"
 	"log/slog"
+	"slices"
 	"strings"
 	"time"
 
@@ -10,8 +11,21 @@ import (
 	"github.com/bluesky-social/indigo/atproto/syntax"
 )
 
+// ParseOptions configures optional query string clean-up in ParsePostQueryWithOptions
+type ParseOptions struct {
+	// StopWords are removed (case-insensitively) from the query text, outside of quoted phrases
+	StopWords []string
+	// NormalizeEmoji strips emoji skin tone modifiers and variation selectors from the query text, outside of quoted phrases, so that eg "👍🏽" matches "👍"
+	NormalizeEmoji bool
+}
+
 // ParsePostQuery takes a query string and pulls out some facet patterns ("from:handle.net") as filters
 func ParsePostQuery(ctx context.Context, dir identity.Directory, raw string, viewer *syntax.DID) PostSearchParams {
+	return ParsePostQueryWithOptions(ctx, dir, raw, viewer, ParseOptions{})
+}
+
+// ParsePostQueryWithOptions is like ParsePostQuery, with additional clean-up of the remaining query text. If clean-up would remove every term, the original terms are kept.
+func ParsePostQueryWithOptions(ctx context.Context, dir identity.Directory, raw string, viewer *syntax.DID, opts ParseOptions) PostSearchParams {
 	quoted := false
 	parts := strings.FieldsFunc(raw, func(r rune) bool {
 		if r == '"' {
@@ -99,6 +113,17 @@ func ParsePostQuery(ctx context.Context, dir identity.Directory, raw string, vie
 				params.Mentions = &id.DID
 			}
 			continue
+		case "actor":
+			// may be repeated; matches posts by any of the actors
+			did, err := resolveActor(ctx, dir, tokParts[1])
+			if err != nil {
+				slog.Warn("dropping unresolvable actor from query", "actor", tokParts[1], "err", err)
+				continue
+			}
+			if !slices.Contains(params.Authors, did) {
+				params.Authors = append(params.Authors, did)
+			}
+			continue
 		case "http", "https":
 			params.URL = p
 			continue
@@ -135,6 +160,8 @@ func ParsePostQuery(ctx context.Context, dir identity.Directory, raw string, vie
 		keep = append(keep, p)
 	}
 
+	keep = opts.clean(keep)
+
 	out := ""
 	for _, p := range keep {
 		if out == "" {
@@ -149,3 +176,59 @@ func ParsePostQuery(ctx context.Context, dir identity.Directory, raw string, vie
 	params.Query = out
 	return params
 }
+
+// resolves an "actor:" operator value, which is either a DID or a handle (optionally with "@" prefix), to a DID
+func resolveActor(ctx context.Context, dir identity.Directory, raw string) (syntax.DID, error) {
+	if did, err := syntax.ParseDID(raw); err == nil {
+		return did, nil
+	}
+	handle, err := syntax.ParseHandle(strings.TrimPrefix(raw, "@"))
+	if err != nil {
+		return "", err
+	}
+	id, err := dir.LookupHandle(ctx, handle)
+	if err != nil {
+		return "", err
+	}
+	return id.DID, nil
+}
+
+// clean applies stop word and emoji clean-up to query terms, passing through quoted terms
+func (opts ParseOptions) clean(terms []string) []string {
+	if len(opts.StopWords) == 0 && !opts.NormalizeEmoji {
+		return terms
+	}
+	stop := make(map[string]bool, len(opts.StopWords))
+	for _, w := range opts.StopWords {
+		stop[strings.ToLower(w)] = true
+	}
+
+	out := make([]string, 0, len(terms))
+	for _, t := range terms {
+		if strings.HasPrefix(t, "\"") {
+			out = append(out, t)
+			continue
+		}
+		if opts.NormalizeEmoji {
+			t = strings.Map(func(r rune) rune {
+				if isEmojiModifier(r) {
+					return -1
+				}
+				return r
+			}, t)
+		}
+		if t == "" || stop[strings.ToLower(t)] {
+			continue
+		}
+		out = append(out, t)
+	}
+	if len(out) == 0 {
+		return terms
+	}
+	return out
+}
+
+// emoji skin tone modifiers, and text/emoji presentation variation selectors
+func isEmojiModifier(r rune) bool {
+	return (r >= 0x1F3FB && r <= 0x1F3FF) || r == 0xFE0E || r == 0xFE0F
+}
@@ -96,3 +96,75 @@ func TestParseQuery(t *testing.T) {
 
 	// TODO: more parsing tests: bare handles, to:, since:, until:, URL, domain:, lang
 }
+
+func TestParseQueryOptions(t *testing.T) {
+	ctx := context.Background()
+	assert := assert.New(t)
+	dir := identity.NewMockDirectory()
+
+	opts := ParseOptions{StopWords: []string{"the", "of", "a"}}
+
+	p := ParsePostQueryWithOptions(ctx, &dir, `The history of "the lord of the rings" a book`, nil, opts)
+	assert.Equal(`history "the lord of the rings" book`, p.Query)
+
+	p = ParsePostQueryWithOptions(ctx, &dir, `"of" the lang:en`, nil, opts)
+	assert.Equal(`"of"`, p.Query)
+	assert.NotNil(p.Lang)
+
+	// a query of only stop words is left alone, rather than matching everything
+	p = ParsePostQueryWithOptions(ctx, &dir, "the of", nil, opts)
+	assert.Equal("the of", p.Query)
+
+	// no options is the same as ParsePostQuery
+	p = ParsePostQueryWithOptions(ctx, &dir, "the history", nil, ParseOptions{})
+	assert.Equal("the history", p.Query)
+
+	opts = ParseOptions{NormalizeEmoji: true}
+	p = ParsePostQueryWithOptions(ctx, &dir, "great 👍🏽 ❤️ \"👍🏽\"", nil, opts)
+	assert.Equal("great 👍 ❤ \"👍🏽\"", p.Query)
+}
+
+func TestParseQueryActors(t *testing.T) {
+	ctx := context.Background()
+	assert := assert.New(t)
+	dir := identity.NewMockDirectory()
+	dir.Insert(identity.Identity{
+		Handle: syntax.Handle("alice.example.com"),
+		DID:    syntax.DID("did:plc:abc111"),
+	})
+	dir.Insert(identity.Identity{
+		Handle: syntax.Handle("bob.example.com"),
+		DID:    syntax.DID("did:plc:abc222"),
+	})
+
+	q := "cats actor:alice.example.com actor:did:web:carol.example.com dogs actor:@bob.example.com actor:missing.example.com actor:not_valid actor:did:plc:abc111"
+	p := ParsePostQuery(ctx, &dir, q, nil)
+	assert.Equal("cats dogs", p.Query)
+	assert.Nil(p.Author)
+	assert.Equal([]syntax.DID{"did:plc:abc111", "did:web:carol.example.com", "did:plc:abc222"}, p.Authors)
+	assert.Equal([]map[string]interface{}{
+		{"terms": map[string]interface{}{"did": []string{"did:plc:abc111", "did:web:carol.example.com", "did:plc:abc222"}}},
+	}, p.Filters())
+
+	// only unresolvable actors: no filter at all
+	p = ParsePostQuery(ctx, &dir, "cats actor:missing.example.com", nil)
+	assert.Equal("cats", p.Query)
+	assert.Empty(p.Authors)
+	assert.Empty(p.Filters())
+
+	// combined with "from:"
+	p = ParsePostQuery(ctx, &dir, "actor:bob.example.com from:alice.example.com", nil)
+	assert.Equal("*", p.Query)
+	assert.Equal(syntax.DID("did:plc:abc111"), *p.Author)
+	assert.Equal([]syntax.DID{"did:plc:abc222"}, p.Authors)
+	// a single filter matching posts by either account
+	assert.Equal([]map[string]interface{}{
+		{"terms": map[string]interface{}{"did": []string{"did:plc:abc111", "did:plc:abc222"}}},
+	}, p.Filters())
+
+	// repeated account
+	p = ParsePostQuery(ctx, &dir, "actor:alice.example.com from:alice.example.com", nil)
+	assert.Equal([]map[string]interface{}{
+		{"terms": map[string]interface{}{"did": []string{"did:plc:abc111"}}},
+	}, p.Filters())
+}
@@ -4,23 +4,33 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
-	"log/slog"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/bluesky-social/indigo/atproto/identity"
 	"github.com/bluesky-social/indigo/atproto/syntax"
 
-	es "github.com/opensearch-project/opensearch-go/v2"
 	"go.opentelemetry.io/otel/attribute"
 )
 
+// Returned by post search when the query string is empty and MatchAllIfEmpty is not set
+var ErrEmptyQuery = errors.New("search query string is empty")
+
 type EsSearchHit struct {
 	Index  string          `json:"_index"`
 	ID     string          `json:"_id"`
 	Score  float64         `json:"_score"`
 	Source json.RawMessage `json:"_source"`
+
+	// Explanation is the raw relevance scoring explanation, only present if the query requested it
+	Explanation json.RawMessage `json:"_explanation,omitempty"`
+	// Sort holds the hit's sort values, which can be passed as "search_after" to fetch the following page
+	Sort []interface{} `json:"sort,omitempty"`
 }
 
 type EsSearchHits struct {
@@ -33,9 +43,22 @@ type EsSearchHits struct {
 }
 
 type EsSearchResponse struct {
-	Took     int          `json:"took"`
-	TimedOut bool         `json:"timed_out"`
-	Hits     EsSearchHits `json:"hits"`
+	Took         int                        `json:"took"`
+	TimedOut     bool                       `json:"timed_out"`
+	Hits         EsSearchHits               `json:"hits"`
+	Aggregations map[string]json.RawMessage `json:"aggregations,omitempty"`
+	Suggest      map[string]json.RawMessage `json:"suggest,omitempty"`
+
+	// Timeline is parsed from the "timeline" date histogram aggregation, when requested
+	Timeline []TimeBucket `json:"-"`
+	// Suggestions are alternative ("did you mean") query strings, parsed from the "did_you_mean" suggester, when requested. Nil otherwise.
+	Suggestions []string `json:"-"`
+}
+
+// TimeBucket is a single interval of a date histogram: the count of matching documents created in the interval starting at Time
+type TimeBucket struct {
+	Time  time.Time `json:"time"`
+	Count int       `json:"count"`
 }
 
 type UserResult struct {
@@ -64,8 +87,82 @@ type PostSearchParams struct {
 	Viewer   *syntax.DID      `json:"viewer"`
 	Offset   int              `json:"offset"`
 	Size     int              `json:"size"`
+
+	// CollapseField, if set, limits results to a single top hit per distinct value of that field (eg, "did" for one post per author)
+	CollapseField string `json:"collapse_field"`
+	// CollapseMax, if greater than one, includes up to this many hits per collapsed value as inner hits
+	CollapseMax int `json:"collapse_max"`
+	// Fields overrides the document fields searched by the query string, optionally with weights (eg, "text^2"). Defaults to the combined "everything" field.
+	Fields []string `json:"fields"`
+	// Explain requests a relevance scoring explanation for each hit, for debugging. Expensive; not for regular traffic.
+	Explain bool `json:"explain"`
+	// Histogram, if set to "day" or "hour", requests a date histogram of matching posts by created_at, returned as the response Timeline
+	Histogram string `json:"histogram"`
+	// SortMode selects result ordering. Defaults to SortRecent; the API-style Sort value does not change the ordering.
+	SortMode SortMode `json:"sort_mode"`
+	// QueryFlags overrides the simple_query_string operators enabled for the query string (eg, drop "PRECEDENCE" to reduce query complexity, or add "FUZZY"). Defaults to DefaultQueryFlags.
+	QueryFlags []string `json:"query_flags"`
+	// RelativeWindow, if positive, limits results to posts created within this duration before the current time (eg, the last 7 days). An explicit Since takes precedence.
+	RelativeWindow time.Duration `json:"relative_window"`
+	// ProximityPhrases requires each phrase to appear in the post text, with terms at most Slop positions apart
+	ProximityPhrases []ProximityClause `json:"proximity_phrases"`
+	// DefaultOperator is the operator between query string terms which have no explicit operator: "and" (the default) requires all terms, "or" matches any term
+	DefaultOperator string `json:"default_operator"`
+	// QuotesURI limits results to posts embedding (quoting) the record with this AT-URI
+	QuotesURI string `json:"quotes_uri"`
+	// AltTextOnly limits results to posts with image embeds where every image has alt text
+	AltTextOnly bool `json:"alt_text_only"`
+	// TagsMatchAny, if set, matches posts with any of the Tags, instead of requiring all of them
+	TagsMatchAny bool `json:"tags_match_any"`
+	// ExcludeLangs hides posts tagged with any of these languages (matched by 2-letter code, so "en-US" excludes all "en" posts), including multi-language posts which also match Lang
+	ExcludeLangs []string `json:"exclude_langs"`
+	// Suggest requests spelling suggestions for the query string, returned as the response Suggestions. Mostly useful for showing "did you mean" alternatives when there are no hits.
+	Suggest bool `json:"suggest"`
+	// Authors limits results to posts by any of these accounts (eg, from "actor:" query operators). If Author is also set, it is included as one more of these accounts.
+	Authors []syntax.DID `json:"authors"`
+	// Lenient controls whether the query string ignores type errors, such as text queried against a numeric or date field (via Fields). Defaults to true; set to false to have such queries fail instead.
+	Lenient *bool `json:"lenient"`
+	// MatchAllIfEmpty, if set, matches every post (subject to filters) when the query string is empty. Otherwise an empty query string is an error (ErrEmptyQuery), unless ProximityPhrases are given.
+	MatchAllIfEmpty bool `json:"match_all_if_empty"`
+}
+
+// ProximityClause is a "near" phrase query against post text, like `"climate change"~5` in query string syntax
+type ProximityClause struct {
+	Phrase string `json:"phrase"`
+	// Slop is the number of positional moves allowed between phrase terms. Zero requires an exact phrase match.
+	Slop int `json:"slop"`
 }
 
+// DefaultQueryFlags are the simple_query_string operators enabled for post and profile queries
+var DefaultQueryFlags = []string{"AND", "NOT", "OR", "PHRASE", "PRECEDENCE", "WHITESPACE"}
+
+// all flags accepted by simple_query_string
+var knownQueryFlags = map[string]bool{
+	"ALL":        true,
+	"AND":        true,
+	"ESCAPE":     true,
+	"FUZZY":      true,
+	"NEAR":       true,
+	"NONE":       true,
+	"NOT":        true,
+	"OR":         true,
+	"PHRASE":     true,
+	"PRECEDENCE": true,
+	"PREFIX":     true,
+	"SLOP":       true,
+	"WHITESPACE": true,
+}
+
+// SortMode controls the ordering of post search results
+type SortMode string
+
+const (
+	// SortRecent orders results by creation time, newest first, ignoring relevance
+	SortRecent SortMode = "recent"
+	// SortRelevance orders results by relevance score, with creation time as a tie-breaker
+	SortRelevance SortMode = "relevance"
+)
+
 type ActorSearchParams struct {
 	Query     string       `json:"q"`
 	Typeahead bool         `json:"typeahead"`
@@ -73,6 +170,70 @@ type ActorSearchParams struct {
 	Viewer    *syntax.DID  `json:"viewer"`
 	Offset    int          `json:"offset"`
 	Size      int          `json:"size"`
+
+	// BoostHandle adds a weighted match on the handle field, which improves relevance for exact handle queries at some additional query cost
+	BoostHandle bool `json:"boost_handle"`
+
+	// SortField is a numeric document field (eg, "pagerank") to rank profile results by, descending. Documents missing the field are ranked last, and ties are broken by relevance score. Empty (or "_score") sorts by relevance only.
+	SortField string `json:"sort_field"`
+
+	// RecencyBoost, if positive, blends a recency signal in to typeahead ranking: profiles indexed (created or updated) recently get up to this much added to their relevance score, decaying over about a month. Zero (the default) ranks by relevance only.
+	RecencyBoost float64 `json:"recency_boost"`
+
+	// Fields, if set, searches these document fields with per-field weights (eg, handle^3, display_name^2, description) using a "multi_match" query, instead of the combined "everything" field. BoostHandle is ignored when Fields is set.
+	Fields []FieldBoost `json:"fields"`
+}
+
+// FieldBoost is a document field to search, with a relevance weight
+type FieldBoost struct {
+	Field string `json:"field"`
+	// Boost multiplies the relevance score of matches on this field. Zero means the default weight of 1.
+	Boost float64 `json:"boost"`
+}
+
+// String returns the field in query DSL "field^boost" syntax
+func (fb FieldBoost) String() string {
+	if fb.Boost == 0 || fb.Boost == 1 {
+		return fb.Field
+	}
+	return fb.Field + "^" + strconv.FormatFloat(fb.Boost, 'f', -1, 64)
+}
+
+func (p *ActorSearchParams) checkFields() error {
+	if p.Fields == nil {
+		return nil
+	}
+	if len(p.Fields) == 0 {
+		return fmt.Errorf("empty search fields list")
+	}
+	for _, fb := range p.Fields {
+		if strings.TrimSpace(fb.Field) == "" {
+			return fmt.Errorf("empty search field name")
+		}
+		if fb.Boost < 0 {
+			return fmt.Errorf("negative boost for search field %s", fb.Field)
+		}
+	}
+	return nil
+}
+
+// Sorts returns the sort DSL for profile search results
+func (p *ActorSearchParams) Sorts() []map[string]interface{} {
+	score := map[string]interface{}{
+		"_score": map[string]interface{}{"order": "desc"},
+	}
+	if p.SortField == "" || p.SortField == "_score" {
+		return []map[string]interface{}{score}
+	}
+	return []map[string]interface{}{
+		{p.SortField: map[string]interface{}{
+			"order":   "desc",
+			"missing": "_last",
+			// don't fail the query on indices where the field has never been mapped
+			"unmapped_type": "float",
+		}},
+		score,
+	}
 }
 
 // Merges params from another param object in to this one. Intended to meld parsed query with HTTP query params, so not all functionality is supported, and priority is with the "current" object
@@ -81,6 +242,9 @@ func (p *PostSearchParams) Update(other *PostSearchParams) {
 	if p.Author == nil {
 		p.Author = other.Author
 	}
+	if len(p.Authors) == 0 {
+		p.Authors = other.Authors
+	}
 	if p.Since == nil {
 		p.Since = other.Since
 	}
@@ -101,6 +265,65 @@ func (p *PostSearchParams) Update(other *PostSearchParams) {
 	}
 	if len(p.Tags) == 0 {
 		p.Tags = other.Tags
+		p.TagsMatchAny = other.TagsMatchAny
+	}
+	if p.QuotesURI == "" {
+		p.QuotesURI = other.QuotesURI
+	}
+	if !p.AltTextOnly {
+		p.AltTextOnly = other.AltTextOnly
+	}
+	if len(p.ExcludeLangs) == 0 {
+		p.ExcludeLangs = other.ExcludeLangs
+	}
+}
+
+// Aggregations returns any elasticsearch/opensearch aggregations requested by params, or nil. Aggregations are computed over the same filtered query as the hits.
+func (p *PostSearchParams) Aggregations() (map[string]interface{}, error) {
+	if p.Histogram == "" {
+		return nil, nil
+	}
+	switch p.Histogram {
+	case "day", "hour":
+	default:
+		return nil, fmt.Errorf("unsupported histogram interval: %q", p.Histogram)
+	}
+	return map[string]interface{}{
+		"timeline": map[string]interface{}{
+			"date_histogram": map[string]interface{}{
+				"field":             "created_at",
+				"calendar_interval": p.Histogram,
+				"min_doc_count":     0,
+			},
+		},
+	}, nil
+}
+
+// Sorts returns the elasticsearch/opensearch sort array for the configured sort mode
+func (p *PostSearchParams) Sorts() ([]map[string]interface{}, error) {
+	mode := p.SortMode
+	if mode == "" {
+		mode = SortRecent
+	}
+
+	createdAt := map[string]interface{}{
+		"created_at": map[string]interface{}{"order": "desc"},
+	}
+	// final tiebreaker on the unique document ID, so that the order of posts with identical timestamps is deterministic, and search_after paging doesn't skip or repeat hits
+	docID := map[string]interface{}{
+		"_id": map[string]interface{}{"order": "asc"},
+	}
+	switch mode {
+	case SortRecent:
+		return []map[string]interface{}{createdAt, docID}, nil
+	case SortRelevance:
+		return []map[string]interface{}{
+			{"_score": map[string]interface{}{"order": "desc"}},
+			createdAt,
+			docID,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported sort mode: %q", mode)
 	}
 }
 
@@ -108,7 +331,7 @@ func (p *PostSearchParams) Update(other *PostSearchParams) {
 func (p *PostSearchParams) Filters() []map[string]interface{} {
 	var filters []map[string]interface{}
 
-	if p.Author != nil {
+	if p.Author != nil && len(p.Authors) == 0 {
 		filters = append(filters, map[string]interface{}{
 			"term": map[string]interface{}{"did": map[string]interface{}{
 				"value":            p.Author.String(),
@@ -117,6 +340,22 @@ func (p *PostSearchParams) Filters() []map[string]interface{} {
 		})
 	}
 
+	if len(p.Authors) > 0 {
+		// a single filter for the union of Author and Authors; separate filters would be ANDed together
+		var dids []string
+		if p.Author != nil {
+			dids = append(dids, p.Author.String())
+		}
+		for _, did := range p.Authors {
+			if !slices.Contains(dids, did.String()) {
+				dids = append(dids, did.String())
+			}
+		}
+		filters = append(filters, map[string]interface{}{
+			"terms": map[string]interface{}{"did": dids},
+		})
+	}
+
 	if p.Mentions != nil {
 		filters = append(filters, map[string]interface{}{
 			"term": map[string]interface{}{"mention_did": map[string]interface{}{
@@ -136,11 +375,11 @@ func (p *PostSearchParams) Filters() []map[string]interface{} {
 		})
 	}
 
-	if p.Since != nil {
+	if since := p.effectiveSince(); since != nil {
 		filters = append(filters, map[string]interface{}{
 			"range": map[string]interface{}{
 				"created_at": map[string]interface{}{
-					"gte": p.Since.String(),
+					"gte": since.UTC().String(),
 				},
 			},
 		})
@@ -150,7 +389,7 @@ func (p *PostSearchParams) Filters() []map[string]interface{} {
 		filters = append(filters, map[string]interface{}{
 			"range": map[string]interface{}{
 				"created_at": map[string]interface{}{
-					"lt": p.Until.String(),
+					"lt": p.Until.UTC().String(),
 				},
 			},
 		})
@@ -174,8 +413,24 @@ func (p *PostSearchParams) Filters() []map[string]interface{} {
 		})
 	}
 
-	for _, tag := range p.Tags {
+	if p.QuotesURI != "" {
 		filters = append(filters, map[string]interface{}{
+			"term": map[string]interface{}{"embed_aturi": map[string]interface{}{
+				"value":            p.QuotesURI,
+				"case_insensitive": true,
+			}},
+		})
+	}
+
+	if p.AltTextOnly {
+		filters = append(filters, map[string]interface{}{
+			"term": map[string]interface{}{"has_alt_text": true},
+		})
+	}
+
+	var tagClauses []Clause
+	for _, tag := range p.Tags {
+		tagClauses = append(tagClauses, map[string]interface{}{
 			"term": map[string]interface{}{
 				"tag": map[string]interface{}{
 					"value":            tag,
@@ -184,11 +439,49 @@ func (p *PostSearchParams) Filters() []map[string]interface{} {
 			},
 		})
 	}
+	if p.TagsMatchAny && len(tagClauses) > 1 {
+		one := 1
+		anyTag := Query{Should: tagClauses, MinimumShouldMatch: &one}
+		filters = append(filters, anyTag.Build())
+	} else {
+		filters = append(filters, tagClauses...)
+	}
 
 	return filters
 }
 
-// Filters turns search params in to actual elasticsearch/opensearch filter DSL
+// MustNots returns the elasticsearch/opensearch clauses excluding posts from results
+func (p *PostSearchParams) MustNots() []map[string]interface{} {
+	if len(p.ExcludeLangs) == 0 {
+		return nil
+	}
+	langs := make([]string, 0, len(p.ExcludeLangs))
+	for _, l := range p.ExcludeLangs {
+		langs = append(langs, langCodeIso2(l))
+	}
+	return []map[string]interface{}{
+		{"terms": map[string]interface{}{"lang_code_iso2": langs}},
+	}
+}
+
+// reduces a language tag to the lower-case primary language code, as indexed in lang_code_iso2
+func langCodeIso2(lang string) string {
+	return strings.ToLower(strings.SplitN(lang, "-", 2)[0])
+}
+
+// checks that the included language isn't also excluded, which would never match anything
+func (p *PostSearchParams) checkLangs() error {
+	if p.Lang == nil {
+		return nil
+	}
+	for _, l := range p.ExcludeLangs {
+		if langCodeIso2(l) == langCodeIso2(p.Lang.String()) {
+			return fmt.Errorf("language %q is both included and excluded", p.Lang.String())
+		}
+	}
+	return nil
+}
+
 func (p *ActorSearchParams) Filters() []map[string]interface{} {
 	var filters []map[string]interface{}
 
@@ -207,6 +500,128 @@ func (p *ActorSearchParams) Filters() []map[string]interface{} {
 	return filters
 }
 
+// SuggestQuery returns the suggest DSL for the query string, or nil if not requested. A phrase suggester over post text proposes whole corrected query strings.
+func (p *PostSearchParams) SuggestQuery() map[string]interface{} {
+	q := strings.TrimSpace(p.Query)
+	if !p.Suggest || q == "" || q == "*" {
+		return nil
+	}
+	return map[string]interface{}{
+		"did_you_mean": map[string]interface{}{
+			"text": q,
+			"phrase": map[string]interface{}{
+				"field": "text",
+				"size":  3,
+			},
+		},
+	}
+}
+
+// Collapse returns an elasticsearch/opensearch "collapse" clause, or nil if results should not be collapsed
+func (p *PostSearchParams) Collapse() map[string]interface{} {
+	if p.CollapseField == "" {
+		return nil
+	}
+	collapse := map[string]interface{}{
+		"field": p.CollapseField,
+	}
+	if p.CollapseMax > 1 {
+		collapse["inner_hits"] = map[string]interface{}{
+			"name": "collapsed",
+			"size": p.CollapseMax,
+		}
+	}
+	return collapse
+}
+
+// returns the list of fields to run the query string against
+func (p *PostSearchParams) queryFields() ([]string, error) {
+	if p.Fields == nil {
+		if containsJapanese(p.Query) {
+			return []string{"everything_ja"}, nil
+		}
+		return []string{"everything"}, nil
+	}
+	if len(p.Fields) == 0 {
+		return nil, fmt.Errorf("empty search fields list")
+	}
+	for _, f := range p.Fields {
+		if strings.TrimSpace(f) == "" {
+			return nil, fmt.Errorf("empty search field name")
+		}
+	}
+	return p.Fields, nil
+}
+
+// returns the lower bound on created_at: Since if set, otherwise computed from RelativeWindow, or nil if neither is set
+func (p *PostSearchParams) effectiveSince() *syntax.Datetime {
+	if p.Since != nil {
+		return p.Since
+	}
+	if p.RelativeWindow > 0 {
+		dt := syntax.Datetime(time.Now().Add(-p.RelativeWindow).UTC().Format(syntax.AtprotoDatetimeLayout))
+		return &dt
+	}
+	return nil
+}
+
+// returns the simple_query_string "flags" value
+func (p *PostSearchParams) queryFlags() (string, error) {
+	if p.QueryFlags == nil {
+		return strings.Join(DefaultQueryFlags, "|"), nil
+	}
+	if len(p.QueryFlags) == 0 {
+		return "", fmt.Errorf("empty query flags list (use NONE to disable all operators)")
+	}
+	flags := make([]string, len(p.QueryFlags))
+	for i, f := range p.QueryFlags {
+		flags[i] = strings.ToUpper(strings.TrimSpace(f))
+		if !knownQueryFlags[flags[i]] {
+			return "", fmt.Errorf("unknown query flag: %q", f)
+		}
+	}
+	return strings.Join(flags, "|"), nil
+}
+
+// returns the simple_query_string "lenient" value
+func (p *PostSearchParams) lenient() bool {
+	return p.Lenient == nil || *p.Lenient
+}
+
+// returns the simple_query_string "default_operator" value
+func (p *PostSearchParams) defaultOperator() (string, error) {
+	switch op := strings.ToLower(strings.TrimSpace(p.DefaultOperator)); op {
+	case "":
+		return "and", nil
+	case "and", "or":
+		return op, nil
+	default:
+		return "", fmt.Errorf("invalid default operator: %q", p.DefaultOperator)
+	}
+}
+
+// returns a match_phrase clause for each of the proximity phrases
+func (p *PostSearchParams) proximityClauses() ([]Clause, error) {
+	var clauses []Clause
+	for _, pc := range p.ProximityPhrases {
+		if strings.TrimSpace(pc.Phrase) == "" {
+			return nil, fmt.Errorf("empty proximity phrase")
+		}
+		if pc.Slop < 0 {
+			return nil, fmt.Errorf("negative slop for proximity phrase %q: %d", pc.Phrase, pc.Slop)
+		}
+		clauses = append(clauses, Clause{
+			"match_phrase": map[string]interface{}{
+				"text": map[string]interface{}{
+					"query": pc.Phrase,
+					"slop":  pc.Slop,
+				},
+			},
+		})
+	}
+	return clauses, nil
+}
+
 func checkParams(offset, size int) error {
 	if offset+size > 10000 || size > 250 || offset > 10000 || offset < 0 || size < 0 {
 		return fmt.Errorf("disallowed size/offset parameters")
@@ -214,29 +629,77 @@ func checkParams(offset, size int) error {
 	return nil
 }
 
-func DoSearchPosts(ctx context.Context, dir identity.Directory, escli *es.Client, index string, params *PostSearchParams) (*EsSearchResponse, error) {
+func DoSearchPosts(ctx context.Context, dir identity.Directory, searcher Searcher, index string, params *PostSearchParams) (*EsSearchResponse, error) {
 	ctx, span := tracer.Start(ctx, "DoSearchPosts")
 	defer span.End()
 
 	if err := checkParams(params.Offset, params.Size); err != nil {
 		return nil, err
 	}
-	queryStringParams := ParsePostQuery(ctx, dir, params.Query, params.Viewer)
-	params.Update(&queryStringParams)
-	idx := "everything"
-	if containsJapanese(params.Query) {
-		idx = "everything_ja"
-	}
-	basic := map[string]interface{}{
-		"simple_query_string": map[string]interface{}{
-			"query":            params.Query,
-			"fields":           []string{idx},
-			"flags":            "AND|NOT|OR|PHRASE|PRECEDENCE|WHITESPACE",
-			"default_operator": "and",
-			"lenient":          true,
-			"analyze_wildcard": false,
-		},
+	// an empty query string is handled by postSearchQuery; parsing would turn it in to "*"
+	if strings.TrimSpace(params.Query) != "" {
+		queryStringParams := ParsePostQuery(ctx, dir, params.Query, params.Viewer)
+		params.Update(&queryStringParams)
+	}
+	query, err := postSearchQuery(params)
+	if err != nil {
+		return nil, err
 	}
+
+	return doSearch(ctx, searcher, index, query)
+}
+
+// postSearchQuery builds the full post search request body from params. Any query string syntax should already have been parsed and merged in to params.
+func postSearchQuery(params *PostSearchParams) (map[string]interface{}, error) {
+	fields, err := params.queryFields()
+	if err != nil {
+		return nil, err
+	}
+	flags, err := params.queryFlags()
+	if err != nil {
+		return nil, err
+	}
+	sorts, err := params.Sorts()
+	if err != nil {
+		return nil, err
+	}
+	aggs, err := params.Aggregations()
+	if err != nil {
+		return nil, err
+	}
+	proximity, err := params.proximityClauses()
+	if err != nil {
+		return nil, err
+	}
+	operator, err := params.defaultOperator()
+	if err != nil {
+		return nil, err
+	}
+	if err := params.checkLangs(); err != nil {
+		return nil, err
+	}
+	// an empty simple_query_string matches nothing, which is rarely what the caller intended
+	var must []Clause
+	switch {
+	case strings.TrimSpace(params.Query) != "":
+		must = append(must, Clause{
+			"simple_query_string": map[string]interface{}{
+				"query":            params.Query,
+				"fields":           fields,
+				"flags":            flags,
+				"default_operator": operator,
+				"lenient":          params.lenient(),
+				"analyze_wildcard": false,
+			},
+		})
+	case len(proximity) > 0:
+		// the proximity phrases are the query
+	case params.MatchAllIfEmpty:
+		must = append(must, Clause{"match_all": map[string]interface{}{}})
+	default:
+		return nil, ErrEmptyQuery
+	}
+	must = append(must, proximity...)
 	filters := params.Filters()
 	// filter out future posts (TODO: temporary hack)
 	now := syntax.DatetimeNow()
@@ -247,44 +710,82 @@ func DoSearchPosts(ctx context.Context, dir identity.Directory, escli *es.Client
 			},
 		},
 	})
+	bq := Query{
+		Must:    must,
+		Filter:  filters,
+		MustNot: params.MustNots(),
+	}
 	query := map[string]interface{}{
-		"query": map[string]interface{}{
-			"bool": map[string]interface{}{
-				"must":   basic,
-				"filter": filters,
-			},
-		},
-		"sort": map[string]any{
-			"created_at": map[string]any{
-				"order": "desc",
-			},
-		},
-		"size": params.Size,
-		"from": params.Offset,
+		"query": bq.Build(),
+		"sort":  sorts,
+		"size":  params.Size,
+		"from":  params.Offset,
+	}
+
+	if collapse := params.Collapse(); collapse != nil {
+		query["collapse"] = collapse
+	}
+	if aggs != nil {
+		query["aggs"] = aggs
+	}
+	if params.Explain {
+		query["explain"] = true
+	}
+	if suggest := params.SuggestQuery(); suggest != nil {
+		query["suggest"] = suggest
 	}
 
-	return doSearch(ctx, escli, index, query)
+	return query, nil
 }
 
-func DoSearchProfiles(ctx context.Context, dir identity.Directory, escli *es.Client, index string, params *ActorSearchParams) (*EsSearchResponse, error) {
+func DoSearchProfiles(ctx context.Context, dir identity.Directory, searcher Searcher, index string, params *ActorSearchParams) (*EsSearchResponse, error) {
 	ctx, span := tracer.Start(ctx, "DoSearchProfiles")
 	defer span.End()
 
 	if err := checkParams(params.Offset, params.Size); err != nil {
 		return nil, err
 	}
+	if err := params.checkFields(); err != nil {
+		return nil, err
+	}
 
-	filters := params.Filters()
+	query := profileSearchQuery(params)
 
-	fulltext := map[string]interface{}{
-		"simple_query_string": map[string]interface{}{
-			"query":            params.Query,
-			"fields":           []string{"everything"},
-			"flags":            "AND|NOT|OR|PHRASE|PRECEDENCE|WHITESPACE",
-			"default_operator": "and",
-			"lenient":          true,
-			"analyze_wildcard": false,
-		},
+	return doSearch(ctx, searcher, index, query)
+}
+
+// profileSearchQuery builds the full profile search request body from params. Fields should already have been checked.
+func profileSearchQuery(params *ActorSearchParams) map[string]interface{} {
+	var fulltext Clause
+	if params.Fields != nil {
+		fields := make([]string, len(params.Fields))
+		for i, fb := range params.Fields {
+			fields[i] = fb.String()
+		}
+		fulltext = Clause{
+			"multi_match": map[string]interface{}{
+				"query":    params.Query,
+				"fields":   fields,
+				"type":     "best_fields",
+				"operator": "and",
+				"lenient":  true,
+			},
+		}
+	} else {
+		fields := []string{"everything"}
+		if params.BoostHandle {
+			fields = append(fields, "handle^2")
+		}
+		fulltext = Clause{
+			"simple_query_string": map[string]interface{}{
+				"query":            params.Query,
+				"fields":           fields,
+				"flags":            strings.Join(DefaultQueryFlags, "|"),
+				"default_operator": "and",
+				"lenient":          true,
+				"analyze_wildcard": false,
+			},
+		}
 	}
 	primary := fulltext
 
@@ -304,79 +805,120 @@ func DoSearchProfiles(ctx context.Context, dir identity.Directory, escli *es.Cli
 				},
 			},
 		}
-		primary = map[string]interface{}{
-			"bool": map[string]interface{}{
-				"should": []interface{}{
-					fulltext,
-					typeahead,
-				},
-			},
-		}
+		either := Query{Should: []Clause{fulltext, typeahead}}
+		primary = either.Build()
 	}
 
-	query := map[string]interface{}{
-		"query": map[string]interface{}{
-			"bool": map[string]interface{}{
-				"must": primary,
-				"should": []interface{}{
-					map[string]interface{}{"term": map[string]interface{}{"has_avatar": true}},
-					map[string]interface{}{"term": map[string]interface{}{"has_banner": true}},
-				},
-				"minimum_should_match": 0,
-				"boost":                0.5,
-			},
+	minShould := 0
+	boost := 0.5
+	bq := Query{
+		Must: []Clause{primary},
+		Should: []Clause{
+			{"term": map[string]interface{}{"has_avatar": true}},
+			{"term": map[string]interface{}{"has_banner": true}},
 		},
-		"size": params.Size,
-		"from": params.Offset,
+		Filter:             params.Filters(),
+		MinimumShouldMatch: &minShould,
+		Boost:              &boost,
 	}
 
-	if len(filters) > 0 {
-		query["query"].(map[string]interface{})["bool"].(map[string]interface{})["filter"] = filters
+	query := map[string]interface{}{
+		"query": bq.Build(),
+		"size":  params.Size,
+		"from":  params.Offset,
 	}
-
-	return doSearch(ctx, escli, index, query)
+	if params.SortField != "" {
+		query["sort"] = params.Sorts()
+	}
+	return query
 }
 
-func DoSearchProfilesTypeahead(ctx context.Context, escli *es.Client, index string, params *ActorSearchParams) (*EsSearchResponse, error) {
+func DoSearchProfilesTypeahead(ctx context.Context, searcher Searcher, index string, params *ActorSearchParams) (*EsSearchResponse, error) {
 	ctx, span := tracer.Start(ctx, "DoSearchProfilesTypeahead")
 	defer span.End()
 
 	if err := checkParams(0, params.Size); err != nil {
 		return nil, err
 	}
+	if params.RecencyBoost < 0 {
+		return nil, fmt.Errorf("negative recency boost")
+	}
 
-	filters := params.Filters()
+	query := profileTypeaheadQuery(params)
 
-	query := map[string]interface{}{
-		"query": map[string]interface{}{
-			"bool": map[string]interface{}{
-				"must": map[string]interface{}{
-					"multi_match": map[string]interface{}{
-						"query":    params.Query,
-						"type":     "bool_prefix",
-						"operator": "and",
-						"fields": []string{
-							"typeahead",
-							"typeahead._2gram",
-							"typeahead._3gram",
+	return doSearch(ctx, searcher, index, query)
+}
+
+// distance from "now" at which the typeahead recency signal has decayed to half
+const typeaheadRecencyScale = "30d"
+
+// profileTypeaheadQuery builds the full profile typeahead request body from params
+func profileTypeaheadQuery(params *ActorSearchParams) map[string]interface{} {
+	bq := Query{
+		Must: []Clause{{
+			"multi_match": map[string]interface{}{
+				"query":    params.Query,
+				"type":     "bool_prefix",
+				"operator": "and",
+				"fields": []string{
+					"typeahead",
+					"typeahead._2gram",
+					"typeahead._3gram",
+				},
+			},
+		}},
+		Filter: params.Filters(),
+	}
+	q := bq.Build()
+	if params.RecencyBoost > 0 {
+		// added to (not multiplied with) the text relevance score, so prefix matching still dominates
+		q = Clause{
+			"function_score": map[string]interface{}{
+				"query": q,
+				"functions": []Clause{{
+					"gauss": map[string]interface{}{
+						"doc_index_ts": map[string]interface{}{
+							"origin": "now",
+							"scale":  typeaheadRecencyScale,
+							"decay":  0.5,
 						},
 					},
-				},
+					"weight": params.RecencyBoost,
+				}},
+				"boost_mode": "sum",
 			},
-		},
-		"size": params.Size,
-		"from": params.Offset,
+		}
+	}
+	return map[string]interface{}{
+		"query": q,
+		"size":  params.Size,
+		"from":  params.Offset,
 	}
+}
+
+// DoTopProfiles returns the size profiles with the highest pagerank, without any text query, eg for "suggested follows". Profiles which have no pagerank are ranked last.
+func DoTopProfiles(ctx context.Context, searcher Searcher, index string, size int) (*EsSearchResponse, error) {
+	ctx, span := tracer.Start(ctx, "DoTopProfiles")
+	defer span.End()
 
-	if len(filters) > 0 {
-		query["query"].(map[string]interface{})["bool"].(map[string]interface{})["filter"] = filters
+	if err := checkParams(0, size); err != nil {
+		return nil, err
 	}
 
-	return doSearch(ctx, escli, index, query)
+	params := ActorSearchParams{SortField: "pagerank"}
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"match_all": map[string]interface{}{},
+		},
+		"sort": params.Sorts(),
+		"size": size,
+	}
+
+	return doSearch(ctx, searcher, index, query)
 }
 
 // helper to do a full-featured Lucene query parser (query_string) search, with all possible facets. Not safe to expose publicly.
-func DoSearchGeneric(ctx context.Context, escli *es.Client, index, q string) (*EsSearchResponse, error) {
+func DoSearchGeneric(ctx context.Context, searcher Searcher, index, q string) (*EsSearchResponse, error) {
 	ctx, span := tracer.Start(ctx, "DoSearchGeneric")
 	defer span.End()
 
@@ -393,27 +935,25 @@ func DoSearchGeneric(ctx context.Context, escli *es.Client, index, q string) (*E
 		},
 	}
 
-	return doSearch(ctx, escli, index, query)
+	return doSearch(ctx, searcher, index, query)
 }
 
-func doSearch(ctx context.Context, escli *es.Client, index string, query interface{}) (*EsSearchResponse, error) {
+func doSearch(ctx context.Context, searcher Searcher, index string, query interface{}) (*EsSearchResponse, error) {
 	ctx, span := tracer.Start(ctx, "doSearch")
 	defer span.End()
 
 	span.SetAttributes(attribute.String("index", index), attribute.String("query", fmt.Sprintf("%+v", query)))
 
+	logger := searcherLogger(searcher).With("index", index)
+
 	b, err := json.Marshal(query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to serialize query: %w", err)
 	}
-	slog.Info("sending query", "index", index, "query", string(b))
+	logger.Info("sending query", "query", string(b))
 
 	// Perform the search request.
-	res, err := escli.Search(
-		escli.Search.WithContext(ctx),
-		escli.Search.WithIndex(index),
-		escli.Search.WithBody(bytes.NewBuffer(b)),
-	)
+	res, err := searcher.Search(ctx, index, bytes.NewReader(b))
 	if err != nil {
 		return nil, fmt.Errorf("search query error: %w", err)
 	}
@@ -421,7 +961,7 @@ func doSearch(ctx context.Context, escli *es.Client, index string, query interfa
 	if res.IsError() {
 		raw, err := ioutil.ReadAll(res.Body)
 		if nil == err {
-			slog.Warn("search query error", "resp", string(raw), "status_code", res.StatusCode)
+			logger.Warn("search query error", "resp", string(raw), "status", res.StatusCode)
 		}
 		return nil, fmt.Errorf("search query error, code=%d", res.StatusCode)
 	}
@@ -430,6 +970,63 @@ func doSearch(ctx context.Context, escli *es.Client, index string, query interfa
 	if err := json.NewDecoder(res.Body).Decode(&out); err != nil {
 		return nil, fmt.Errorf("decoding search response: %w", err)
 	}
+	if raw, ok := out.Aggregations["timeline"]; ok {
+		timeline, err := parseTimeline(raw)
+		if err != nil {
+			return nil, err
+		}
+		out.Timeline = timeline
+	}
+	if raw, ok := out.Suggest["did_you_mean"]; ok {
+		suggestions, err := parseSuggestions(raw)
+		if err != nil {
+			return nil, err
+		}
+		out.Suggestions = suggestions
+	}
+	logger.Info("search query complete", "status", res.StatusCode, "took_ms", out.Took, "hit_count", len(out.Hits.Hits))
 
 	return &out, nil
 }
+
+// parseSuggestions decodes the options of a suggester result, in order, without duplicates
+func parseSuggestions(raw json.RawMessage) ([]string, error) {
+	var entries []struct {
+		Options []struct {
+			Text string `json:"text"`
+		} `json:"options"`
+	}
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return nil, fmt.Errorf("decoding search suggestions: %w", err)
+	}
+	suggestions := []string{}
+	for _, e := range entries {
+		for _, o := range e.Options {
+			if !slices.Contains(suggestions, o.Text) {
+				suggestions = append(suggestions, o.Text)
+			}
+		}
+	}
+	return suggestions, nil
+}
+
+// parseTimeline decodes the buckets of a date_histogram aggregation result
+func parseTimeline(raw json.RawMessage) ([]TimeBucket, error) {
+	var agg struct {
+		Buckets []struct {
+			Key      int64 `json:"key"`
+			DocCount int   `json:"doc_count"`
+		} `json:"buckets"`
+	}
+	if err := json.Unmarshal(raw, &agg); err != nil {
+		return nil, fmt.Errorf("decoding timeline aggregation: %w", err)
+	}
+	timeline := make([]TimeBucket, len(agg.Buckets))
+	for i, b := range agg.Buckets {
+		timeline[i] = TimeBucket{
+			Time:  time.UnixMilli(b.Key).UTC(),
+			Count: b.DocCount,
+		}
+	}
+	return timeline, nil
+}
@@ -0,0 +1,811 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+
+	es "github.com/opensearch-project/opensearch-go/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// captureHandler is a slog.Handler which records all log records, for inspection in tests
+type captureHandler struct {
+	lk      *sync.Mutex
+	attrs   []slog.Attr
+	records *[]slog.Record
+}
+
+func newCaptureHandler() *captureHandler {
+	return &captureHandler{
+		lk:      &sync.Mutex{},
+		records: &[]slog.Record{},
+	}
+}
+
+func (h *captureHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *captureHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.lk.Lock()
+	defer h.lk.Unlock()
+	r = r.Clone()
+	r.AddAttrs(h.attrs...)
+	*h.records = append(*h.records, r)
+	return nil
+}
+
+func (h *captureHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &captureHandler{
+		lk:      h.lk,
+		attrs:   append(append([]slog.Attr{}, h.attrs...), attrs...),
+		records: h.records,
+	}
+}
+
+func (h *captureHandler) WithGroup(name string) slog.Handler { return h }
+
+func (h *captureHandler) find(msg string) (map[string]any, bool) {
+	h.lk.Lock()
+	defer h.lk.Unlock()
+	for _, r := range *h.records {
+		if r.Message != msg {
+			continue
+		}
+		attrs := make(map[string]any)
+		r.Attrs(func(a slog.Attr) bool {
+			attrs[a.Key] = a.Value.Any()
+			return true
+		})
+		return attrs, true
+	}
+	return nil, false
+}
+
+// testMockEsClient returns an opensearch client talking to a local HTTP server which responds to every request with the given status and body
+func testMockEsClient(t *testing.T, status int, body string) *es.Client {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+
+	escli, err := es.NewClient(es.Config{Addresses: []string{srv.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return escli
+}
+
+func mustPostSearchQuery(t *testing.T, params *PostSearchParams) map[string]interface{} {
+	t.Helper()
+	query, err := postSearchQuery(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return query
+}
+
+// returns the "simple_query_string" clause from a post search query body
+func simpleQueryString(t *testing.T, query map[string]interface{}) map[string]interface{} {
+	t.Helper()
+	must := query["query"].(map[string]interface{})["bool"].(map[string]interface{})["must"].(map[string]interface{})
+	sqs, ok := must["simple_query_string"].(map[string]interface{})
+	if !ok {
+		t.Fatal("no simple_query_string in query")
+	}
+	return sqs
+}
+
+func TestPostSearchQueryCollapse(t *testing.T) {
+	assert := assert.New(t)
+
+	params := PostSearchParams{Query: "hello", Size: 10}
+	query := mustPostSearchQuery(t, &params)
+	_, ok := query["collapse"]
+	assert.False(ok)
+
+	params.CollapseField = "did"
+	query = mustPostSearchQuery(t, &params)
+	collapse, ok := query["collapse"].(map[string]interface{})
+	assert.True(ok)
+	assert.Equal("did", collapse["field"])
+	_, ok = collapse["inner_hits"]
+	assert.False(ok)
+
+	params.CollapseMax = 3
+	query = mustPostSearchQuery(t, &params)
+	collapse, ok = query["collapse"].(map[string]interface{})
+	assert.True(ok)
+	assert.Equal("did", collapse["field"])
+	inner, ok := collapse["inner_hits"].(map[string]interface{})
+	assert.True(ok)
+	assert.Equal(3, inner["size"])
+}
+
+func TestSearchLogging(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	handler := newCaptureHandler()
+	logger := slog.New(handler)
+
+	escli := testMockEsClient(t, 200, `{"took": 7, "timed_out": false, "hits": {"hits": [{"_id": "a"}, {"_id": "b"}]}}`)
+	res, err := doSearch(ctx, NewLoggingSearcher(NewClientSearcher(escli), logger), "test_index", map[string]any{"query": map[string]any{"match_all": map[string]any{}}})
+	assert.NoError(err)
+	assert.Equal(2, len(res.Hits.Hits))
+
+	attrs, ok := handler.find("search query complete")
+	assert.True(ok)
+	assert.Equal("test_index", attrs["index"])
+	assert.Equal(int64(7), attrs["took_ms"])
+	assert.Equal(int64(2), attrs["hit_count"])
+	assert.Equal(int64(200), attrs["status"])
+
+	// the logger is still found under other wrappers
+	escli = testMockEsClient(t, 400, `{"error": "bad query"}`)
+	_, err = doSearch(ctx, NewCachingSearcher(NewLoggingSearcher(NewClientSearcher(escli), logger), time.Minute, 10), "test_index", map[string]any{})
+	assert.Error(err)
+
+	attrs, ok = handler.find("search query error")
+	assert.True(ok)
+	assert.Equal("test_index", attrs["index"])
+	assert.Equal(int64(400), attrs["status"])
+
+	// servers log queries to their configured logger
+	srv, err := NewServer(escli, nil, ServerConfig{Logger: logger, QueryCacheTTL: time.Minute})
+	assert.NoError(err)
+	assert.Same(logger, searcherLogger(srv.searcher))
+	assert.Same(slog.Default(), searcherLogger(NewClientSearcher(escli)))
+}
+
+func TestPostSearchQueryFields(t *testing.T) {
+	assert := assert.New(t)
+
+	params := PostSearchParams{Query: "hello"}
+	assert.Equal([]string{"everything"}, simpleQueryString(t, mustPostSearchQuery(t, &params))["fields"])
+
+	params = PostSearchParams{Query: "パリ"}
+	assert.Equal([]string{"everything_ja"}, simpleQueryString(t, mustPostSearchQuery(t, &params))["fields"])
+
+	params = PostSearchParams{Query: "hello", Fields: []string{"text^2", "alt_text"}}
+	assert.Equal([]string{"text^2", "alt_text"}, simpleQueryString(t, mustPostSearchQuery(t, &params))["fields"])
+
+	params = PostSearchParams{Query: "hello", Fields: []string{}}
+	_, err := postSearchQuery(&params)
+	assert.Error(err)
+
+	params = PostSearchParams{Query: "hello", Fields: []string{"text", " "}}
+	_, err = postSearchQuery(&params)
+	assert.Error(err)
+}
+
+// returns the "simple_query_string" clause from a profile search query body
+func profileSimpleQueryString(t *testing.T, query map[string]interface{}) map[string]interface{} {
+	t.Helper()
+	must := query["query"].(map[string]interface{})["bool"].(map[string]interface{})["must"].(map[string]interface{})
+	if inner, ok := must["bool"].(map[string]interface{}); ok {
+		// single-token queries are an OR of fulltext and typeahead
+		must = inner["should"].([]Clause)[0]
+	}
+	sqs, ok := must["simple_query_string"].(map[string]interface{})
+	if !ok {
+		t.Fatal("no simple_query_string in query")
+	}
+	return sqs
+}
+
+func TestPostSearchQueryFlags(t *testing.T) {
+	assert := assert.New(t)
+
+	params := PostSearchParams{Query: "hello"}
+	assert.Equal("AND|NOT|OR|PHRASE|PRECEDENCE|WHITESPACE", simpleQueryString(t, mustPostSearchQuery(t, &params))["flags"])
+
+	params.QueryFlags = []string{"AND", "NOT", "OR", "PHRASE", "WHITESPACE"}
+	assert.Equal("AND|NOT|OR|PHRASE|WHITESPACE", simpleQueryString(t, mustPostSearchQuery(t, &params))["flags"])
+
+	params.QueryFlags = []string{"and", " fuzzy"}
+	assert.Equal("AND|FUZZY", simpleQueryString(t, mustPostSearchQuery(t, &params))["flags"])
+
+	params.QueryFlags = []string{"NONE"}
+	assert.Equal("NONE", simpleQueryString(t, mustPostSearchQuery(t, &params))["flags"])
+
+	params.QueryFlags = []string{"AND", "REGEXP"}
+	_, err := postSearchQuery(&params)
+	assert.ErrorContains(err, "REGEXP")
+
+	params.QueryFlags = []string{}
+	_, err = postSearchQuery(&params)
+	assert.Error(err)
+}
+
+func TestPostSearchDefaultOperator(t *testing.T) {
+	assert := assert.New(t)
+
+	params := PostSearchParams{Query: "hello world"}
+	assert.Equal("and", simpleQueryString(t, mustPostSearchQuery(t, &params))["default_operator"])
+
+	params.DefaultOperator = "or"
+	assert.Equal("or", simpleQueryString(t, mustPostSearchQuery(t, &params))["default_operator"])
+
+	params.DefaultOperator = "AND"
+	assert.Equal("and", simpleQueryString(t, mustPostSearchQuery(t, &params))["default_operator"])
+
+	params.DefaultOperator = "xor"
+	_, err := postSearchQuery(&params)
+	assert.ErrorContains(err, "xor")
+}
+
+func TestPostSearchProximityPhrases(t *testing.T) {
+	assert := assert.New(t)
+
+	params := PostSearchParams{
+		Query: "hello",
+		ProximityPhrases: []ProximityClause{
+			{Phrase: "climate change", Slop: 5},
+			{Phrase: "sea level", Slop: 0},
+		},
+	}
+	must := mustPostSearchQuery(t, &params)["query"].(map[string]interface{})["bool"].(map[string]interface{})["must"].([]Clause)
+	assert.Len(must, 3)
+	assert.Contains(must[0], "simple_query_string")
+	assert.Equal(Clause{
+		"match_phrase": map[string]interface{}{
+			"text": map[string]interface{}{"query": "climate change", "slop": 5},
+		},
+	}, must[1])
+	assert.Equal(Clause{
+		"match_phrase": map[string]interface{}{
+			"text": map[string]interface{}{"query": "sea level", "slop": 0},
+		},
+	}, must[2])
+
+	params.ProximityPhrases = []ProximityClause{{Phrase: "climate change", Slop: -1}}
+	_, err := postSearchQuery(&params)
+	assert.ErrorContains(err, "negative slop")
+
+	params.ProximityPhrases = []ProximityClause{{Phrase: " ", Slop: 2}}
+	_, err = postSearchQuery(&params)
+	assert.Error(err)
+}
+
+func TestPostSearchQuotesURI(t *testing.T) {
+	assert := assert.New(t)
+
+	quoted := "at://did:plc:abc123/app.bsky.feed.post/3jzfcijpj2z2a"
+	params := PostSearchParams{Query: "hello", QuotesURI: quoted}
+	assert.Equal([]map[string]interface{}{
+		{"term": map[string]interface{}{"embed_aturi": map[string]interface{}{"value": quoted, "case_insensitive": true}}},
+	}, params.Filters())
+
+	// combined with other filters
+	author := syntax.DID("did:plc:def456")
+	params.Author = &author
+	params.Tags = []string{"art"}
+	filters := params.Filters()
+	assert.Len(filters, 3)
+	assert.Contains(filters, map[string]interface{}{
+		"term": map[string]interface{}{"embed_aturi": map[string]interface{}{"value": quoted, "case_insensitive": true}},
+	})
+
+	// the filter field matches the indexed field name for quoted records
+	var doc PostDoc
+	doc.EmbedATURI = &quoted
+	b, err := json.Marshal(doc)
+	assert.NoError(err)
+	assert.Contains(string(b), `"embed_aturi":"`+quoted+`"`)
+	assert.Contains(palomarPostSchemaJSON, `"embed_aturi"`)
+}
+
+func TestPostSearchExcludeLangs(t *testing.T) {
+	assert := assert.New(t)
+
+	boolQuery := func(params *PostSearchParams) map[string]interface{} {
+		return mustPostSearchQuery(t, params)["query"].(map[string]interface{})["bool"].(map[string]interface{})
+	}
+
+	params := PostSearchParams{Query: "hello"}
+	assert.Nil(params.MustNots())
+	assert.NotContains(boolQuery(&params), "must_not")
+
+	params.ExcludeLangs = []string{"ja", "pt-BR", "DE"}
+	expected := []map[string]interface{}{
+		{"terms": map[string]interface{}{"lang_code_iso2": []string{"ja", "pt", "de"}}},
+	}
+	assert.Equal(expected, params.MustNots())
+	assert.Equal(expected, boolQuery(&params)["must_not"])
+
+	// combined with an included language: inclusion is a filter, exclusion a must_not
+	en := syntax.Language("en")
+	params.Lang = &en
+	bq := boolQuery(&params)
+	assert.Equal(expected, bq["must_not"])
+	assert.Contains(bq["filter"], map[string]interface{}{
+		"term": map[string]interface{}{"lang_code_iso2": map[string]interface{}{"value": "en", "case_insensitive": true}},
+	})
+
+	// excluding the included language can never match
+	params.ExcludeLangs = []string{"en-GB"}
+	_, err := postSearchQuery(&params)
+	assert.ErrorContains(err, "both included and excluded")
+
+	// carried over by Update
+	var merged PostSearchParams
+	merged.Update(&PostSearchParams{ExcludeLangs: []string{"ja"}})
+	assert.Equal([]string{"ja"}, merged.ExcludeLangs)
+}
+
+func TestPostSearchTagsMatchAny(t *testing.T) {
+	assert := assert.New(t)
+
+	tagTerm := func(tag string) map[string]interface{} {
+		return map[string]interface{}{"term": map[string]interface{}{"tag": map[string]interface{}{"value": tag, "case_insensitive": true}}}
+	}
+
+	// all tags required by default: one filter per tag
+	params := PostSearchParams{Query: "hello", Tags: []string{"art", "photography"}}
+	assert.Equal([]map[string]interface{}{tagTerm("art"), tagTerm("photography")}, params.Filters())
+
+	// any tag: a single bool "should" filter
+	params.TagsMatchAny = true
+	assert.Equal([]map[string]interface{}{
+		{"bool": map[string]interface{}{
+			"should":               []Clause{tagTerm("art"), tagTerm("photography")},
+			"minimum_should_match": 1,
+		}},
+	}, params.Filters())
+
+	// a single tag is the same either way
+	params.Tags = []string{"art"}
+	assert.Equal([]map[string]interface{}{tagTerm("art")}, params.Filters())
+
+	// the flag is carried over with the tags
+	var merged PostSearchParams
+	merged.Update(&PostSearchParams{Tags: []string{"a", "b"}, TagsMatchAny: true})
+	assert.True(merged.TagsMatchAny)
+}
+
+func TestPostSearchLenient(t *testing.T) {
+	assert := assert.New(t)
+
+	params := PostSearchParams{Query: "hello"}
+	assert.Equal(true, simpleQueryString(t, mustPostSearchQuery(t, &params))["lenient"])
+
+	strict := false
+	params.Lenient = &strict
+	assert.Equal(false, simpleQueryString(t, mustPostSearchQuery(t, &params))["lenient"])
+
+	lenient := true
+	params.Lenient = &lenient
+	assert.Equal(true, simpleQueryString(t, mustPostSearchQuery(t, &params))["lenient"])
+}
+
+func TestPostSearchEmptyQuery(t *testing.T) {
+	assert := assert.New(t)
+
+	boolQuery := func(params *PostSearchParams) map[string]interface{} {
+		return mustPostSearchQuery(t, params)["query"].(map[string]interface{})["bool"].(map[string]interface{})
+	}
+
+	// empty query string is an error by default
+	for _, q := range []string{"", "  "} {
+		params := PostSearchParams{Query: q}
+		_, err := postSearchQuery(&params)
+		assert.ErrorIs(err, ErrEmptyQuery)
+	}
+
+	// or matches all posts, still subject to filters
+	author := syntax.DID("did:plc:abc123")
+	params := PostSearchParams{Author: &author, MatchAllIfEmpty: true}
+	bq := boolQuery(&params)
+	assert.Equal(Clause{"match_all": map[string]interface{}{}}, bq["must"])
+	assert.Contains(bq["filter"], params.Filters()[0])
+
+	// proximity phrases alone are enough of a query
+	params = PostSearchParams{ProximityPhrases: []ProximityClause{{Phrase: "sea level", Slop: 1}}}
+	assert.Contains(boolQuery(&params)["must"], "match_phrase")
+
+	// a non-empty query string is unaffected by the option
+	params = PostSearchParams{Query: "hello", MatchAllIfEmpty: true}
+	assert.Equal("hello", simpleQueryString(t, mustPostSearchQuery(t, &params))["query"])
+}
+
+func TestPostSearchAltTextOnly(t *testing.T) {
+	assert := assert.New(t)
+
+	altClause := map[string]interface{}{"term": map[string]interface{}{"has_alt_text": true}}
+
+	params := PostSearchParams{Query: "hello"}
+	assert.NotContains(params.Filters(), altClause)
+
+	params.AltTextOnly = true
+	assert.Equal([]map[string]interface{}{altClause}, params.Filters())
+
+	// combined with other filters
+	params.Tags = []string{"art"}
+	params.Domain = "example.com"
+	filters := params.Filters()
+	assert.Len(filters, 3)
+	assert.Contains(filters, altClause)
+
+	// carried over by Update, like other filters
+	var merged PostSearchParams
+	merged.Update(&params)
+	assert.True(merged.AltTextOnly)
+
+	assert.Contains(palomarPostSchemaJSON, `"has_alt_text"`)
+}
+
+func TestPostSearchFiltersUTC(t *testing.T) {
+	assert := assert.New(t)
+
+	since := syntax.Datetime("2024-01-02T03:04:05+02:00")
+	until := syntax.Datetime("2024-02-01T00:00:00Z")
+	params := PostSearchParams{Query: "hello", Since: &since, Until: &until}
+	assert.Equal([]map[string]interface{}{
+		{"range": map[string]interface{}{"created_at": map[string]interface{}{"gte": "2024-01-02T01:04:05Z"}}},
+		{"range": map[string]interface{}{"created_at": map[string]interface{}{"lt": "2024-02-01T00:00:00Z"}}},
+	}, params.Filters())
+}
+
+func TestPostSearchRelativeWindow(t *testing.T) {
+	assert := assert.New(t)
+
+	// returns the created_at lower bound from the filters, if any
+	lowerBound := func(params *PostSearchParams) (time.Time, bool) {
+		for _, f := range params.Filters() {
+			rng, ok := f["range"].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if gte, ok := rng["created_at"].(map[string]interface{})["gte"]; ok {
+				ts, err := syntax.ParseDatetimeTime(gte.(string))
+				if err != nil {
+					t.Fatal(err)
+				}
+				return ts, true
+			}
+		}
+		return time.Time{}, false
+	}
+
+	params := PostSearchParams{Query: "hello"}
+	_, ok := lowerBound(&params)
+	assert.False(ok)
+
+	params.RelativeWindow = 7 * 24 * time.Hour
+	bound, ok := lowerBound(&params)
+	assert.True(ok)
+	assert.WithinDuration(time.Now().Add(-7*24*time.Hour), bound, 5*time.Second)
+
+	// explicit since wins over the relative window
+	since := syntax.Datetime("2024-01-02T03:04:05Z")
+	params.Since = &since
+	bound, ok = lowerBound(&params)
+	assert.True(ok)
+	assert.Equal(since.Time(), bound)
+	assert.Len(params.Filters(), 1)
+
+	// an explicit until still applies alongside the window
+	until := syntax.Datetime("2024-02-01T00:00:00Z")
+	params = PostSearchParams{Query: "hello", RelativeWindow: time.Hour, Until: &until}
+	bound, ok = lowerBound(&params)
+	assert.True(ok)
+	assert.WithinDuration(time.Now().Add(-time.Hour), bound, 5*time.Second)
+	assert.Len(params.Filters(), 2)
+
+	params = PostSearchParams{Query: "hello", RelativeWindow: -time.Hour}
+	_, ok = lowerBound(&params)
+	assert.False(ok)
+}
+
+func TestProfileSearchQueryBoostHandle(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, q := range []string{"alice", "alice smith"} {
+		params := ActorSearchParams{Query: q}
+		assert.Equal([]string{"everything"}, profileSimpleQueryString(t, profileSearchQuery(&params))["fields"])
+
+		params.BoostHandle = true
+		assert.Equal([]string{"everything", "handle^2"}, profileSimpleQueryString(t, profileSearchQuery(&params))["fields"])
+	}
+}
+
+func TestProfileSearchQueryFields(t *testing.T) {
+	assert := assert.New(t)
+
+	fields := []FieldBoost{
+		{Field: "handle", Boost: 3},
+		{Field: "display_name", Boost: 2},
+		{Field: "description"},
+		{Field: "img_alt_text", Boost: 0.5},
+	}
+	assert.Equal("handle^3", fields[0].String())
+	assert.Equal("description", fields[2].String())
+
+	for _, q := range []string{"alice", "alice smith"} {
+		params := ActorSearchParams{Query: q, Fields: fields, BoostHandle: true}
+		assert.NoError(params.checkFields())
+		must := profileSearchQuery(&params)["query"].(map[string]interface{})["bool"].(map[string]interface{})["must"].(map[string]interface{})
+		if inner, ok := must["bool"].(map[string]interface{}); ok {
+			// single-token queries are still an OR with the typeahead query
+			must = inner["should"].([]Clause)[0]
+		}
+		assert.NotContains(must, "simple_query_string")
+		assert.Equal(map[string]interface{}{
+			"query":    q,
+			"fields":   []string{"handle^3", "display_name^2", "description", "img_alt_text^0.5"},
+			"type":     "best_fields",
+			"operator": "and",
+			"lenient":  true,
+		}, must["multi_match"])
+	}
+
+	// invalid field lists
+	for _, bad := range [][]FieldBoost{{}, {{Field: " "}}, {{Field: "handle", Boost: -1}}} {
+		params := ActorSearchParams{Query: "alice", Fields: bad}
+		assert.Error(params.checkFields())
+		_, err := DoSearchProfiles(context.Background(), nil, &fakeSearcher{}, "palomar_profile", &params)
+		assert.Error(err)
+	}
+}
+
+func TestProfileTypeaheadRecencyBoost(t *testing.T) {
+	assert := assert.New(t)
+
+	// off by default: a plain bool query
+	params := ActorSearchParams{Query: "ali", Size: 5}
+	query := profileTypeaheadQuery(&params)["query"].(Clause)
+	assert.Contains(query, "bool")
+	assert.NotContains(query, "function_score")
+
+	params.RecencyBoost = 0.2
+	fs, ok := profileTypeaheadQuery(&params)["query"].(Clause)["function_score"].(map[string]interface{})
+	if !assert.True(ok) {
+		return
+	}
+	// the wrapped query is unchanged
+	params.RecencyBoost = 0
+	assert.Equal(profileTypeaheadQuery(&params)["query"], fs["query"])
+	assert.Equal("sum", fs["boost_mode"])
+	assert.Equal([]Clause{{
+		"gauss": map[string]interface{}{
+			"doc_index_ts": map[string]interface{}{
+				"origin": "now",
+				"scale":  "30d",
+				"decay":  0.5,
+			},
+		},
+		"weight": 0.2,
+	}}, fs["functions"])
+
+	params.RecencyBoost = -1
+	_, err := DoSearchProfilesTypeahead(context.Background(), &fakeSearcher{}, "palomar_profile", &params)
+	assert.Error(err)
+}
+
+func TestProfileSearchQuerySort(t *testing.T) {
+	assert := assert.New(t)
+
+	// relevance order by default, without an explicit sort
+	params := ActorSearchParams{Query: "alice"}
+	_, ok := profileSearchQuery(&params)["sort"]
+	assert.False(ok)
+	assert.Equal([]map[string]interface{}{
+		{"_score": map[string]interface{}{"order": "desc"}},
+	}, params.Sorts())
+
+	params.SortField = "pagerank"
+	assert.Equal([]map[string]interface{}{
+		{"pagerank": map[string]interface{}{
+			"order":         "desc",
+			"missing":       "_last",
+			"unmapped_type": "float",
+		}},
+		{"_score": map[string]interface{}{"order": "desc"}},
+	}, profileSearchQuery(&params)["sort"])
+
+	params.SortField = "_score"
+	assert.Equal(params.Sorts(), profileSearchQuery(&params)["sort"])
+	assert.Len(params.Sorts(), 1)
+}
+
+func TestPostSearchQuerySortMode(t *testing.T) {
+	assert := assert.New(t)
+
+	recent := []map[string]interface{}{
+		{"created_at": map[string]interface{}{"order": "desc"}},
+		{"_id": map[string]interface{}{"order": "asc"}},
+	}
+	relevance := []map[string]interface{}{
+		{"_score": map[string]interface{}{"order": "desc"}},
+		{"created_at": map[string]interface{}{"order": "desc"}},
+		{"_id": map[string]interface{}{"order": "asc"}},
+	}
+
+	params := PostSearchParams{Query: "hello"}
+	assert.Equal(recent, mustPostSearchQuery(t, &params)["sort"])
+
+	params.SortMode = SortRecent
+	assert.Equal(recent, mustPostSearchQuery(t, &params)["sort"])
+
+	params.SortMode = SortRelevance
+	assert.Equal(relevance, mustPostSearchQuery(t, &params)["sort"])
+
+	// API-style sort values don't change the ordering; relevance must be requested explicitly
+	params = PostSearchParams{Query: "hello", Sort: "top"}
+	assert.Equal(recent, mustPostSearchQuery(t, &params)["sort"])
+	params = PostSearchParams{Query: "hello", Sort: "latest"}
+	assert.Equal(recent, mustPostSearchQuery(t, &params)["sort"])
+	params = PostSearchParams{Query: "hello", Sort: "top", SortMode: SortRelevance}
+	assert.Equal(relevance, mustPostSearchQuery(t, &params)["sort"])
+
+	params = PostSearchParams{Query: "hello", SortMode: "random"}
+	_, err := postSearchQuery(&params)
+	assert.Error(err)
+}
+
+func TestPostSearchSortTiebreaker(t *testing.T) {
+	assert := assert.New(t)
+
+	tiebreaker := map[string]interface{}{"_id": map[string]interface{}{"order": "asc"}}
+	for _, mode := range []SortMode{"", SortRecent, SortRelevance} {
+		params := PostSearchParams{Query: "hello", SortMode: mode}
+		sorts, err := params.Sorts()
+		assert.NoError(err)
+		// the unique document ID is always the final sort key
+		if assert.NotEmpty(sorts) {
+			assert.Equal(tiebreaker, sorts[len(sorts)-1], mode)
+		}
+		assert.Equal(sorts, mustPostSearchQuery(t, &params)["sort"])
+	}
+}
+
+func TestPostSearchQueryHistogram(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	params := PostSearchParams{Query: "hello"}
+	_, ok := mustPostSearchQuery(t, &params)["aggs"]
+	assert.False(ok)
+
+	for _, interval := range []string{"day", "hour"} {
+		author := syntax.DID("did:plc:abc123")
+		params = PostSearchParams{Query: "hello", Histogram: interval, Author: &author}
+		query := mustPostSearchQuery(t, &params)
+		aggs, ok := query["aggs"].(map[string]interface{})
+		assert.True(ok)
+		hist := aggs["timeline"].(map[string]interface{})["date_histogram"].(map[string]interface{})
+		assert.Equal("created_at", hist["field"])
+		assert.Equal(interval, hist["calendar_interval"])
+
+		// aggregations run over the filtered query, so the filters must still be present
+		filters := query["query"].(map[string]interface{})["bool"].(map[string]interface{})["filter"].([]map[string]interface{})
+		assert.Equal(params.Filters()[0], filters[0])
+	}
+
+	params = PostSearchParams{Query: "hello", Histogram: "fortnight"}
+	_, err := postSearchQuery(&params)
+	assert.Error(err)
+
+	escli := testMockEsClient(t, 200, `{"took": 3, "hits": {"hits": []}, "aggregations": {"timeline": {"buckets": [
+		{"key_as_string": "2024-01-01T00:00:00.000Z", "key": 1704067200000, "doc_count": 4},
+		{"key_as_string": "2024-01-02T00:00:00.000Z", "key": 1704153600000, "doc_count": 0}
+	]}}}`)
+	res, err := doSearch(ctx, NewClientSearcher(escli), "test_index", map[string]any{})
+	assert.NoError(err)
+	assert.Equal([]TimeBucket{
+		{Time: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Count: 4},
+		{Time: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), Count: 0},
+	}, res.Timeline)
+}
+
+func TestPostSearchSuggest(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	// off by default
+	params := PostSearchParams{Query: "helo wrld"}
+	_, ok := mustPostSearchQuery(t, &params)["suggest"]
+	assert.False(ok)
+
+	params.Suggest = true
+	assert.Equal(map[string]interface{}{
+		"did_you_mean": map[string]interface{}{
+			"text": "helo wrld",
+			"phrase": map[string]interface{}{
+				"field": "text",
+				"size":  3,
+			},
+		},
+	}, mustPostSearchQuery(t, &params)["suggest"])
+
+	// nothing to suggest for a match-everything query
+	params = PostSearchParams{Query: "*", Suggest: true}
+	_, ok = mustPostSearchQuery(t, &params)["suggest"]
+	assert.False(ok)
+
+	escli := testMockEsClient(t, 200, `{"took": 3, "hits": {"hits": []}, "suggest": {"did_you_mean": [
+		{"text": "helo wrld", "offset": 0, "length": 9, "options": [
+			{"text": "hello world", "score": 0.5},
+			{"text": "help world", "score": 0.2},
+			{"text": "hello world", "score": 0.1}
+		]}
+	]}}`)
+	res, err := doSearch(ctx, NewClientSearcher(escli), "test_index", map[string]any{})
+	assert.NoError(err)
+	assert.Equal([]string{"hello world", "help world"}, res.Suggestions)
+
+	// no suggester in the response
+	escli = testMockEsClient(t, 200, `{"took": 3, "hits": {"hits": []}}`)
+	res, err = doSearch(ctx, NewClientSearcher(escli), "test_index", map[string]any{})
+	assert.NoError(err)
+	assert.Nil(res.Suggestions)
+}
+
+func TestPostSearchQueryExplain(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	params := PostSearchParams{Query: "hello"}
+	_, ok := mustPostSearchQuery(t, &params)["explain"]
+	assert.False(ok)
+
+	params.Explain = true
+	assert.Equal(true, mustPostSearchQuery(t, &params)["explain"])
+
+	escli := testMockEsClient(t, 200, `{"took": 3, "hits": {"hits": [
+		{"_id": "a", "_explanation": {"value": 1.5, "description": "weight(text:hello)", "details": []}},
+		{"_id": "b"}
+	]}}`)
+	res, err := doSearch(ctx, NewClientSearcher(escli), "test_index", map[string]any{})
+	assert.NoError(err)
+	assert.JSONEq(`{"value": 1.5, "description": "weight(text:hello)", "details": []}`, string(res.Hits.Hits[0].Explanation))
+	assert.Nil(res.Hits.Hits[1].Explanation)
+}
+
+func TestQueryBuildMatchesExistingJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	author := syntax.DID("did:plc:abc123")
+	lang := syntax.Language("ja")
+	params := PostSearchParams{Query: "hello world", Author: &author, Lang: &lang, Tags: []string{"art"}, Size: 25, Offset: 50}
+	query := mustPostSearchQuery(t, &params)
+	// pin the "future posts" cutoff, which is otherwise the current time
+	filters := query["query"].(map[string]interface{})["bool"].(map[string]interface{})["filter"].([]map[string]interface{})
+	filters[len(filters)-1]["range"].(map[string]interface{})["created_at"].(map[string]interface{})["lte"] = "2024-01-01T00:00:00.000Z"
+	b, err := json.Marshal(query)
+	assert.NoError(err)
+	assert.JSONEq(`{"from":50,"query":{"bool":{"filter":[{"term":{"did":{"case_insensitive":true,"value":"did:plc:abc123"}}},{"term":{"lang_code_iso2":{"case_insensitive":true,"value":"ja"}}},{"term":{"tag":{"case_insensitive":true,"value":"art"}}},{"range":{"created_at":{"lte":"2024-01-01T00:00:00.000Z"}}}],"must":{"simple_query_string":{"analyze_wildcard":false,"default_operator":"and","fields":["everything"],"flags":"AND|NOT|OR|PHRASE|PRECEDENCE|WHITESPACE","lenient":true,"query":"hello world"}}}},"size":25,"sort":[{"created_at":{"order":"desc"}},{"_id":{"order":"asc"}}]}`, string(b))
+
+	b, err = json.Marshal(profileSearchQuery(&ActorSearchParams{Query: "alice", Size: 10}))
+	assert.NoError(err)
+	assert.JSONEq(`{"from":0,"query":{"bool":{"boost":0.5,"minimum_should_match":0,"must":{"bool":{"should":[{"simple_query_string":{"analyze_wildcard":false,"default_operator":"and","fields":["everything"],"flags":"AND|NOT|OR|PHRASE|PRECEDENCE|WHITESPACE","lenient":true,"query":"alice"}},{"multi_match":{"fields":["typeahead","typeahead._2gram","typeahead._3gram"],"operator":"and","query":"alice","type":"bool_prefix"}}]}},"should":[{"term":{"has_avatar":true}},{"term":{"has_banner":true}}]}},"size":10}`, string(b))
+
+	b, err = json.Marshal(profileSearchQuery(&ActorSearchParams{Query: "alice smith", Follows: []syntax.DID{author}, Size: 10}))
+	assert.NoError(err)
+	assert.JSONEq(`{"from":0,"query":{"bool":{"boost":0.5,"filter":[{"terms":{"did":["did:plc:abc123"]}}],"minimum_should_match":0,"must":{"simple_query_string":{"analyze_wildcard":false,"default_operator":"and","fields":["everything"],"flags":"AND|NOT|OR|PHRASE|PRECEDENCE|WHITESPACE","lenient":true,"query":"alice smith"}},"should":[{"term":{"has_avatar":true}},{"term":{"has_banner":true}}]}},"size":10}`, string(b))
+}
+
+func TestQueryBuild(t *testing.T) {
+	assert := assert.New(t)
+
+	a := Clause{"term": map[string]interface{}{"a": 1}}
+	b := Clause{"term": map[string]interface{}{"b": 2}}
+
+	q := Query{}
+	assert.Equal(Clause{"bool": map[string]interface{}{}}, q.Build())
+
+	q = Query{Must: []Clause{a, b}, MustNot: []Clause{b}, Filter: []Clause{}}
+	assert.Equal(Clause{"bool": map[string]interface{}{
+		"must":     []Clause{a, b},
+		"must_not": []Clause{b},
+	}}, q.Build())
+}
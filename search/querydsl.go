@@ -0,0 +1,46 @@
+package search
+
+// Clause is a single elasticsearch/opensearch query DSL clause, such as {"term": {...}}
+type Clause = map[string]interface{}
+
+// Query is a compound "bool" query, assembled from clauses. Build turns it in to query DSL.
+type Query struct {
+	Must    []Clause
+	Should  []Clause
+	Filter  []Clause
+	MustNot []Clause
+
+	// Optional bool query parameters; omitted when nil
+	MinimumShouldMatch *int
+	Boost              *float64
+}
+
+// Build returns the query DSL for this query, as a {"bool": {...}} clause.
+//
+// Empty clause lists are omitted. A single Must clause is emitted as an object instead of a list, which is equivalent.
+func (q *Query) Build() Clause {
+	b := map[string]interface{}{}
+	switch len(q.Must) {
+	case 0:
+	case 1:
+		b["must"] = q.Must[0]
+	default:
+		b["must"] = q.Must
+	}
+	if len(q.Should) > 0 {
+		b["should"] = q.Should
+	}
+	if len(q.Filter) > 0 {
+		b["filter"] = q.Filter
+	}
+	if len(q.MustNot) > 0 {
+		b["must_not"] = q.MustNot
+	}
+	if q.MinimumShouldMatch != nil {
+		b["minimum_should_match"] = *q.MinimumShouldMatch
+	}
+	if q.Boost != nil {
+		b["boost"] = *q.Boost
+	}
+	return Clause{"bool": b}
+}
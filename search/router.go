@@ -0,0 +1,52 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Record collections with a search index by default
+const (
+	PostCollection    = "app.bsky.feed.post"
+	ProfileCollection = "app.bsky.actor.profile"
+)
+
+// Returned by [IndexRouter.IndexForType] for record types which are not indexed
+var ErrUnmappedType = errors.New("record type has no search index")
+
+// IndexRouter maps record collection NSIDs to the search index holding documents of that type, so a single ingestion stream can dispatch each record to the right index.
+type IndexRouter struct {
+	indices map[string]string
+}
+
+// Creates a router for the default record types: posts and profiles.
+func NewIndexRouter(postIndex, profileIndex string) *IndexRouter {
+	return &IndexRouter{
+		indices: map[string]string{
+			PostCollection:    postIndex,
+			ProfileCollection: profileIndex,
+		},
+	}
+}
+
+// Route adds (or replaces) the index for a record type.
+func (r *IndexRouter) Route(nsid, index string) {
+	r.indices[nsid] = index
+}
+
+// IndexForType returns the name of the index for records in the given collection, or an error wrapping [ErrUnmappedType].
+func (r *IndexRouter) IndexForType(nsid string) (string, error) {
+	index, ok := r.indices[nsid]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnmappedType, nsid)
+	}
+	return index, nil
+}
+
+// uses the default palomar index names
+var defaultRouter = NewIndexRouter("palomar_post", "palomar_profile")
+
+// IndexForType returns the default index name for records in the given collection, or an error wrapping [ErrUnmappedType].
+func IndexForType(nsid string) (string, error) {
+	return defaultRouter.IndexForType(nsid)
+}
@@ -0,0 +1,42 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIndexForType(t *testing.T) {
+	assert := assert.New(t)
+
+	index, err := IndexForType("app.bsky.feed.post")
+	assert.NoError(err)
+	assert.Equal("palomar_post", index)
+
+	index, err = IndexForType("app.bsky.actor.profile")
+	assert.NoError(err)
+	assert.Equal("palomar_profile", index)
+
+	for _, nsid := range []string{"app.bsky.feed.like", "app.bsky.feed.generator", "app.bsky.feed", ""} {
+		_, err = IndexForType(nsid)
+		assert.ErrorIs(err, ErrUnmappedType)
+	}
+
+	r := NewIndexRouter("test_post", "test_profile")
+	r.Route("app.bsky.feed.generator", "test_feedgen")
+	for nsid, expected := range map[string]string{
+		"app.bsky.feed.post":      "test_post",
+		"app.bsky.actor.profile":  "test_profile",
+		"app.bsky.feed.generator": "test_feedgen",
+	} {
+		index, err := r.IndexForType(nsid)
+		assert.NoError(err)
+		assert.Equal(expected, index)
+	}
+	_, err = r.IndexForType("com.example.unknown")
+	assert.ErrorIs(err, ErrUnmappedType)
+
+	// routes are per-router
+	_, err = IndexForType("app.bsky.feed.generator")
+	assert.ErrorIs(err, ErrUnmappedType)
+}
@@ -0,0 +1,122 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/expirable"
+	es "github.com/opensearch-project/opensearch-go/v2"
+	"github.com/opensearch-project/opensearch-go/v2/opensearchapi"
+)
+
+// Searcher is the minimal search backend needed to run queries: it sends a JSON query body to an index and returns the raw response. Implementations other than [NewClientSearcher] are mostly useful as in-memory fakes for testing.
+type Searcher interface {
+	Search(ctx context.Context, index string, body io.Reader) (*opensearchapi.Response, error)
+}
+
+// The opensearch client exposes search as a function-typed struct field, not a method, so it needs a small adapter to satisfy an interface.
+type clientSearcher struct {
+	escli *es.Client
+}
+
+// NewClientSearcher wraps an OpenSearch client as a [Searcher].
+func NewClientSearcher(escli *es.Client) Searcher {
+	return &clientSearcher{escli: escli}
+}
+
+func (cs *clientSearcher) Search(ctx context.Context, index string, body io.Reader) (*opensearchapi.Response, error) {
+	return cs.escli.Search(
+		cs.escli.Search.WithContext(ctx),
+		cs.escli.Search.WithIndex(index),
+		cs.escli.Search.WithBody(body),
+	)
+}
+
+type loggingSearcher struct {
+	Searcher
+	logger *slog.Logger
+}
+
+// NewLoggingSearcher wraps a [Searcher] so that the query functions in this package log to logger, instead of slog.Default().
+func NewLoggingSearcher(inner Searcher, logger *slog.Logger) Searcher {
+	return &loggingSearcher{Searcher: inner, logger: logger}
+}
+
+func (ls *loggingSearcher) queryLogger() *slog.Logger {
+	return ls.logger
+}
+
+// returns the logger set on a Searcher with [NewLoggingSearcher] (possibly under other wrappers), or slog.Default()
+func searcherLogger(s Searcher) *slog.Logger {
+	if ls, ok := s.(interface{ queryLogger() *slog.Logger }); ok {
+		if logger := ls.queryLogger(); logger != nil {
+			return logger
+		}
+	}
+	return slog.Default()
+}
+
+// a successful search response, buffered for re-use
+type cachedResponse struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+func (cr *cachedResponse) response() *opensearchapi.Response {
+	return &opensearchapi.Response{
+		StatusCode: cr.status,
+		Header:     cr.header.Clone(),
+		Body:       io.NopCloser(bytes.NewReader(cr.body)),
+	}
+}
+
+type cachingSearcher struct {
+	inner Searcher
+	cache *expirable.LRU[string, *cachedResponse]
+}
+
+// NewCachingSearcher wraps a [Searcher] with a short-lived in-memory cache of responses, keyed by index and exact query body. Up to size responses are kept, each for ttl. Errors (including error responses from the backend) are never cached.
+//
+// This helps with bursts of identical queries, like popular typeahead prefixes. Note that post search queries include the current time (to exclude future posts), so identical post searches only share a cache entry within the same millisecond.
+func NewCachingSearcher(inner Searcher, ttl time.Duration, size int) Searcher {
+	return &cachingSearcher{
+		inner: inner,
+		cache: expirable.NewLRU[string, *cachedResponse](size, nil, ttl),
+	}
+}
+
+func (cs *cachingSearcher) queryLogger() *slog.Logger {
+	return searcherLogger(cs.inner)
+}
+
+func (cs *cachingSearcher) Search(ctx context.Context, index string, body io.Reader) (*opensearchapi.Response, error) {
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("reading query body: %w", err)
+	}
+	key := index + "\x00" + string(b)
+	if cr, ok := cs.cache.Get(key); ok {
+		searchCacheHits.Inc()
+		return cr.response(), nil
+	}
+	searchCacheMisses.Inc()
+
+	res, err := cs.inner.Search(ctx, index, bytes.NewReader(b))
+	if err != nil || res.IsError() {
+		return res, err
+	}
+	defer res.Body.Close()
+	raw, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading search response: %w", err)
+	}
+	cr := &cachedResponse{status: res.StatusCode, header: res.Header, body: raw}
+	cs.cache.Add(key, cr)
+	return cr.response(), nil
+}
@@ -0,0 +1,181 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/identity"
+
+	"github.com/opensearch-project/opensearch-go/v2/opensearchapi"
+	"github.com/stretchr/testify/assert"
+)
+
+// in-memory Searcher which records requests and replies with a canned response
+type fakeSearcher struct {
+	status int
+	body   string
+
+	index string
+	query map[string]interface{}
+}
+
+func (fs *fakeSearcher) Search(ctx context.Context, index string, body io.Reader) (*opensearchapi.Response, error) {
+	fs.index = index
+	if err := json.NewDecoder(body).Decode(&fs.query); err != nil {
+		return nil, err
+	}
+	return &opensearchapi.Response{
+		StatusCode: fs.status,
+		Body:       io.NopCloser(strings.NewReader(fs.body)),
+	}, nil
+}
+
+func TestSearchWithFakeSearcher(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	dir := identity.NewMockDirectory()
+
+	fake := &fakeSearcher{
+		status: http.StatusOK,
+		body:   `{"took": 3, "hits": {"hits": [{"_index": "palomar_post", "_id": "abc", "_score": 1.5, "_source": {"text": "hello"}}]}}`,
+	}
+	res, err := DoSearchPosts(ctx, &dir, fake, "palomar_post", &PostSearchParams{Query: "hello", Size: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal("palomar_post", fake.index)
+	assert.Equal(float64(10), fake.query["size"])
+	assert.Equal(3, res.Took)
+	if assert.Len(res.Hits.Hits, 1) {
+		assert.Equal("abc", res.Hits.Hits[0].ID)
+		assert.JSONEq(`{"text": "hello"}`, string(res.Hits.Hits[0].Source))
+	}
+
+	// empty query string: an error, or a match_all query if requested
+	fake.query = nil
+	_, err = DoSearchPosts(ctx, &dir, fake, "palomar_post", &PostSearchParams{Query: "", Size: 10})
+	assert.ErrorIs(err, ErrEmptyQuery)
+	assert.Nil(fake.query)
+	_, err = DoSearchPosts(ctx, &dir, fake, "palomar_post", &PostSearchParams{Query: " ", Size: 10, MatchAllIfEmpty: true})
+	assert.NoError(err)
+	assert.Contains(fake.query["query"].(map[string]interface{})["bool"].(map[string]interface{})["must"], "match_all")
+
+	fake.body = `{"hits": {"hits": []}}`
+	res, err = DoSearchProfilesTypeahead(ctx, fake, "palomar_profile", &ActorSearchParams{Query: "ali", Size: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal("palomar_profile", fake.index)
+	assert.Empty(res.Hits.Hits)
+
+	// error responses from the backend are surfaced
+	fake.status = http.StatusBadRequest
+	fake.body = `{"error": "bad query"}`
+	_, err = DoSearchGeneric(ctx, fake, "palomar_post", "text:hello")
+	assert.ErrorContains(err, "code=400")
+}
+
+func TestDoTopProfiles(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	fake := &fakeSearcher{
+		status: http.StatusOK,
+		body:   `{"hits": {"hits": [{"_id": "did:plc:abc123", "_source": {"did": "did:plc:abc123"}}]}}`,
+	}
+	res, err := DoTopProfiles(ctx, fake, "palomar_profile", 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(res.Hits.Hits, 1)
+	assert.Equal("palomar_profile", fake.index)
+	assert.Equal(map[string]interface{}{"match_all": map[string]interface{}{}}, fake.query["query"])
+	assert.Equal(float64(20), fake.query["size"])
+	assert.Equal([]interface{}{
+		map[string]interface{}{"pagerank": map[string]interface{}{
+			"order":         "desc",
+			"missing":       "_last",
+			"unmapped_type": "float",
+		}},
+		map[string]interface{}{"_score": map[string]interface{}{"order": "desc"}},
+	}, fake.query["sort"])
+
+	_, err = DoTopProfiles(ctx, fake, "palomar_profile", 1000)
+	assert.Error(err)
+}
+
+// counts calls to an inner searcher
+type countingSearcher struct {
+	fakeSearcher
+	calls int
+}
+
+func (cs *countingSearcher) Search(ctx context.Context, index string, body io.Reader) (*opensearchapi.Response, error) {
+	cs.calls++
+	return cs.fakeSearcher.Search(ctx, index, body)
+}
+
+func TestCachingSearcher(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	inner := &countingSearcher{fakeSearcher: fakeSearcher{
+		status: http.StatusOK,
+		body:   `{"hits": {"hits": [{"_id": "did:plc:abc123", "_source": {"handle": "alice.test"}}]}}`,
+	}}
+	searcher := NewCachingSearcher(inner, 100*time.Millisecond, 10)
+	params := &ActorSearchParams{Query: "ali", Size: 5}
+
+	res, err := DoSearchProfilesTypeahead(ctx, searcher, "palomar_profile", params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(res.Hits.Hits, 1)
+	assert.Equal(1, inner.calls)
+
+	// identical query within the TTL is a cache hit, with an identical response
+	again, err := DoSearchProfilesTypeahead(ctx, searcher, "palomar_profile", params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(1, inner.calls)
+	assert.Equal(res, again)
+
+	// a different query, or the same query against another index, is a miss
+	_, err = DoSearchProfilesTypeahead(ctx, searcher, "palomar_profile", &ActorSearchParams{Query: "bob", Size: 5})
+	assert.NoError(err)
+	assert.Equal(2, inner.calls)
+	_, err = DoSearchProfilesTypeahead(ctx, searcher, "other_profile", params)
+	assert.NoError(err)
+	assert.Equal(3, inner.calls)
+
+	// entries expire after the TTL
+	time.Sleep(150 * time.Millisecond)
+	_, err = DoSearchProfilesTypeahead(ctx, searcher, "palomar_profile", params)
+	assert.NoError(err)
+	assert.Equal(4, inner.calls)
+
+	// error responses are not cached
+	inner.status = http.StatusInternalServerError
+	inner.body = `{"error": "overloaded"}`
+	errParams := &ActorSearchParams{Query: "carol", Size: 5}
+	_, err = DoSearchProfilesTypeahead(ctx, searcher, "palomar_profile", errParams)
+	assert.ErrorContains(err, "code=500")
+	_, err = DoSearchProfilesTypeahead(ctx, searcher, "palomar_profile", errParams)
+	assert.ErrorContains(err, "code=500")
+	assert.Equal(6, inner.calls)
+
+	// once the backend recovers, the query succeeds and is then cached
+	inner.status = http.StatusOK
+	inner.body = `{"hits": {"hits": []}}`
+	_, err = DoSearchProfilesTypeahead(ctx, searcher, "palomar_profile", errParams)
+	assert.NoError(err)
+	_, err = DoSearchProfilesTypeahead(ctx, searcher, "palomar_profile", errParams)
+	assert.NoError(err)
+	assert.Equal(7, inner.calls)
+}
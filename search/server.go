@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	_ "net/http/pprof" // For pprof in the metrics server
 
@@ -32,12 +33,20 @@ type ServerConfig struct {
 	ProfileIndex      string
 	PostIndex         string
 	AtlantisAddresses []string
+	// Document field to rank profile search results by (see [ActorSearchParams.SortField])
+	ProfileSortField string
+	// If positive, identical search queries are answered from an in-memory cache for this long (see [NewCachingSearcher])
+	QueryCacheTTL time.Duration
+	// Maximum number of cached search responses; defaults to 1000
+	QueryCacheSize int
 }
 
 type Server struct {
 	escli        *es.Client
+	searcher     Searcher
 	postIndex    string
 	profileIndex string
+	profileSort  string
 	dir          identity.Directory
 	echo         *echo.Echo
 	logger       *slog.Logger
@@ -53,10 +62,21 @@ func NewServer(escli *es.Client, dir identity.Directory, config ServerConfig) (*
 		}))
 	}
 
+	searcher := NewLoggingSearcher(NewClientSearcher(escli), logger)
+	if config.QueryCacheTTL > 0 {
+		size := config.QueryCacheSize
+		if size <= 0 {
+			size = 1000
+		}
+		searcher = NewCachingSearcher(searcher, config.QueryCacheTTL, size)
+	}
+
 	serv := Server{
 		escli:        escli,
+		searcher:     searcher,
 		postIndex:    config.PostIndex,
 		profileIndex: config.ProfileIndex,
+		profileSort:  config.ProfileSortField,
 		dir:          dir,
 		logger:       logger,
 	}
@@ -46,9 +46,13 @@ type PostDoc struct {
 	EmbedImgCount     int      `json:"embed_img_count"`
 	EmbedImgAltText   []string `json:"embed_img_alt_text,omitempty"`
 	EmbedImgAltTextJA []string `json:"embed_img_alt_text_ja,omitempty"`
+	EmbedExtTitle     string   `json:"embed_external_title,omitempty"`
+	EmbedExtDesc      string   `json:"embed_external_description,omitempty"`
+	HasAltText        bool     `json:"has_alt_text"`
 	SelfLabel         []string `json:"self_label,omitempty"`
 	URL               []string `json:"url,omitempty"`
 	Domain            []string `json:"domain,omitempty"`
+	LinkDomain        string   `json:"link_domain,omitempty"`
 	Tag               []string `json:"tag,omitempty"`
 	Emoji             []string `json:"emoji,omitempty"`
 }
@@ -135,8 +139,22 @@ func TransformPost(post *appbsky.FeedPost, did syntax.DID, rkey, cid string) Pos
 	if post.Reply != nil {
 		replyRootATURI = &(post.Reply.Root.Uri)
 	}
+	// external link card, either directly or as the media of a quote post
+	var embedExt *appbsky.EmbedExternal_External
 	if post.Embed != nil && post.Embed.EmbedExternal != nil {
-		urls = append(urls, post.Embed.EmbedExternal.External.Uri)
+		embedExt = post.Embed.EmbedExternal.External
+	} else if post.Embed != nil {
+		if ext, ok := post.Embed.EmbedRecordWithMedia.External(); ok {
+			embedExt = ext.External
+		}
+	}
+	var linkDomain string
+	if embedExt != nil {
+		urls = append(urls, embedExt.Uri)
+		u, err := url.Parse(NormalizeLossyURL(embedExt.Uri))
+		if nil == err {
+			linkDomain = u.Hostname()
+		}
 	}
 	var embedATURI *string
 	if post.Embed != nil && post.Embed.EmbedRecord != nil {
@@ -207,9 +225,11 @@ func TransformPost(post *appbsky.FeedPost, did syntax.DID, rkey, cid string) Pos
 		EmbedImgCount:     embedImgCount,
 		EmbedImgAltText:   embedImgAltText,
 		EmbedImgAltTextJA: embedImgAltTextJA,
+		HasAltText:        embedImgCount > 0 && len(embedImgAltText) == embedImgCount,
 		SelfLabel:         selfLabels,
 		URL:               urls,
 		Domain:            domains,
+		LinkDomain:        linkDomain,
 		Tag:               parsePostTags(post),
 		Emoji:             parseEmojis(post.Text),
 	}
@@ -218,13 +238,18 @@ func TransformPost(post *appbsky.FeedPost, did syntax.DID, rkey, cid string) Pos
 		doc.TextJA = &post.Text
 	}
 
+	if embedExt != nil {
+		doc.EmbedExtTitle = embedExt.Title
+		doc.EmbedExtDesc = embedExt.Description
+	}
+
 	if post.CreatedAt != "" {
 		// there are some old bad timestamps out there!
 		dt, err := syntax.ParseDatetimeLenient(post.CreatedAt)
 		if nil == err { // *not* an error
 			// not more than a few minutes in the future
 			if time.Since(dt.Time()) >= -1*5*time.Minute {
-				s := dt.String()
+				s := dt.UTC().String()
 				doc.CreatedAt = &s
 			} else {
 				slog.Warn("rejecting future post CreatedAt", "datetime", dt.String(), "did", did.String(), "rkey", rkey)
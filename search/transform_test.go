@@ -6,6 +6,7 @@ import (
 	"os"
 	"testing"
 
+	comatproto "github.com/bluesky-social/indigo/api/atproto"
 	appbsky "github.com/bluesky-social/indigo/api/bsky"
 	"github.com/bluesky-social/indigo/atproto/identity"
 	"github.com/bluesky-social/indigo/atproto/syntax"
@@ -113,3 +114,61 @@ func testPostFixture(t *testing.T, row postFixture) {
 	assert.Equal(row.PostDoc, doc)
 	assert.Equal(row.DocId, doc.DocId())
 }
+
+func TestTransformPostHasAltText(t *testing.T) {
+	assert := assert.New(t)
+
+	did := syntax.DID("did:plc:abc123")
+	withImages := func(alts ...string) *appbsky.FeedPost {
+		imgs := make([]*appbsky.EmbedImages_Image, len(alts))
+		for i, alt := range alts {
+			imgs[i] = &appbsky.EmbedImages_Image{Alt: alt}
+		}
+		return &appbsky.FeedPost{
+			Text:  "pics",
+			Embed: &appbsky.FeedPost_Embed{EmbedImages: &appbsky.EmbedImages{Images: imgs}},
+		}
+	}
+
+	assert.False(TransformPost(&appbsky.FeedPost{Text: "no images"}, did, "3jzfcijpj2z2a", "").HasAltText)
+	assert.True(TransformPost(withImages("a cat", "a dog"), did, "3jzfcijpj2z2a", "").HasAltText)
+	// every image needs alt text
+	assert.False(TransformPost(withImages("a cat", ""), did, "3jzfcijpj2z2a", "").HasAltText)
+}
+
+func TestTransformPostExternalEmbed(t *testing.T) {
+	assert := assert.New(t)
+
+	did := syntax.DID("did:plc:abc123")
+	card := &appbsky.EmbedExternal{External: &appbsky.EmbedExternal_External{
+		Uri:         "https://News.Example.com:443/story?utm_source=feed",
+		Title:       "Big News",
+		Description: "Something happened today.",
+	}}
+
+	doc := TransformPost(&appbsky.FeedPost{
+		Text:  "read this",
+		Embed: &appbsky.FeedPost_Embed{EmbedExternal: card},
+	}, did, "3jzfcijpj2z2a", "")
+	assert.Equal("Big News", doc.EmbedExtTitle)
+	assert.Equal("Something happened today.", doc.EmbedExtDesc)
+	assert.Equal("news.example.com", doc.LinkDomain)
+	assert.Equal([]string{"news.example.com"}, doc.Domain)
+
+	// link card as the media of a quote post
+	doc = TransformPost(&appbsky.FeedPost{
+		Text: "quoting, with a link",
+		Embed: &appbsky.FeedPost_Embed{EmbedRecordWithMedia: &appbsky.EmbedRecordWithMedia{
+			Record: &appbsky.EmbedRecord{Record: &comatproto.RepoStrongRef{Uri: "at://did:plc:abc123/app.bsky.feed.post/3jzfcijpj2z2b"}},
+			Media:  &appbsky.EmbedRecordWithMedia_Media{EmbedExternal: card},
+		}},
+	}, did, "3jzfcijpj2z2a", "")
+	assert.Equal("Big News", doc.EmbedExtTitle)
+	assert.Equal("news.example.com", doc.LinkDomain)
+
+	// no link card
+	doc = TransformPost(&appbsky.FeedPost{Text: "just text"}, did, "3jzfcijpj2z2a", "")
+	assert.Empty(doc.EmbedExtTitle)
+	assert.Empty(doc.EmbedExtDesc)
+	assert.Empty(doc.LinkDomain)
+}
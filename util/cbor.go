@@ -5,8 +5,18 @@ import (
 	mh "github.com/multiformats/go-multihash"
 )
 
+// DefaultHashFunc is the multihash function used for CIDs of blocks written through CborStore, as required by the atproto repo spec
+const DefaultHashFunc = mh.SHA2_256
+
 func CborStore(bs cbor.IpldBlockstore) *cbor.BasicIpldStore {
+	return CborStoreWithHash(bs, DefaultHashFunc)
+}
+
+// CborStoreWithHash is like CborStore, but computes CIDs of written blocks with the given multihash function (eg, mh.BLAKE3). Blocks are read regardless of their hash function.
+//
+// Non-default hash functions are not interoperable with the atproto network, and are only for experimentation.
+func CborStoreWithHash(bs cbor.IpldBlockstore, hashFunc uint64) *cbor.BasicIpldStore {
 	cst := cbor.NewCborStore(bs)
-	cst.DefaultMultihash = mh.SHA2_256
+	cst.DefaultMultihash = hashFunc
 	return cst
 }
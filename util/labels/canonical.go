@@ -0,0 +1,62 @@
+package labels
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/bluesky-social/indigo/api/atproto"
+)
+
+// CanonicalizeLabels returns a copy of labels with exact duplicates (identical in every field, including signature) removed, sorted by source, subject URI, value, and then creation time. Remaining ties (eg, a label and its negation at the same timestamp) are ordered by their CBOR encoding, so the output order does not depend on the input order.
+func CanonicalizeLabels(labels []SignedLabel) []SignedLabel {
+	type encoded struct {
+		label SignedLabel
+		cbor  []byte
+	}
+
+	seen := make(map[string]bool, len(labels))
+	out := make([]encoded, 0, len(labels))
+	for _, l := range labels {
+		buf := new(bytes.Buffer)
+		ll := atproto.LabelDefs_Label(l)
+		if err := ll.MarshalCBOR(buf); err != nil {
+			// can't happen for in-memory labels; keep the label rather than silently dropping it
+			out = append(out, encoded{label: l})
+			continue
+		}
+		if seen[buf.String()] {
+			continue
+		}
+		seen[buf.String()] = true
+		out = append(out, encoded{label: l, cbor: buf.Bytes()})
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i].label, out[j].label
+		if a.Src != b.Src {
+			return a.Src < b.Src
+		}
+		if a.Uri != b.Uri {
+			return a.Uri < b.Uri
+		}
+		if a.Val != b.Val {
+			return a.Val < b.Val
+		}
+		if a.Cts != b.Cts {
+			if labelCreatedBefore(a, b) {
+				return true
+			}
+			if labelCreatedBefore(b, a) {
+				return false
+			}
+			return a.Cts < b.Cts
+		}
+		return bytes.Compare(out[i].cbor, out[j].cbor) < 0
+	})
+
+	result := make([]SignedLabel, len(out))
+	for i, e := range out {
+		result[i] = e.label
+	}
+	return result
+}
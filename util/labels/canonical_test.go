@@ -0,0 +1,45 @@
+package labels
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonicalizeLabels(t *testing.T) {
+	assert := assert.New(t)
+
+	neg := true
+	post := "at://did:plc:abc123/app.bsky.feed.post/3jzfcijpj2z2a"
+	srcA := "did:plc:labelera"
+	srcB := "did:plc:labelerb"
+
+	spam1 := SignedLabel{Src: srcA, Uri: post, Val: "spam", Cts: "2024-01-01T00:00:00.000Z", Sig: []byte("sig1")}
+	spam2 := SignedLabel{Src: srcA, Uri: post, Val: "spam", Cts: "2024-01-02T00:00:00.000Z", Sig: []byte("sig2")}
+	spamNeg := SignedLabel{Src: srcA, Uri: post, Val: "spam", Cts: "2024-01-02T00:00:00.000Z", Neg: &neg, Sig: []byte("sig3")}
+	rude := SignedLabel{Src: srcA, Uri: post, Val: "rude", Cts: "2024-01-03T00:00:00.000Z", Sig: []byte("sig4")}
+	acct := SignedLabel{Src: srcA, Uri: "did:plc:abc123", Val: "spam", Cts: "2024-01-01T00:00:00.000Z", Sig: []byte("sig5")}
+	other := SignedLabel{Src: srcB, Uri: post, Val: "spam", Cts: "2023-01-01T00:00:00.000Z", Sig: []byte("sig6")}
+
+	// copy of spam1 with distinct pointers and slices, but identical values
+	spam1Dup := spam1
+	spam1Dup.Sig = []byte("sig1")
+
+	input := []SignedLabel{other, spam2, spamNeg, spam1, rude, spam1Dup, acct, spam2}
+	out := CanonicalizeLabels(input)
+	assert.Equal([]SignedLabel{rude, spam1, spam2, spamNeg, acct, other}, out)
+
+	// output order doesn't depend on input order
+	reversed := make([]SignedLabel, len(input))
+	for i, l := range input {
+		reversed[len(input)-1-i] = l
+	}
+	assert.Equal(out, CanonicalizeLabels(reversed))
+
+	// labels differing only in signature are not duplicates
+	spam1Resigned := spam1
+	spam1Resigned.Sig = []byte("sig7")
+	assert.Len(CanonicalizeLabels([]SignedLabel{spam1, spam1Resigned}), 2)
+
+	assert.Empty(CanonicalizeLabels(nil))
+}
@@ -0,0 +1,90 @@
+package labels
+
+import (
+	"sort"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+)
+
+// LabelsForSubject returns the labels which currently apply to the subject uri, as emitted by the labeler src. If src is empty, labels from all sources are considered.
+//
+// For each (src, val) pair, only the most recent label (by `cts`) is considered: if it is a negation, or has expired, no label is returned for that pair. A negation with the wildcard value "*" removes every label from that source on the subject created at or before it; labels applied afterwards still apply. The result is sorted by source, then value.
+func LabelsForSubject(labels []SignedLabel, uri string, src string) []SignedLabel {
+	return labelsForSubjectAt(labels, uri, src, time.Now())
+}
+
+// Label value which, on a negation label, negates all labels from the same source on the subject
+const WildcardVal = "*"
+
+type labelKey struct {
+	src string
+	val string
+}
+
+func labelsForSubjectAt(labels []SignedLabel, uri, src string, now time.Time) []SignedLabel {
+	latest := make(map[labelKey]SignedLabel)
+	// most recent wildcard negation, by source
+	wildcard := make(map[string]SignedLabel)
+	for _, l := range labels {
+		if l.Uri != uri || (src != "" && l.Src != src) {
+			continue
+		}
+		if l.Val == WildcardVal && l.Neg != nil && *l.Neg {
+			prev, ok := wildcard[l.Src]
+			if !ok || !labelCreatedBefore(l, prev) {
+				wildcard[l.Src] = l
+			}
+			continue
+		}
+		k := labelKey{src: l.Src, val: l.Val}
+		prev, ok := latest[k]
+		if !ok || !labelCreatedBefore(l, prev) {
+			latest[k] = l
+		}
+	}
+
+	var out []SignedLabel
+	for _, l := range latest {
+		if l.Neg != nil && *l.Neg {
+			continue
+		}
+		if labelExpired(l, now) {
+			continue
+		}
+		if w, ok := wildcard[l.Src]; ok && !labelCreatedBefore(w, l) {
+			continue
+		}
+		out = append(out, l)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Src != out[j].Src {
+			return out[i].Src < out[j].Src
+		}
+		return out[i].Val < out[j].Val
+	})
+	return out
+}
+
+// reports whether label a was created strictly before label b
+func labelCreatedBefore(a, b SignedLabel) bool {
+	at, aerr := syntax.ParseDatetimeTime(a.Cts)
+	bt, berr := syntax.ParseDatetimeTime(b.Cts)
+	if aerr != nil || berr != nil {
+		// fall back to lexical ordering of timestamps
+		return a.Cts < b.Cts
+	}
+	return at.Before(bt)
+}
+
+func labelExpired(l SignedLabel, now time.Time) bool {
+	if l.Exp == nil {
+		return false
+	}
+	exp, err := syntax.ParseDatetimeTime(*l.Exp)
+	if err != nil {
+		// malformed expiration: treat as not expiring
+		return false
+	}
+	return !exp.After(now)
+}
@@ -0,0 +1,94 @@
+package labels
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func vals(labels []SignedLabel) []string {
+	out := make([]string, len(labels))
+	for i, l := range labels {
+		out[i] = l.Src + ":" + l.Val
+	}
+	return out
+}
+
+func TestLabelsForSubject(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	neg := true
+	past := "2024-05-01T00:00:00.000Z"
+	future := "2024-07-01T00:00:00.000Z"
+	uri := "at://did:plc:abc123/app.bsky.feed.post/3jzfcijpj2z2a"
+	srcA := "did:plc:labelera"
+	srcB := "did:plc:labelerb"
+
+	labels := []SignedLabel{
+		// applied then negated
+		{Src: srcA, Uri: uri, Val: "spam", Cts: "2024-01-01T00:00:00.000Z"},
+		{Src: srcA, Uri: uri, Val: "spam", Cts: "2024-01-02T00:00:00.000Z", Neg: &neg},
+		// negated then re-applied (out of order in the input)
+		{Src: srcA, Uri: uri, Val: "rude", Cts: "2024-01-03T00:00:00.000Z"},
+		{Src: srcA, Uri: uri, Val: "rude", Cts: "2024-01-02T00:00:00.000Z", Neg: &neg},
+		// expired
+		{Src: srcA, Uri: uri, Val: "temp", Cts: "2024-01-01T00:00:00.000Z", Exp: &past},
+		// not yet expired
+		{Src: srcA, Uri: uri, Val: "porn", Cts: "2024-01-01T00:00:00.000Z", Exp: &future},
+		// other source; negation from A does not affect it
+		{Src: srcB, Uri: uri, Val: "spam", Cts: "2024-01-01T00:00:00.000Z"},
+		// other subject
+		{Src: srcA, Uri: "did:plc:abc123", Val: "spam", Cts: "2024-01-01T00:00:00.000Z"},
+	}
+
+	assert.Equal([]string{srcA + ":porn", srcA + ":rude"}, vals(labelsForSubjectAt(labels, uri, srcA, now)))
+	assert.Equal([]string{srcB + ":spam"}, vals(labelsForSubjectAt(labels, uri, srcB, now)))
+	assert.Equal([]string{srcA + ":porn", srcA + ":rude", srcB + ":spam"}, vals(labelsForSubjectAt(labels, uri, "", now)))
+	assert.Equal([]string{srcA + ":spam"}, vals(labelsForSubjectAt(labels, "did:plc:abc123", srcA, now)))
+	assert.Empty(labelsForSubjectAt(labels, "did:plc:other", srcA, now))
+
+	// once the expiration passes, the label no longer applies
+	later := time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal([]string{srcA + ":rude"}, vals(labelsForSubjectAt(labels, uri, srcA, later)))
+}
+
+func TestLabelsForSubjectWildcardNegation(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	neg := true
+	uri := "at://did:plc:abc123/app.bsky.feed.post/3jzfcijpj2z2a"
+	srcA := "did:plc:labelera"
+	srcB := "did:plc:labelerb"
+
+	labels := []SignedLabel{
+		{Src: srcA, Uri: uri, Val: "spam", Cts: "2024-01-01T00:00:00.000Z"},
+		{Src: srcA, Uri: uri, Val: "rude", Cts: "2024-01-02T00:00:00.000Z"},
+		// same timestamp as the wildcard negation; removed
+		{Src: srcA, Uri: uri, Val: "porn", Cts: "2024-01-03T00:00:00.000Z"},
+		{Src: srcA, Uri: uri, Val: "*", Cts: "2024-01-03T00:00:00.000Z", Neg: &neg},
+		// applied after the wildcard negation
+		{Src: srcA, Uri: uri, Val: "gore", Cts: "2024-01-04T00:00:00.000Z"},
+		// other sources and subjects are not affected
+		{Src: srcB, Uri: uri, Val: "spam", Cts: "2024-01-01T00:00:00.000Z"},
+		{Src: srcA, Uri: "did:plc:abc123", Val: "spam", Cts: "2024-01-01T00:00:00.000Z"},
+	}
+
+	assert.Equal([]string{srcA + ":gore"}, vals(labelsForSubjectAt(labels, uri, srcA, now)))
+	assert.Equal([]string{srcA + ":gore", srcB + ":spam"}, vals(labelsForSubjectAt(labels, uri, "", now)))
+	assert.Equal([]string{srcA + ":spam"}, vals(labelsForSubjectAt(labels, "did:plc:abc123", srcA, now)))
+
+	// a later wildcard negation also removes labels applied in between; the earlier one is superseded
+	labels = append(labels, SignedLabel{Src: srcA, Uri: uri, Val: "*", Cts: "2024-01-05T00:00:00.000Z", Neg: &neg})
+	assert.Empty(labelsForSubjectAt(labels, uri, srcA, now))
+
+	// re-applying a label after the negation brings it back, whatever the input order
+	labels = append([]SignedLabel{{Src: srcA, Uri: uri, Val: "spam", Cts: "2024-01-06T00:00:00.000Z"}}, labels...)
+	assert.Equal([]string{srcA + ":spam"}, vals(labelsForSubjectAt(labels, uri, srcA, now)))
+
+	// a specific negation after the wildcard still applies
+	labels = append(labels, SignedLabel{Src: srcA, Uri: uri, Val: "spam", Cts: "2024-01-07T00:00:00.000Z", Neg: &neg})
+	assert.Empty(labelsForSubjectAt(labels, uri, srcA, now))
+}
@@ -0,0 +1,45 @@
+package labels
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// WriteLabelsJSONL writes labels in JSON Lines format: one JSON-encoded SignedLabel per line.
+func WriteLabelsJSONL(w io.Writer, labels []SignedLabel) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	for i := range labels {
+		if err := enc.Encode(&labels[i]); err != nil {
+			return fmt.Errorf("encoding label %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// ReadLabelsJSONL streams labels in JSON Lines format (as written by [WriteLabelsJSONL]), invoking the callback for each label in order. Blank lines are skipped.
+//
+// If the callback returns an error, reading stops and that error is returned.
+func ReadLabelsJSONL(r io.Reader, cb func(*SignedLabel) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+		var l SignedLabel
+		if err := json.Unmarshal(raw, &l); err != nil {
+			return fmt.Errorf("parsing label on line %d: %w", line, err)
+		}
+		if err := cb(&l); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
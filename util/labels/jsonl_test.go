@@ -0,0 +1,61 @@
+package labels
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLabelsJSONLRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+
+	neg := true
+	exp := "2030-01-01T00:00:00.000Z"
+	cid := "bafyreiclp443lavogvhj3d2ob2cxbfuscni2k5jk7bebjzg7khl3esabwq"
+	ver := int64(1)
+	labels := []SignedLabel{
+		{
+			Src: "did:plc:labeler111",
+			Uri: "at://did:plc:abc123/app.bsky.feed.post/3jzfcijpj2z2a",
+			Cid: &cid,
+			Val: "spam",
+			Cts: "2024-01-02T03:04:05.006Z",
+			Ver: &ver,
+			Sig: []byte{1, 2, 3, 4},
+		},
+		{
+			Src: "did:plc:labeler111",
+			Uri: "at://did:plc:abc123/app.bsky.feed.post/3jzfcijpj2z2a",
+			Val: "spam",
+			Neg: &neg,
+			Cts: "2024-01-03T03:04:05.006Z",
+		},
+		{
+			Src: "did:plc:labeler222",
+			Uri: "did:plc:abc123",
+			Val: "<nsfw> & more",
+			Exp: &exp,
+			Cts: "2024-01-04T03:04:05.006Z",
+		},
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(WriteLabelsJSONL(&buf, labels))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(3, len(lines))
+	assert.Contains(lines[2], "<nsfw> & more")
+
+	var out []SignedLabel
+	assert.NoError(ReadLabelsJSONL(&buf, func(l *SignedLabel) error {
+		out = append(out, *l)
+		return nil
+	}))
+	assert.Equal(labels, out)
+
+	err := ReadLabelsJSONL(strings.NewReader("{\"src\": \"did:plc:abc\"}\n\nnot-json\n"), func(l *SignedLabel) error {
+		return nil
+	})
+	assert.ErrorContains(err, "line 3")
+}
@@ -2,10 +2,19 @@ package labels
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 
 	"github.com/bluesky-social/indigo/api/atproto"
+	"github.com/bluesky-social/indigo/atproto/atcrypto"
 )
 
+// ErrUnsupportedKeyType is returned (wrapped) by Verify for public keys which are not one of the atproto curves (secp256k1 or P-256)
+var ErrUnsupportedKeyType = errors.New("unsupported label signing key type")
+
+// LabelVersion is the label format version set by Sign when the label does not specify one
+const LabelVersion int64 = 1
+
 // UnsignedLabel is a label without the signature so we can validate it
 type UnsignedLabel struct {
 	// cid: Optionally, CID specifying the specific version of 'uri' resource this label applies to.
@@ -37,3 +46,74 @@ func (ul *UnsignedLabel) BytesForSigning() ([]byte, error) {
 	}
 	return buf.Bytes(), nil
 }
+
+// Sign signs the label with the given key, returning a SignedLabel with the signature attached.
+//
+// If Ver is not set, it defaults to LabelVersion; the version is part of the signed bytes. The receiver is not modified.
+func (ul *UnsignedLabel) Sign(key atcrypto.PrivateKey) (*SignedLabel, error) {
+	unsigned := *ul
+	if unsigned.Ver == nil {
+		ver := LabelVersion
+		unsigned.Ver = &ver
+	}
+
+	b, err := unsigned.BytesForSigning()
+	if err != nil {
+		return nil, fmt.Errorf("serializing label for signing: %w", err)
+	}
+	sig, err := key.HashAndSign(b)
+	if err != nil {
+		return nil, fmt.Errorf("signing label: %w", err)
+	}
+
+	return &SignedLabel{
+		Cid: unsigned.Cid,
+		Cts: unsigned.Cts,
+		Exp: unsigned.Exp,
+		Neg: unsigned.Neg,
+		Sig: sig,
+		Src: unsigned.Src,
+		Uri: unsigned.Uri,
+		Val: unsigned.Val,
+		Ver: unsigned.Ver,
+	}, nil
+}
+
+// Unsigned returns the label without its signature, as it was when signed
+func (sl *SignedLabel) Unsigned() UnsignedLabel {
+	return UnsignedLabel{
+		Cid: sl.Cid,
+		Cts: sl.Cts,
+		Exp: sl.Exp,
+		Neg: sl.Neg,
+		Src: sl.Src,
+		Uri: sl.Uri,
+		Val: sl.Val,
+		Ver: sl.Ver,
+	}
+}
+
+// Verify checks the label signature against the labeler's public key.
+//
+// The signature algorithm is picked from the key type: labeler keys may be secp256k1 (K-256) or P-256. Per atproto rules, signatures must be "low-S" on either curve; high-S signatures are rejected. Other key types return ErrUnsupportedKeyType.
+func (sl *SignedLabel) Verify(pub atcrypto.PublicKey) error {
+	var verify func(content, sig []byte) error
+	switch k := pub.(type) {
+	case *atcrypto.PublicKeyK256:
+		verify = k.HashAndVerify
+	case *atcrypto.PublicKeyP256:
+		verify = k.HashAndVerify
+	default:
+		return fmt.Errorf("%w: %T", ErrUnsupportedKeyType, pub)
+	}
+
+	if len(sl.Sig) == 0 {
+		return fmt.Errorf("label is not signed")
+	}
+	ul := sl.Unsigned()
+	b, err := ul.BytesForSigning()
+	if err != nil {
+		return fmt.Errorf("serializing label for verification: %w", err)
+	}
+	return verify(b, sl.Sig)
+}
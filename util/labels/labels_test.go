@@ -0,0 +1,200 @@
+package labels
+
+import (
+	"crypto/elliptic"
+	"math/big"
+	"testing"
+
+	comatproto "github.com/bluesky-social/indigo/api/atproto"
+	"github.com/bluesky-social/indigo/atproto/atcrypto"
+	"github.com/bluesky-social/indigo/atproto/atdata"
+	"github.com/bluesky-social/indigo/atproto/labeling"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignLabel(t *testing.T) {
+	assert := assert.New(t)
+
+	priv, err := atcrypto.GeneratePrivateKeyK256()
+	assert.NoError(err)
+	pub, err := priv.PublicKey()
+	assert.NoError(err)
+
+	neg := true
+	ul := UnsignedLabel{
+		Cts: "2024-01-02T03:04:05.006Z",
+		Neg: &neg,
+		Src: "did:plc:labeler",
+		Uri: "at://did:plc:abc123/app.bsky.feed.post/3jzfcijpj2z2a",
+		Val: "spam",
+	}
+
+	sl, err := ul.Sign(priv)
+	assert.NoError(err)
+	assert.NotEmpty(sl.Sig)
+	assert.Equal(LabelVersion, *sl.Ver)
+	assert.Equal(ul.Uri, sl.Uri)
+	assert.Equal(ul.Val, sl.Val)
+	assert.Equal(&neg, sl.Neg)
+	// receiver is left unchanged
+	assert.Nil(ul.Ver)
+
+	// verify by re-assembling the unsigned label from the signed one
+	unsigned := UnsignedLabel{
+		Cid: sl.Cid,
+		Cts: sl.Cts,
+		Exp: sl.Exp,
+		Neg: sl.Neg,
+		Src: sl.Src,
+		Uri: sl.Uri,
+		Val: sl.Val,
+		Ver: sl.Ver,
+	}
+	b, err := unsigned.BytesForSigning()
+	assert.NoError(err)
+	assert.NoError(pub.HashAndVerify(b, sl.Sig))
+
+	// compatible with the atproto/labeling implementation
+	ll := labeling.FromLexicon((*comatproto.LabelDefs_Label)(sl))
+	assert.NoError(ll.VerifySignature(pub))
+
+	// tampering breaks verification
+	unsigned.Val = "other"
+	b, err = unsigned.BytesForSigning()
+	assert.NoError(err)
+	assert.Error(pub.HashAndVerify(b, sl.Sig))
+
+	// explicit version is preserved
+	ver := int64(2)
+	ul.Ver = &ver
+	sl, err = ul.Sign(priv)
+	assert.NoError(err)
+	assert.Equal(int64(2), *sl.Ver)
+}
+
+func TestBytesForSigningVersion(t *testing.T) {
+	assert := assert.New(t)
+
+	exp := "2030-01-01T00:00:00.000Z"
+	base := UnsignedLabel{
+		Cts: "2024-01-02T03:04:05.006Z",
+		Exp: &exp,
+		Src: "did:plc:labeler",
+		Uri: "at://did:plc:abc123",
+		Val: "spam",
+	}
+	fields := map[string]any{
+		"cts": base.Cts,
+		"exp": exp,
+		"src": base.Src,
+		"uri": base.Uri,
+		"val": base.Val,
+	}
+
+	// canonical DAG-CBOR encoding of the same fields, from the generic data model
+	expected, err := atdata.MarshalCBOR(fields)
+	assert.NoError(err)
+	b, err := base.BytesForSigning()
+	assert.NoError(err)
+	assert.Equal(expected, b)
+
+	withVer := base
+	ver := int64(1)
+	withVer.Ver = &ver
+	fields["ver"] = int64(1)
+	expected, err = atdata.MarshalCBOR(fields)
+	assert.NoError(err)
+	bv, err := withVer.BytesForSigning()
+	assert.NoError(err)
+	assert.Equal(expected, bv)
+	assert.NotEqual(b, bv)
+	// "ver" sorts last among the (equal length) keys, so the version is the final key/value pair: "ver", 1
+	assert.Equal([]byte{0x63, 'v', 'e', 'r', 0x01}, bv[len(bv)-5:])
+
+	// matches the atproto/labeling implementation
+	ll := labeling.Label{
+		CreatedAt: base.Cts,
+		ExpiresAt: &exp,
+		SourceDID: base.Src,
+		URI:       base.Uri,
+		Val:       base.Val,
+		Version:   1,
+	}
+	lb, err := ll.UnsignedBytes()
+	assert.NoError(err)
+	assert.Equal(bv, lb)
+}
+
+// flips the "S" component of a 64-byte ECDSA signature to its high-S equivalent (n - s), which is still a valid signature
+func highS(sig []byte, order *big.Int) []byte {
+	s := new(big.Int).SetBytes(sig[32:])
+	s.Sub(order, s)
+	out := make([]byte, 64)
+	copy(out, sig[:32])
+	s.FillBytes(out[32:])
+	return out
+}
+
+// some other kind of public key
+type otherPublicKey struct {
+	atcrypto.PublicKey
+}
+
+func TestVerifyLabel(t *testing.T) {
+	assert := assert.New(t)
+
+	k256Order, _ := new(big.Int).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEBAAEDCE6AF48A03BBFD25E8CD0364141", 16)
+	p256Order := elliptic.P256().Params().N
+
+	ul := UnsignedLabel{
+		Cts: "2024-01-02T03:04:05.006Z",
+		Src: "did:plc:labeler",
+		Uri: "at://did:plc:abc123/app.bsky.feed.post/3jzfcijpj2z2a",
+		Val: "spam",
+	}
+
+	k256, err := atcrypto.GeneratePrivateKeyK256()
+	assert.NoError(err)
+	p256, err := atcrypto.GeneratePrivateKeyP256()
+	assert.NoError(err)
+
+	for _, tc := range []struct {
+		name  string
+		priv  atcrypto.PrivateKey
+		other atcrypto.PrivateKey
+		order *big.Int
+	}{
+		{"k256", k256, p256, k256Order},
+		{"p256", p256, k256, p256Order},
+	} {
+		pub, err := tc.priv.PublicKey()
+		assert.NoError(err)
+		otherPub, err := tc.other.PublicKey()
+		assert.NoError(err)
+
+		sl, err := ul.Sign(tc.priv)
+		assert.NoError(err)
+		assert.NoError(sl.Verify(pub), tc.name)
+
+		// key for the other curve
+		assert.ErrorIs(sl.Verify(otherPub), atcrypto.ErrInvalidSignature, tc.name)
+
+		// tampered label
+		tampered := *sl
+		tampered.Val = "other"
+		assert.ErrorIs(tampered.Verify(pub), atcrypto.ErrInvalidSignature, tc.name)
+
+		// high-S form of the same signature
+		malleated := *sl
+		malleated.Sig = highS(sl.Sig, tc.order)
+		assert.NotEqual(sl.Sig, malleated.Sig)
+		assert.ErrorIs(malleated.Verify(pub), atcrypto.ErrInvalidSignature, tc.name)
+
+		unsigned := *sl
+		unsigned.Sig = nil
+		assert.Error(unsigned.Verify(pub), tc.name)
+
+		assert.ErrorIs(sl.Verify(otherPublicKey{pub}), ErrUnsupportedKeyType, tc.name)
+	}
+}
@@ -0,0 +1,54 @@
+package labels
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+)
+
+// SubjectKind is the kind of resource a label's `uri` refers to
+type SubjectKind string
+
+const (
+	// A bare DID, or an AT-URI with only an authority
+	SubjectAccount SubjectKind = "account"
+	// An AT-URI with authority, collection, and record key
+	SubjectRecord SubjectKind = "record"
+	// Any other valid URI, such as a blob reference or an AT-URI pointing at a whole collection
+	SubjectOther SubjectKind = "other"
+)
+
+// SubjectType classifies a label subject URI as an account, a record, or some other resource. Returns an error if the URI is empty or malformed.
+func SubjectType(uri string) (SubjectKind, error) {
+	if uri == "" {
+		return "", fmt.Errorf("empty label subject URI")
+	}
+
+	if strings.HasPrefix(uri, "did:") {
+		if _, err := syntax.ParseDID(uri); err != nil {
+			return "", fmt.Errorf("invalid label subject DID: %w", err)
+		}
+		return SubjectAccount, nil
+	}
+
+	if strings.HasPrefix(uri, "at://") {
+		aturi, err := syntax.ParseATURI(uri)
+		if err != nil {
+			return "", fmt.Errorf("invalid label subject AT-URI: %w", err)
+		}
+		switch {
+		case aturi.Collection() == "":
+			return SubjectAccount, nil
+		case aturi.RecordKey() != "":
+			return SubjectRecord, nil
+		default:
+			return SubjectOther, nil
+		}
+	}
+
+	if _, err := syntax.ParseURI(uri); err != nil {
+		return "", fmt.Errorf("invalid label subject URI: %w", err)
+	}
+	return SubjectOther, nil
+}
@@ -0,0 +1,46 @@
+package labels
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubjectType(t *testing.T) {
+	assert := assert.New(t)
+
+	valid := []struct {
+		uri  string
+		kind SubjectKind
+	}{
+		{"did:plc:abc123", SubjectAccount},
+		{"did:web:example.com", SubjectAccount},
+		{"at://did:plc:abc123", SubjectAccount},
+		{"at://handle.example.com", SubjectAccount},
+		{"at://did:plc:abc123/app.bsky.feed.post/3jzfcijpj2z2a", SubjectRecord},
+		{"at://did:plc:abc123/app.bsky.actor.profile/self", SubjectRecord},
+		{"at://did:plc:abc123/app.bsky.feed.post", SubjectOther},
+		{"https://cdn.example.com/img/bafkreiabc", SubjectOther},
+	}
+	for _, tc := range valid {
+		kind, err := SubjectType(tc.uri)
+		assert.NoError(err, tc.uri)
+		assert.Equal(tc.kind, kind, tc.uri)
+	}
+
+	malformed := []string{
+		"",
+		"did:",
+		"did:plc:",
+		"did:PLC:abc123",
+		"at://",
+		"at://not a did",
+		"at://did:plc:abc123/app.bsky.feed.post/3jzfcijpj2z2a/extra",
+		"at://did:plc:abc123/not_an_nsid/3jzfcijpj2z2a",
+		"not a uri",
+	}
+	for _, uri := range malformed {
+		_, err := SubjectType(uri)
+		assert.Error(err, uri)
+	}
+}